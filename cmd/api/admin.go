@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/signal"
+	"syscall"
+
+	"github.com/ivan-almanza/notes-api/internal/auth"
+	"github.com/ivan-almanza/notes-api/internal/store"
+)
+
+// runUser implements `api user create|set-password|disable`.
+func runUser(args []string) error {
+	if len(args) == 0 {
+		return usageError{"user: expected create, set-password or disable"}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	switch args[0] {
+	case "create":
+		return runUserCreate(ctx, args[1:])
+	case "set-password":
+		return runUserSetPassword(ctx, args[1:])
+	case "disable":
+		return runUserDisable(ctx, args[1:])
+	}
+	return usageError{fmt.Sprintf("user: unknown command %q", args[0])}
+}
+
+func runUserCreate(ctx context.Context, args []string) error {
+	fs := newFlagSet("user create")
+	email := fs.String("email", "", "email of the new user")
+	password := fs.String("password", "", "password (read from stdin when empty)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return usageError{"user create: -email is required"}
+	}
+
+	plain, err := readPassword(*password)
+	if err != nil {
+		return err
+	}
+	user := &store.User{Email: *email, Password: plain}
+	if err := user.Validate(); err != nil {
+		return usageError{"user create: " + err.Error()}
+	}
+	if user.Password, err = auth.HashContext(ctx, plain); err != nil {
+		return err
+	}
+
+	users, closer, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	if err := users.Create(ctx, user); err != nil {
+		return fmt.Errorf("creating %s: %w", *email, err)
+	}
+	user.Password = ""
+	return report(user, func(w io.Writer) {
+		fmt.Fprintf(w, "created user %s (%s)\n", user.Email, user.ID)
+	})
+}
+
+func runUserSetPassword(ctx context.Context, args []string) error {
+	fs := newFlagSet("user set-password")
+	email := fs.String("email", "", "email of the user")
+	password := fs.String("password", "", "new password (read from stdin when empty)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return usageError{"user set-password: -email is required"}
+	}
+
+	plain, err := readPassword(*password)
+	if err != nil {
+		return err
+	}
+	if err := (&store.User{Email: *email, Password: plain}).Validate(); err != nil {
+		return usageError{"user set-password: " + err.Error()}
+	}
+	hash, err := auth.HashContext(ctx, plain)
+	if err != nil {
+		return err
+	}
+
+	users, closer, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	if err := users.SetPassword(ctx, *email, hash); err != nil {
+		return fmt.Errorf("setting password of %s: %w", *email, err)
+	}
+	return report(map[string]any{"email": *email, "password_reset": true, "tokens_revoked": true}, func(w io.Writer) {
+		fmt.Fprintf(w, "password of %s reset; existing tokens revoked\n", *email)
+	})
+}
+
+func runUserDisable(ctx context.Context, args []string) error {
+	fs := newFlagSet("user disable")
+	email := fs.String("email", "", "email of the user")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return usageError{"user disable: -email is required"}
+	}
+
+	users, closer, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	if err := users.DisableUser(ctx, *email); err != nil {
+		return fmt.Errorf("disabling %s: %w", *email, err)
+	}
+	return report(map[string]any{"email": *email, "disabled": true, "tokens_revoked": true}, func(w io.Writer) {
+		fmt.Fprintf(w, "disabled %s; existing tokens revoked\n", *email)
+	})
+}
+
+// runTokens implements `api tokens revoke-all`.
+func runTokens(args []string) error {
+	if len(args) == 0 || args[0] != "revoke-all" {
+		return usageError{"tokens: expected revoke-all"}
+	}
+	fs := newFlagSet("tokens revoke-all")
+	email := fs.String("email", "", "only revoke the tokens of this user")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	users, closer, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	n, err := users.RevokeTokens(ctx, *email)
+	if err != nil {
+		if *email != "" {
+			return fmt.Errorf("revoking tokens of %s: %w", *email, err)
+		}
+		return err
+	}
+	return report(map[string]any{"users": n}, func(w io.Writer) {
+		fmt.Fprintf(w, "revoked the tokens of %d user(s)\n", n)
+	})
+}
+
+// seededUser is one entry of the seed report.
+type seededUser struct {
+	ID      string `json:"id"`
+	Email   string `json:"email"`
+	Created bool   `json:"created"`
+	Notes   int    `json:"notes"`
+}
+
+// runSeed implements `api seed`: demo users with a few notes each. Existing
+// users are kept and get no extra notes, so seeding twice is harmless.
+func runSeed(args []string) error {
+	fs := newFlagSet("seed")
+	count := fs.Int("users", 3, "number of demo users")
+	notes := fs.Int("notes", 5, "notes per new user")
+	password := fs.String("password", "password123", "password of the demo users")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *count < 1 || *notes < 0 {
+		return usageError{"seed: -users must be positive and -notes non-negative"}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	s, closer, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	hash, err := auth.HashContext(ctx, *password)
+	if err != nil {
+		return err
+	}
+
+	var seeded []seededUser
+	for i := 1; i <= *count; i++ {
+		user := &store.User{Email: fmt.Sprintf("demo%d@example.com", i), Password: hash}
+		err := s.Create(ctx, user)
+		if errors.Is(err, store.ErrDuplicateEmail) {
+			existing, err := s.GetByEmail(ctx, user.Email)
+			if err != nil {
+				return err
+			}
+			seeded = append(seeded, seededUser{ID: existing.ID, Email: existing.Email})
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("creating %s: %w", user.Email, err)
+		}
+
+		entry := seededUser{ID: user.ID, Email: user.Email, Created: true}
+		for n := 1; n <= *notes; n++ {
+			note := &store.Note{UserID: user.ID, Content: fmt.Sprintf("Demo note %d of %s", n, user.Email)}
+			if err := s.CreateNote(ctx, note); err != nil {
+				return fmt.Errorf("creating notes for %s: %w", user.Email, err)
+			}
+			entry.Notes++
+		}
+		seeded = append(seeded, entry)
+	}
+
+	return report(seeded, func(w io.Writer) {
+		for _, u := range seeded {
+			if u.Created {
+				fmt.Fprintf(w, "created %s with %d notes\n", u.Email, u.Notes)
+			} else {
+				fmt.Fprintf(w, "kept    %s\n", u.Email)
+			}
+		}
+		fmt.Fprintf(w, "demo password: %s\n", *password)
+	})
+}
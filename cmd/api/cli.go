@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ivan-almanza/notes-api/internal/config"
+	"github.com/ivan-almanza/notes-api/internal/store"
+	"github.com/ivan-almanza/notes-api/internal/store/sqlite"
+)
+
+const usage = `Usage: api [command] [arguments]
+
+Commands:
+  serve                       run the HTTP server (default)
+  config print                show the effective configuration and its sources
+  migrate up|down|status      manage the database schema
+  user create                 create a user (-email, -password)
+  user set-password           reset a password and revoke the user's tokens
+  user disable                block logins and revoke the user's tokens
+  tokens revoke-all           revoke every token (or one user's with -email)
+  seed                        create demo users and notes
+
+Every command except serve accepts -json for machine-readable output.
+serve and config print take -config <file> and a flag for every setting;
+see api config print -h. Other commands read the file named by CONFIG_FILE.
+Secrets can be read from files (JWT_SECRET_FILE, DB_URL_FILE, ...); serve
+reloads them when the files change and on SIGHUP. Tokens signed with a
+rotated JWT secret verify for a day, or not at all with
+JWT_DROP_PREVIOUS_SECRET=true.
+TLS_CERT_FILE and TLS_KEY_FILE serve HTTPS and HTTP/2, reloaded the same way;
+TLS_CLIENT_AUTH and TLS_CLIENT_CA_FILE verify client certificates.
+Passwords are read from the first line of stdin when -password is omitted.
+
+Exit status: 0 success, 1 failure, 2 usage error, 3 not found, 4 conflict.
+`
+
+// Exit statuses of the admin commands, for scripts.
+const (
+	exitFailure  = 1
+	exitUsage    = 2
+	exitNotFound = 3
+	exitConflict = 4
+)
+
+// jsonOutput is set by the -json flag of the command being run.
+var jsonOutput bool
+
+// usageError reports a malformed command line.
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+func exitStatus(err error) int {
+	var usageErr usageError
+	switch {
+	case errors.As(err, &usageErr), errors.Is(err, flag.ErrHelp):
+		return exitUsage
+	case errors.Is(err, store.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, store.ErrDuplicateEmail):
+		return exitConflict
+	}
+	return exitFailure
+}
+
+// fail prints err to stderr, as JSON with -json, and returns the exit status.
+func fail(err error) int {
+	status := exitStatus(err)
+	if errors.Is(err, flag.ErrHelp) {
+		return status // the FlagSet already printed its usage
+	}
+	if jsonOutput {
+		json.NewEncoder(os.Stderr).Encode(map[string]any{"error": err.Error(), "exit_status": status})
+	} else {
+		fmt.Fprintln(os.Stderr, "api:", err)
+	}
+	return status
+}
+
+// newFlagSet returns a FlagSet for a subcommand with the shared -json flag.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("api "+name, flag.ContinueOnError)
+	fs.BoolVar(&jsonOutput, "json", false, "write results as JSON")
+	return fs
+}
+
+// report writes v as indented JSON with -json and calls text otherwise.
+func report(v any, text func(w io.Writer)) error {
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	text(os.Stdout)
+	return nil
+}
+
+// openStore loads the db settings and connects to the database. Close the
+// returned io.Closer when done.
+func openStore() (store.Store, io.Closer, error) {
+	cfg, err := config.LoadDBConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg.URL == config.MemoryDBURL {
+		return nil, nil, errors.New("admin commands need a Postgres or SQLite DB_URL; the in-memory store lives inside `api serve`")
+	}
+	if path, ok := config.SQLitePath(cfg.URL); ok {
+		s, err := sqlite.Open(context.Background(), path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open SQLite database: %w", err)
+		}
+		return s, s, nil
+	}
+
+	db, err := openPostgres(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return store.NewPostgresStore(db, store.WithQueryTimeout(cfg.QueryTimeout)), db, nil
+}
+
+// readPassword returns password, or the first line of stdin when it is
+// empty, so passwords stay out of the process list and shell history.
+func readPassword(password string) (string, error) {
+	if password != "" {
+		return password, nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("reading password from stdin: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/ivan-almanza/notes-api/internal/config"
+)
+
+// runConfig implements `api config print`, which takes the same -config and
+// setting flags as serve and shows the configuration serve would run with.
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return usageError{"config: expected print"}
+	}
+	fs := newFlagSet("config print")
+	flags := config.RegisterFlags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError{fmt.Sprintf("config print: unexpected argument %q", fs.Arg(0))}
+	}
+
+	cfg, err := config.LoadConfig(flags)
+	if err != nil {
+		return err
+	}
+	settings := cfg.Settings()
+	return report(settings, func(w io.Writer) {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+		for _, s := range settings {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Key, s.Value, s.Source)
+		}
+		tw.Flush()
+	})
+}
@@ -1,74 +1,433 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/ivan-almanza/notes-api/internal/api"
 	"github.com/ivan-almanza/notes-api/internal/auth"
 	"github.com/ivan-almanza/notes-api/internal/config"
+	"github.com/ivan-almanza/notes-api/internal/idempotency"
+	"github.com/ivan-almanza/notes-api/internal/metrics"
+	"github.com/ivan-almanza/notes-api/internal/migrate"
+	"github.com/ivan-almanza/notes-api/internal/ratelimit"
 	"github.com/ivan-almanza/notes-api/internal/store"
+	"github.com/ivan-almanza/notes-api/internal/store/memory"
+	"github.com/ivan-almanza/notes-api/internal/store/sqlite"
+	"github.com/ivan-almanza/notes-api/internal/tlsconfig"
+	"github.com/ivan-almanza/notes-api/internal/tracing"
 
 	_ "github.com/lib/pq"
 )
 
 func main() {
-	// 1. Load Configuration
-	cfg, err := config.LoadConfig()
+	// Setup Logging (level is adjusted once config is loaded)
+	logLevel := new(slog.LevelVar)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel}))
+	slog.SetDefault(logger)
+
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	var err error
+	switch command {
+	case "serve":
+		err = run(logger, logLevel, args)
+		if err != nil && !errors.Is(err, flag.ErrHelp) && !errors.As(err, new(usageError)) {
+			logger.Error("Server exited with error", "error", err)
+			os.Exit(1)
+		}
+	case "config":
+		err = runConfig(args)
+	case "migrate":
+		err = runMigrate(args)
+	case "user":
+		err = runUser(args)
+	case "tokens":
+		err = runTokens(args)
+	case "seed":
+		err = runSeed(args)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		err = usageError{fmt.Sprintf("unknown command %q", command)}
+	}
+
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		var usageErr usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprint(os.Stderr, usage)
+		}
+		os.Exit(fail(err))
 	}
+}
 
-	// 2. Configure Auth Secret
-	auth.SetSecret(cfg.JWTSecret)
+func run(logger *slog.Logger, logLevel *slog.LevelVar, args []string) error {
+	// 1. Load Configuration: defaults, then the config file, the environment
+	// and the command line
+	fs := flag.NewFlagSet("api serve", flag.ContinueOnError)
+	flags := config.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError{fmt.Sprintf("serve: unexpected argument %q", fs.Arg(0))}
+	}
+	cfg, err := config.LoadConfig(flags)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	logLevel.Set(cfg.Log.Level)
+
+	// 2. Load the token signing secret; the reloader swaps it when it rotates
+	keys := auth.NewKeys(cfg.Auth.JWTSecret)
 
-	// 3. Connect to Database
-	db, err := sql.Open("postgres", cfg.DBURL)
+	// 3. Setup Tracing
+	tracer, err := newTracer(cfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		return fmt.Errorf("failed to configure tracing: %w", err)
+	}
+	if tracer != nil {
+		tracing.SetTracer(tracer)
 	}
-	defer db.Close()
 
-	if err := db.Ping(); err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
+	// 4. Connect to Postgres, open a SQLite file, or keep everything in memory
+	var appStore store.Store
+	var db *store.Pool
+	var replicas []*store.Pool
+	var postgresStore *store.PostgresStore
+	health := api.NewHealthChecker()
+	if sqlitePath, ok := config.SQLitePath(cfg.DB.URL); ok {
+		sqliteStore, err := sqlite.Open(context.Background(), sqlitePath)
+		if err != nil {
+			return fmt.Errorf("failed to open SQLite database: %w", err)
+		}
+		defer sqliteStore.Close()
+		logger.Info("Using the SQLite store", "path", sqlitePath)
+		appStore = sqliteStore
+		health.Register("sqlite", 2*time.Second, sqliteStore.Ping)
+	} else if cfg.DB.URL == config.MemoryDBURL {
+		logger.Warn("Using the in-memory store; all data is lost on exit")
+		appStore = memory.New()
+	} else {
+		primary, err := connectDatabase(cfg, logger)
+		if err != nil {
+			return err
+		}
+		db = store.NewPool(primary)
+		defer db.Close()
+		replicas, err = connectReplicas(cfg)
+		if err != nil {
+			return err
+		}
+		dbStats := metrics.NewDBStatsCollector()
+		dbStats.Add("primary", db)
+		replicaDBs := make([]store.DB, len(replicas))
+		for i, replica := range replicas {
+			defer replica.Close()
+			dbStats.Add(fmt.Sprintf("replica-%d", i), replica)
+			replicaDBs[i] = replica
+		}
+		metrics.Register(dbStats)
+		postgresStore = store.NewPostgresStore(db,
+			store.WithQueryTimeout(cfg.DB.QueryTimeout),
+			store.WithReplicas(replicaDBs, cfg.DB.ReplicaWindow),
+		)
+		appStore = postgresStore
 	}
 
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(25)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	// 5. Initialize Handlers
+	authHandler := api.NewAuthHandler(appStore, keys)
+	notesHandler := api.NewNotesHandler(appStore)
+	oauthHandler := api.NewOAuthHandler(appStore, keys, cfg.Auth.DeviceVerificationURI)
 
-	// 4. Initialize Store and Handlers
-	postgresStore := store.NewPostgresStore(db)
-	authHandler := api.NewAuthHandler(postgresStore)
-	notesHandler := api.NewNotesHandler(postgresStore)
+	var limiter ratelimit.Limiter = ratelimit.NewMemoryLimiter()
+	var postgresLimiter *ratelimit.PostgresLimiter
+	if cfg.RateLimit.Backend == "postgres" {
+		postgresLimiter = ratelimit.NewPostgresLimiter(db)
+		limiter = postgresLimiter
+	}
+	rateLimiter := api.NewRateLimiter(limiter, cfg.RateLimit.Default, cfg.RateLimit.Routes)
+	if cfg.RateLimit.TrustProxyHeaders {
+		rateLimiter.TrustProxies(cfg.RateLimit.TrustedProxies)
+	}
 
-	// 5. Setup Router
-	mux := http.NewServeMux()
+	var idempotencyStore idempotency.Store = idempotency.NewMemoryStore()
+	var postgresIdempotency *idempotency.PostgresStore
+	if cfg.Idempotency.Backend == "postgres" {
+		postgresIdempotency = idempotency.NewPostgresStore(db)
+		idempotencyStore = postgresIdempotency
+	}
+	idempotent := api.NewIdempotency(idempotencyStore, cfg.Idempotency.TTL, cfg.Idempotency.Wait)
 
-	// Auth Routes
-	mux.HandleFunc("POST /auth/register", authHandler.Register)
-	mux.HandleFunc("POST /auth/login", authHandler.Login)
+	if db != nil {
+		health.Register("postgres", 2*time.Second, db.PingContext)
+	}
 
-	// Notes Routes (Protected)
-	mux.HandleFunc("POST /notes", func(w http.ResponseWriter, r *http.Request) {
-		api.WithAuth(http.HandlerFunc(notesHandler.CreateNote)).ServeHTTP(w, r)
-	})
-	mux.HandleFunc("GET /notes", func(w http.ResponseWriter, r *http.Request) {
-		api.WithAuth(http.HandlerFunc(notesHandler.GetNotes)).ServeHTTP(w, r)
-	})
+	// 6. Setup Router: every route comes from the documented route table,
+	// so /openapi.json always matches what is served
+	router := api.NewRouter(keys, rateLimiter, idempotent, appStore)
+	router.Handle(api.Handlers{
+		Auth:    authHandler,
+		Notes:   notesHandler,
+		OAuth:   oauthHandler,
+		Health:  health,
+		Metrics: metrics.Handler(),
+	}.Routes()...)
 
-	// 6. Start Server
+	// 7. Wrap with request ID, tracing, access logging, metrics and CORS
+	var routes http.Handler = router
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		routes = api.WithCORS(api.CORSOptions{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedMethods:   cfg.CORS.AllowedMethods,
+			AllowedHeaders:   cfg.CORS.AllowedHeaders,
+			ExposedHeaders:   api.DefaultExposedHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           cfg.CORS.MaxAge,
+		}, router)
+	}
+	if cfg.TLS.ClientIdentity {
+		routes = api.WithClientIdentity(routes)
+	}
+	handler := api.WithRequestID(api.WithTracing(api.WithRequestLogging(logger, api.WithMetrics(routes))))
+
+	// 8. Start Background Workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		oauthHandler.PurgeExpiredDeviceCodes(workerCtx, time.Minute)
+	}()
+
+	if postgresStore != nil && len(cfg.DB.ReplicaURLs) > 0 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			postgresStore.MonitorReplicas(workerCtx, 5*time.Second)
+		}()
+	}
+
+	if postgresLimiter != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			postgresLimiter.PurgeExpired(workerCtx, time.Minute)
+		}()
+	}
+
+	if postgresIdempotency != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			postgresIdempotency.PurgeExpired(workerCtx, time.Minute)
+		}()
+	}
+
+	var certs *tlsconfig.Reloader
+	if cfg.TLS.Enabled() {
+		certs, err = tlsconfig.New(tlsconfig.Options{
+			CertFile:     cfg.TLS.CertFile,
+			KeyFile:      cfg.TLS.KeyFile,
+			ClientCAFile: cfg.TLS.ClientCAFile,
+			ClientAuth:   tlsconfig.ParseClientAuth(cfg.TLS.ClientAuth),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	reloader := &reloader{flags: flags, logger: logger, keys: keys, primary: db, replicas: replicas, certs: certs, cfg: cfg}
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		reloader.run(workerCtx, 10*time.Second)
+	}()
+
+	// 9. Start Server
 	server := &http.Server{
-		Addr:         ":" + cfg.Port,
-		Handler:      mux,
+		Addr:         ":" + cfg.Server.Port,
+		Handler:      handler,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
+		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelError),
+	}
+	if certs != nil {
+		server.TLSConfig = certs.Config()
+		server.Protocols = new(http.Protocols)
+		server.Protocols.SetHTTP1(true)
+		server.Protocols.SetHTTP2(true)
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		if certs == nil {
+			logger.Info("Starting server", "port", cfg.Server.Port)
+			serverErr <- server.ListenAndServe()
+			return
+		}
+		logger.Info("Starting server", "port", cfg.Server.Port, "tls", true, "client_auth", cfg.TLS.ClientAuth)
+		// The certificate comes from server.TLSConfig
+		serverErr <- server.ListenAndServeTLS("", "")
+	}()
+
+	// 10. Wait for a shutdown signal
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	select {
+	case err := <-serverErr:
+		return fmt.Errorf("server failed to start: %w", err)
+	case <-signalCtx.Done():
 	}
+	stopSignals() // a second signal terminates immediately
+
+	// 11. Drain: fail readiness so load balancers stop routing new traffic,
+	// close the listener, then give in-flight requests and background workers
+	// until the deadline. The database pool is closed last by the deferred
+	// db.Close above.
+	logger.Info("Shutting down", "drain_delay", cfg.Shutdown.DrainDelay.String(), "timeout", cfg.Shutdown.Timeout.String())
+	health.SetDraining(true)
+	time.Sleep(cfg.Shutdown.DrainDelay)
 
-	log.Printf("Starting server on port %s...", cfg.Port)
-	if err := server.ListenAndServe(); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+	defer cancel()
+
+	var errs []error
+	if err := server.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to drain HTTP server: %w", err))
 	}
+
+	stopWorkers()
+	if err := waitGroup(ctx, &workers); err != nil {
+		errs = append(errs, fmt.Errorf("background workers did not stop: %w", err))
+	}
+
+	if tracer != nil {
+		if err := tracer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to flush traces: %w", err))
+		}
+	}
+
+	if len(errs) == 0 {
+		logger.Info("Shutdown complete")
+	}
+	return errors.Join(errs...)
+}
+
+// connectDatabase opens the Postgres pool, waits up to db.connect_timeout
+// for the database to answer and applies pending migrations when
+// db.auto_migrate is set.
+func connectDatabase(cfg *config.Config, logger *slog.Logger) (*sql.DB, error) {
+	db, err := openPostgres(&cfg.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.DB.AutoMigrate {
+		migrator, err := migrate.New(db)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to load migrations: %w", err)
+		}
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+		logger.Info("Database schema is up to date", "applied", len(applied))
+	}
+	return db, nil
+}
+
+// openPostgres opens the primary pool sized by the db settings and waits up
+// to db.connect_timeout for it to answer.
+func openPostgres(cfg *config.DBConfig) (*sql.DB, error) {
+	db, err := store.OpenPostgres(cfg.URL, cfg.Pool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
+	defer cancel()
+	if err := store.WaitForPostgres(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+	return db, nil
+}
+
+// connectReplicas opens the read replicas. They are not pinged: a replica
+// that is down at startup is skipped until MonitorReplicas sees it healthy.
+func connectReplicas(cfg *config.Config) ([]*store.Pool, error) {
+	var replicas []*store.Pool
+	for i, url := range cfg.DB.ReplicaURLs {
+		replica, err := store.OpenPostgres(url, cfg.DB.Pool)
+		if err != nil {
+			for _, opened := range replicas {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("failed to open replica %d: %w", i, err)
+		}
+		replicas = append(replicas, store.NewPool(replica))
+	}
+	return replicas, nil
+}
+
+// waitGroup waits for wg or until ctx is done.
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// newTracer builds the tracer selected by the configuration, or nil when
+// tracing is disabled.
+func newTracer(cfg *config.Config) (*tracing.Tracer, error) {
+	var exporter tracing.Exporter
+	switch cfg.Tracing.Exporter {
+	case "none":
+		return nil, nil
+	case "stdout":
+		exporter = tracing.NewWriterExporter(os.Stdout, nil)
+	case "file":
+		f, err := os.OpenFile(cfg.Tracing.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		exporter = tracing.NewWriterExporter(f, f)
+	case "otlp":
+		exporter = tracing.NewOTLPExporter(cfg.Tracing.OTLPEndpoint, cfg.Tracing.OTLPHeaders)
+	}
+
+	return tracing.NewTracer(tracing.Options{
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		Exporter:    exporter,
+	}), nil
 }
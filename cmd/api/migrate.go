@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/config"
+	"github.com/ivan-almanza/notes-api/internal/migrate"
+)
+
+// migrationReport is one line of `api migrate` JSON output.
+type migrationReport struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Status    string     `json:"status"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// runMigrate implements `api migrate up|down|status`. Only db.url has to be
+// set.
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return usageError{"migrate: expected up, down or status"}
+	}
+	command := args[0]
+	fs := newFlagSet("migrate " + command)
+	steps := 0
+	if command == "down" {
+		fs.IntVar(&steps, "steps", 1, "number of migrations to roll back")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	var run func(ctx context.Context, m *migrate.Migrator) ([]migrationReport, error)
+	switch command {
+	case "up":
+		run = func(ctx context.Context, m *migrate.Migrator) ([]migrationReport, error) {
+			applied, err := m.Up(ctx)
+			return reports(applied, "applied"), err
+		}
+	case "down":
+		if steps < 1 {
+			return usageError{"migrate down: -steps must be at least 1"}
+		}
+		run = func(ctx context.Context, m *migrate.Migrator) ([]migrationReport, error) {
+			reverted, err := m.Down(ctx, steps)
+			return reports(reverted, "reverted"), err
+		}
+	case "status":
+		run = migrationStatus
+	default:
+		return usageError{fmt.Sprintf("migrate: unknown command %q", command)}
+	}
+
+	dbConfig, err := config.LoadDBConfig()
+	if err != nil {
+		return err
+	}
+	if dbConfig.URL == config.MemoryDBURL {
+		return errors.New("the in-memory store has no schema to migrate")
+	}
+	if _, ok := config.SQLitePath(dbConfig.URL); ok {
+		return errors.New("SQLite databases are migrated when they are opened")
+	}
+	db, err := openPostgres(dbConfig)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migrate.New(db)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Report what was done even when a later migration failed.
+	result, err := run(ctx, migrator)
+	if result == nil {
+		result = []migrationReport{}
+	}
+	if reportErr := report(result, func(w io.Writer) { writeMigrations(w, command, result) }); err == nil {
+		err = reportErr
+	}
+	return err
+}
+
+func migrationStatus(ctx context.Context, m *migrate.Migrator) ([]migrationReport, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]migrationReport, 0, len(statuses))
+	for _, s := range statuses {
+		r := migrationReport{Version: s.Version, Name: s.Name, Status: "pending"}
+		if s.Applied {
+			r.Status, r.AppliedAt = "applied", &s.AppliedAt
+		}
+		if s.Modified {
+			r.Status = "modified"
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+func reports(migrations []migrate.Migration, status string) []migrationReport {
+	result := make([]migrationReport, 0, len(migrations))
+	for _, m := range migrations {
+		result = append(result, migrationReport{Version: m.Version, Name: m.Name, Status: status})
+	}
+	return result
+}
+
+func writeMigrations(w io.Writer, command string, result []migrationReport) {
+	if command != "status" {
+		if len(result) == 0 {
+			fmt.Fprintln(w, "nothing to do")
+		}
+		for _, r := range result {
+			fmt.Fprintf(w, "%-8s %04d_%s\n", r.Status, r.Version, r.Name)
+		}
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, r := range result {
+		appliedAt := ""
+		if r.AppliedAt != nil {
+			appliedAt = r.AppliedAt.Local().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%04d\t%s\t%s\t%s\n", r.Version, r.Name, r.Status, appliedAt)
+	}
+	tw.Flush()
+}
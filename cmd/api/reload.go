@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/auth"
+	"github.com/ivan-almanza/notes-api/internal/config"
+	"github.com/ivan-almanza/notes-api/internal/store"
+	"github.com/ivan-almanza/notes-api/internal/tlsconfig"
+)
+
+// reloader reloads the configuration on SIGHUP and when a file a secret was
+// read from or a TLS file changes, as when Kubernetes rotates a mounted
+// secret. It swaps in the new JWT secret and TLS certificate and reconnects
+// database pools whose URL changed; in-flight requests finish on the old
+// pool. Other settings only change on restart.
+type reloader struct {
+	flags    *config.Flags
+	logger   *slog.Logger
+	keys     *auth.Keys
+	primary  *store.Pool // nil unless the store is Postgres
+	replicas []*store.Pool
+	certs    *tlsconfig.Reloader // nil unless the server speaks HTTPS
+
+	// cfg is the configuration in effect
+	cfg *config.Config
+}
+
+// settings that reload applies; changes to any other need a restart.
+var reloadable = map[string]bool{"auth.jwt_secret": true, "db.url": true, "db.replica_urls": true}
+
+// run reloads on SIGHUP, and when a watched file has changed since the last
+// check every interval, until ctx is done.
+func (r *reloader) run(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	version := fileVersion(r.files())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.logger.Info("Reloading configuration", "trigger", "SIGHUP")
+		case <-ticker.C:
+			if fileVersion(r.files()) == version {
+				continue
+			}
+			r.logger.Info("Reloading configuration", "trigger", "file changed")
+		}
+
+		r.reload(ctx)
+		// Taken after the reload, so a rejected file is not retried until it
+		// changes again.
+		version = fileVersion(r.files())
+	}
+}
+
+// files returns the secret and TLS files to watch.
+func (r *reloader) files() []string {
+	files := r.cfg.SecretFiles()
+	if r.certs != nil {
+		files = append(files, r.certs.Files()...)
+	}
+	return files
+}
+
+// reload loads the configuration again and applies what it can. An invalid
+// configuration, or a database that does not accept the new URL, leaves the
+// current one in effect.
+func (r *reloader) reload(ctx context.Context) {
+	cfg, err := config.LoadConfig(r.flags)
+	if err != nil {
+		r.logger.Error("Keeping the current configuration; the new one is invalid", "error", err)
+		return
+	}
+
+	r.keys.Set(cfg.Auth.JWTSecret)
+	if cfg.Auth.DropPreviousSecret {
+		r.keys.DropPrevious()
+	}
+
+	if r.certs != nil {
+		if err := r.certs.Reload(); err != nil {
+			r.logger.Error("Keeping the current TLS certificate", "error", err)
+		}
+	}
+
+	if cfg.DB.URL != r.cfg.DB.URL && r.primary == nil {
+		r.logger.Warn("Only a Postgres db.url changes without a restart")
+		cfg.DB.URL = r.cfg.DB.URL
+	}
+	if cfg.DB.URL != r.cfg.DB.URL {
+		if err := reconnect(ctx, r.primary, cfg.DB.URL, cfg); err != nil {
+			r.logger.Error("Keeping the current database connection", "error", err)
+			cfg.DB.URL = r.cfg.DB.URL // retried on the next reload
+		} else {
+			r.logger.Info("Reconnected to the database")
+		}
+	}
+
+	if len(cfg.DB.ReplicaURLs) != len(r.replicas) {
+		r.logger.Warn("The number of replicas only changes on restart")
+		cfg.DB.ReplicaURLs = r.cfg.DB.ReplicaURLs
+	}
+	for i, url := range cfg.DB.ReplicaURLs {
+		if url == r.cfg.DB.ReplicaURLs[i] {
+			continue
+		}
+		if err := reconnect(ctx, r.replicas[i], url, cfg); err != nil {
+			r.logger.Error("Keeping the current replica connection", "replica", i, "error", err)
+			cfg.DB.ReplicaURLs[i] = r.cfg.DB.ReplicaURLs[i]
+		} else {
+			r.logger.Info("Reconnected to the replica", "replica", i)
+		}
+	}
+
+	var restart []string
+	old := r.cfg.Settings()
+	for i, s := range cfg.Settings() {
+		if !reloadable[s.Key] && s.Value != old[i].Value {
+			restart = append(restart, s.Key)
+		}
+	}
+	if len(restart) > 0 {
+		r.logger.Warn("Some changed settings take effect on restart", "settings", restart)
+	}
+
+	r.cfg = cfg
+	r.logger.Info("Configuration reloaded")
+}
+
+// reconnect opens a pool for dsn, waits up to db.connect_timeout for it to
+// answer and swaps it into pool.
+func reconnect(ctx context.Context, pool *store.Pool, dsn string, cfg *config.Config) error {
+	db, err := store.OpenPostgres(dsn, cfg.DB.Pool)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.DB.ConnectTimeout)
+	defer cancel()
+	if err := store.WaitForPostgres(ctx, db); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	pool.Replace(db)
+	return nil
+}
+
+// fileVersion hashes the contents of files, so a rotation is noticed even
+// when it swaps a symlink and keeps the modification time.
+func fileVersion(files []string) string {
+	h := sha256.New()
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(h, "%s: %v\n", file, err)
+			continue
+		}
+		fmt.Fprintf(h, "%s: %d\n", file, len(data))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/ivan-almanza/notes-api/pkg/client"
+	"golang.org/x/term"
+)
+
+// deviceClientID identifies the CLI in the device authorization grant.
+const deviceClientID = "notes-cli"
+
+func runLogin(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("login", "")
+	email := fs.String("email", "", "account email (prompted when empty)")
+	device := fs.Bool("device", false, "approve the login from another, logged-in device")
+	if err := a.parse(fs, args, false); err != nil {
+		return err
+	}
+
+	creds := &credentials{Server: a.server}
+	if *device {
+		da, err := a.client.RequestDeviceCode(ctx, deviceClientID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stderr, "Open %s and enter the code %s\n", da.VerificationURI, da.UserCode)
+		fmt.Fprintln(a.stderr, "Waiting for approval…")
+		token, err := a.client.WaitForDeviceToken(ctx, deviceClientID, da)
+		if err != nil {
+			return err
+		}
+		creds.Token = token.AccessToken
+	} else {
+		if *email == "" {
+			line, err := a.prompt("Email: ")
+			if err != nil {
+				return err
+			}
+			*email = line
+		}
+		password := os.Getenv("NOTES_PASSWORD")
+		if password == "" {
+			line, err := a.promptPassword("Password: ")
+			if err != nil {
+				return err
+			}
+			password = line
+		}
+
+		token, err := a.client.Login(ctx, *email, password)
+		if err != nil {
+			return err
+		}
+		creds.Email, creds.Token = *email, token
+	}
+
+	if err := saveCredentials(a.credentialsPath, creds); err != nil {
+		return err
+	}
+	if creds.Email != "" {
+		fmt.Fprintf(a.stderr, "Logged in to %s as %s\n", a.server, creds.Email)
+	} else {
+		fmt.Fprintf(a.stderr, "Logged in to %s\n", a.server)
+	}
+	return nil
+}
+
+func runLogout(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("logout", "")
+	if err := a.parse(fs, args, false); err != nil {
+		return err
+	}
+	return removeCredentials(a.credentialsPath)
+}
+
+func runNew(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("new", "")
+	message := fs.String("m", "", "note content (opens $EDITOR when empty)")
+	if err := a.parse(fs, args, true); err != nil {
+		return err
+	}
+
+	content := *message
+	if content == "" {
+		edited, err := a.edit("")
+		if err != nil {
+			return err
+		}
+		content = edited
+	}
+	if strings.TrimSpace(content) == "" {
+		return errors.New("aborting: empty note")
+	}
+
+	id, err := a.client.CreateNote(ctx, content)
+	if err != nil {
+		return err
+	}
+	if a.output == outputJSON {
+		return writeJSON(a.stdout, map[string]string{"id": id})
+	}
+	fmt.Fprintln(a.stdout, id)
+	return nil
+}
+
+func runList(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("ls", "")
+	limit := fs.Int("limit", 0, "show at most this many notes (0 shows all)")
+	if err := a.parse(fs, args, true); err != nil {
+		return err
+	}
+
+	var notes []*client.Note
+	for note, err := range a.client.Notes(ctx, 100) {
+		if err != nil {
+			return err
+		}
+		notes = append(notes, note)
+		if *limit > 0 && len(notes) == *limit {
+			break
+		}
+	}
+	return writeNotes(a.stdout, a.output, notes)
+}
+
+func runShow(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("show", "<id>")
+	if err := a.parse(fs, args, true); err != nil {
+		return err
+	}
+	id, err := singleArg(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	note, err := a.client.GetNote(ctx, id)
+	if err != nil {
+		return err
+	}
+	return writeNote(a.stdout, a.output, note)
+}
+
+func runEdit(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("edit", "<id>")
+	message := fs.String("m", "", "new content (opens $EDITOR when empty)")
+	if err := a.parse(fs, args, true); err != nil {
+		return err
+	}
+	id, err := singleArg(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	content := *message
+	if content == "" {
+		note, err := a.client.GetNote(ctx, id)
+		if err != nil {
+			return err
+		}
+		edited, err := a.edit(note.Content)
+		if err != nil {
+			return err
+		}
+		if edited == note.Content {
+			fmt.Fprintln(a.stderr, "No changes")
+			return nil
+		}
+		content = edited
+	}
+	if strings.TrimSpace(content) == "" {
+		return errors.New("aborting: empty note; use `notes rm` to delete it")
+	}
+
+	note, err := a.client.UpdateNote(ctx, id, content)
+	if err != nil {
+		return err
+	}
+	return writeNote(a.stdout, a.output, note)
+}
+
+func runRemove(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("rm", "<id>...")
+	if err := a.parse(fs, args, true); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	for _, id := range fs.Args() {
+		if err := a.client.DeleteNote(ctx, id); err != nil {
+			return fmt.Errorf("deleting %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func runSearch(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("search", "<text>")
+	limit := fs.Int("limit", 0, "show at most this many notes (0 uses the server default)")
+	if err := a.parse(fs, args, true); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	notes, err := a.client.SearchNotes(ctx, strings.Join(fs.Args(), " "), *limit)
+	if err != nil {
+		return err
+	}
+	return writeNotes(a.stdout, a.output, notes)
+}
+
+func singleArg(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("expected exactly one note ID, got %d arguments", len(args))
+	}
+	return args[0], nil
+}
+
+// prompt writes label to stderr and reads one line from stdin.
+func (a *app) prompt(label string) (string, error) {
+	if a.input == nil {
+		a.input = bufio.NewReader(a.stdin)
+	}
+	fmt.Fprint(a.stderr, label)
+	line, err := a.input.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("reading input: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// promptPassword is prompt without echoing the input when stdin is a
+// terminal. Piped input is read as a plain line.
+func (a *app) promptPassword(label string) (string, error) {
+	f, ok := a.stdin.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return a.prompt(label)
+	}
+	fmt.Fprint(a.stderr, label)
+	password, err := term.ReadPassword(int(f.Fd()))
+	// The newline typed by the user was not echoed either.
+	fmt.Fprintln(a.stderr)
+	if err != nil {
+		return "", fmt.Errorf("reading password: %w", err)
+	}
+	return string(password), nil
+}
+
+// edit opens content in $VISUAL or $EDITOR (default vi) and returns the
+// saved text.
+func (a *app) edit(content string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	f, err := os.CreateTemp("", "note-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	// The editor may carry arguments, e.g. "code --wait".
+	argv := append(strings.Fields(editor), f.Name())
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running editor %q: %w", editor, err)
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
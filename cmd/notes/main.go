@@ -0,0 +1,188 @@
+// Command notes is a command-line client for the Notes API.
+//
+//	notes login -email ada@example.com
+//	notes new -m "buy milk"
+//	notes ls -o json
+//
+// The token from login is cached in the user config directory, so later
+// commands run without credentials.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/ivan-almanza/notes-api/pkg/client"
+)
+
+const defaultServer = "http://localhost:8080"
+
+const usage = `Usage: notes [-server URL] [-o table|json|plain] <command> [arguments]
+
+Commands:
+  login   log in and cache the token (-email, or -device for the device flow)
+  logout  forget the cached token
+  new     create a note (-m text, otherwise opens $EDITOR)
+  ls      list notes (-limit N)
+  show    print a note
+  edit    edit a note in $EDITOR (or replace it with -m text)
+  rm      delete notes
+  search  find notes containing a text (-limit N)
+
+Environment:
+  NOTES_SERVER    API base URL (default ` + defaultServer + `)
+  NOTES_PASSWORD  password for login instead of prompting
+  EDITOR          editor for new and edit
+`
+
+// commands maps each subcommand to its implementation, which receives the
+// arguments after the command name.
+var commands = map[string]func(ctx context.Context, a *app, args []string) error{
+	"login":  runLogin,
+	"logout": runLogout,
+	"new":    runNew,
+	"ls":     runList,
+	"show":   runShow,
+	"edit":   runEdit,
+	"rm":     runRemove,
+	"search": runSearch,
+}
+
+// errUsage makes main print the usage and exit with status 2.
+var errUsage = errors.New("usage")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	a := &app{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	err := a.main(ctx, os.Args[1:])
+	switch {
+	case err == nil:
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		os.Exit(2)
+	default:
+		fmt.Fprintln(os.Stderr, "notes:", err)
+		if errors.Is(err, client.ErrUnauthorized) {
+			fmt.Fprintln(os.Stderr, "Run `notes login` to sign in again.")
+		}
+		os.Exit(1)
+	}
+}
+
+// app carries the global options and streams shared by every command.
+type app struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	input  *bufio.Reader
+
+	server string
+	output string
+	// credentialsPath overrides the default location, for tests.
+	credentialsPath string
+
+	creds  *credentials
+	client *client.Client
+}
+
+func (a *app) main(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("notes", flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() { fmt.Fprint(a.stderr, usage) }
+	a.globalFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	name, args := fs.Arg(0), fs.Args()[1:]
+	run, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(a.stderr, "notes: unknown command %q\n\n", name)
+		fs.Usage()
+		return errUsage
+	}
+	return run(ctx, a, args)
+}
+
+// globalFlags registers -server and -o on fs. Every subcommand registers them
+// too, so they may appear before or after the command name.
+func (a *app) globalFlags(fs *flag.FlagSet) {
+	fs.StringVar(&a.server, "server", a.server, "API base URL")
+	fs.StringVar(&a.output, "o", a.output, "output format: table, json or plain")
+}
+
+// flagSet returns a FlagSet for a subcommand with the global flags.
+func (a *app) flagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet("notes "+name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(a.stderr, "Usage: notes %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	a.globalFlags(fs)
+	return fs
+}
+
+// parse parses a subcommand's flags, then loads the cached credentials and
+// builds the API client. Commands that needsAuth fail early without a token.
+func (a *app) parse(fs *flag.FlagSet, args []string, needsAuth bool) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	switch a.output {
+	case "":
+		a.output = outputTable
+	case outputTable, outputJSON, outputPlain:
+	default:
+		return fmt.Errorf("unknown output format %q", a.output)
+	}
+	return a.connect(needsAuth)
+}
+
+// connect loads the cached credentials and builds the API client. The
+// server comes from -server, then NOTES_SERVER, then the cached login.
+func (a *app) connect(needsAuth bool) error {
+	if a.credentialsPath == "" {
+		path, err := defaultCredentialsPath()
+		if err != nil {
+			return err
+		}
+		a.credentialsPath = path
+	}
+	creds, err := loadCredentials(a.credentialsPath)
+	if err != nil {
+		return err
+	}
+	a.creds = creds
+
+	if a.server == "" {
+		a.server = os.Getenv("NOTES_SERVER")
+	}
+	if a.server == "" {
+		a.server = creds.Server
+	}
+	if a.server == "" {
+		a.server = defaultServer
+	}
+
+	var opts []client.Option
+	if creds.Token != "" && creds.Server == a.server {
+		opts = append(opts, client.WithToken(creds.Token))
+	} else if needsAuth {
+		return fmt.Errorf("not logged in to %s; run `notes login`", a.server)
+	}
+	opts = append(opts, client.WithUserAgent("notes-cli"))
+	a.client = client.New(a.server, opts...)
+	return nil
+}
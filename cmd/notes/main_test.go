@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/api"
+	"github.com/ivan-almanza/notes-api/pkg/client"
+)
+
+func TestCredentials_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes", "credentials.json")
+
+	creds, err := loadCredentials(path)
+	if err != nil || *creds != (credentials{}) {
+		t.Fatalf("Expected empty credentials for a missing file, got %+v, %v", creds, err)
+	}
+
+	want := &credentials{Server: "http://notes.test", Email: "ada@example.com", Token: "tok"}
+	if err := saveCredentials(path, want); err != nil {
+		t.Fatalf("saveCredentials failed: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("Expected mode 0600, got %o", perm)
+	}
+
+	got, err := loadCredentials(path)
+	if err != nil || *got != *want {
+		t.Errorf("Expected %+v, got %+v, %v", want, got, err)
+	}
+
+	if err := removeCredentials(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeCredentials(path); err != nil {
+		t.Errorf("Removing missing credentials should succeed, got %v", err)
+	}
+}
+
+func TestWriteNotes_Formats(t *testing.T) {
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	notes := []*client.Note{
+		{ID: "n1", Content: "first line\nsecond line", CreatedAt: created, UpdatedAt: created},
+		{ID: "n2", Content: strings.Repeat("x", 80), CreatedAt: created, UpdatedAt: created},
+	}
+
+	var plain bytes.Buffer
+	writeNotes(&plain, outputPlain, notes)
+	if want := "n1\tfirst line …\nn2\t" + strings.Repeat("x", 80) + "\n"; plain.String() != want {
+		t.Errorf("Unexpected plain output %q", plain.String())
+	}
+
+	var table bytes.Buffer
+	writeNotes(&table, outputTable, notes)
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "ID") || !strings.HasSuffix(lines[2], "…") {
+		t.Errorf("Unexpected table output:\n%s", table.String())
+	}
+
+	var out bytes.Buffer
+	writeNotes(&out, outputJSON, nil)
+	if strings.TrimSpace(out.String()) != "[]" {
+		t.Errorf("Expected an empty JSON array, got %q", out.String())
+	}
+}
+
+func newTestApp(t *testing.T, server string) (*app, *bytes.Buffer) {
+	t.Helper()
+	var stdout bytes.Buffer
+	return &app{
+		stdin:           strings.NewReader(""),
+		stdout:          &stdout,
+		stderr:          &bytes.Buffer{},
+		server:          server,
+		credentialsPath: filepath.Join(t.TempDir(), "credentials.json"),
+	}, &stdout
+}
+
+func TestApp_RequiresLogin(t *testing.T) {
+	a, _ := newTestApp(t, "http://notes.test")
+	err := a.main(context.Background(), []string{"ls"})
+	if err == nil || !strings.Contains(err.Error(), "notes login") {
+		t.Errorf("Expected a login hint, got %v", err)
+	}
+
+	a, _ = newTestApp(t, "http://notes.test")
+	if err := a.main(context.Background(), []string{"frobnicate"}); !errors.Is(err, errUsage) {
+		t.Errorf("Expected errUsage for an unknown command, got %v", err)
+	}
+
+	a, _ = newTestApp(t, "http://notes.test")
+	if err := a.main(context.Background(), []string{"ls", "-o", "yaml"}); err == nil {
+		t.Error("Expected an error for an unknown output format")
+	}
+}
+
+func TestApp_LoginCachesTokenForLaterCommands(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /auth/login", func(w http.ResponseWriter, r *http.Request) {
+		var req api.LoginRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Email != "ada@example.com" || req.Password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(api.LoginResponse{Token: "tok"})
+	})
+	mux.HandleFunc("GET /notes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(client.Note{ID: r.PathValue("id"), Content: "hello"})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Setenv("NOTES_PASSWORD", "secret")
+	a, _ := newTestApp(t, server.URL)
+	if err := a.main(context.Background(), []string{"login", "-email", "ada@example.com"}); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	// A new invocation picks the server and token up from the cache.
+	next, stdout := newTestApp(t, "")
+	next.credentialsPath = a.credentialsPath
+	if err := next.main(context.Background(), []string{"show", "-o", "plain", "n1"}); err != nil {
+		t.Fatalf("show failed: %v", err)
+	}
+	if stdout.String() != "hello\n" {
+		t.Errorf("Expected the note content, got %q", stdout.String())
+	}
+}
+
+func TestApp_PromptPasswordReadsPipedInput(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe failed: %v", err)
+	}
+	defer r.Close()
+	w.WriteString("secret\n")
+	w.Close()
+
+	a, _ := newTestApp(t, "http://notes.test")
+	a.stdin = r
+	password, err := a.promptPassword("Password: ")
+	if err != nil || password != "secret" {
+		t.Errorf("Expected the piped line, got %q, %v", password, err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/pkg/client"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputPlain = "plain"
+)
+
+// summaryWidth caps the content column of tables.
+const summaryWidth = 60
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeNotes prints a list of notes. Plain output is one "id<TAB>summary"
+// line per note, for scripts.
+func writeNotes(w io.Writer, format string, notes []*client.Note) error {
+	switch format {
+	case outputJSON:
+		if notes == nil {
+			notes = []*client.Note{}
+		}
+		return writeJSON(w, notes)
+	case outputPlain:
+		for _, note := range notes {
+			fmt.Fprintf(w, "%s\t%s\n", note.ID, summary(note.Content, 0))
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tUPDATED\tCONTENT")
+	for _, note := range notes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", note.ID, formatTime(note.UpdatedAt), summary(note.Content, summaryWidth))
+	}
+	return tw.Flush()
+}
+
+// writeNote prints a single note. Plain output is the raw content.
+func writeNote(w io.Writer, format string, note *client.Note) error {
+	switch format {
+	case outputJSON:
+		return writeJSON(w, note)
+	case outputPlain:
+		_, err := io.WriteString(w, ensureNewline(note.Content))
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%s\n", note.ID)
+	fmt.Fprintf(tw, "Created:\t%s\n", formatTime(note.CreatedAt))
+	fmt.Fprintf(tw, "Updated:\t%s\n", formatTime(note.UpdatedAt))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n"+ensureNewline(note.Content))
+	return err
+}
+
+// summary returns the first line of content, shortened to width runes when
+// width is positive.
+func summary(content string, width int) string {
+	line, _, more := strings.Cut(strings.TrimSpace(content), "\n")
+	line = strings.TrimSpace(line)
+	runes := []rune(line)
+	if width > 0 && len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	if more {
+		return line + " …"
+	}
+	return line
+}
+
+func formatTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func ensureNewline(s string) string {
+	if s == "" || strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return s + "\n"
+}
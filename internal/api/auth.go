@@ -2,66 +2,92 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/ivan-almanza/notes-api/internal/auth"
 	"github.com/ivan-almanza/notes-api/internal/store"
+	"github.com/ivan-almanza/notes-api/pkg/apitypes"
 )
 
 type AuthHandler struct {
 	store store.UserStorer
+	keys  *auth.Keys
 }
 
-func NewAuthHandler(store store.UserStorer) *AuthHandler {
-	return &AuthHandler{store: store}
+func NewAuthHandler(store store.UserStorer, keys *auth.Keys) *AuthHandler {
+	return &AuthHandler{store: store, keys: keys}
 }
 
-type RegisterRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
-}
-
-type RegisterResponse struct {
-	ID    string `json:"id"`
-	Email string `json:"email"`
-}
+type (
+	RegisterRequest  = apitypes.RegisterRequest
+	RegisterResponse = apitypes.RegisterResponse
+	LoginRequest     = apitypes.LoginRequest
+	LoginResponse    = apitypes.LoginResponse
+)
 
-type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
-}
+// errInvalidCredentials is shared by every login failure so responses do not
+// reveal whether the email exists.
+var errInvalidCredentials = NewError(http.StatusUnauthorized, CodeInvalidCredentials, "Invalid credentials")
 
-type LoginResponse struct {
-	Token string `json:"token"`
+// userValidationError maps store.User validation errors to field errors,
+// all of them in one response.
+func userValidationError(err error) *Error {
+	var fields []FieldError
+	if errors.Is(err, store.ErrInvalidEmail) {
+		fields = append(fields, FieldError{Pointer: "/email", Code: FieldInvalidEmail, Detail: store.ErrInvalidEmail.Error()})
+	}
+	if errors.Is(err, store.ErrPasswordTooShort) {
+		fields = append(fields, FieldError{Pointer: "/password", Code: FieldTooShort, Detail: store.ErrPasswordTooShort.Error()})
+	}
+	if errors.Is(err, store.ErrPasswordTooLong) {
+		fields = append(fields, FieldError{Pointer: "/password", Code: FieldTooLong, Detail: store.ErrPasswordTooLong.Error()})
+	}
+	if len(fields) == 0 {
+		return NewError(http.StatusBadRequest, CodeValidationFailed, err.Error())
+	}
+	return ValidationError(fields...)
 }
 
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if err := DecodeJSON(w, r, &req); err != nil {
+		WriteError(w, r, err)
 		return
 	}
 
 	user, err := h.store.GetByEmail(r.Context(), req.Email)
 	if err != nil {
 		if err == store.ErrNotFound {
-			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+			_ = auth.CompareContext(r.Context(), req.Password, auth.DummyHash)
+			loginsTotal.WithLabelValues("failure").Inc()
+			WriteError(w, r, errInvalidCredentials)
 			return
 		}
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		loginsTotal.WithLabelValues("error").Inc()
+		WriteError(w, r, InternalError(err))
+		return
+	}
+
+	if err := auth.CompareContext(r.Context(), req.Password, user.Password); err != nil {
+		loginsTotal.WithLabelValues("failure").Inc()
+		WriteError(w, r, errInvalidCredentials)
 		return
 	}
 
-	if err := auth.Compare(req.Password, user.Password); err != nil {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	if user.Disabled {
+		loginsTotal.WithLabelValues("failure").Inc()
+		WriteError(w, r, errAccountDisabled)
 		return
 	}
 
-	token, err := auth.GenerateToken(user.ID)
+	token, err := h.keys.GenerateToken(user.ID, user.TokenGeneration)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		loginsTotal.WithLabelValues("error").Inc()
+		WriteError(w, r, InternalError(err))
 		return
 	}
+	loginsTotal.WithLabelValues("success").Inc()
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(LoginResponse{Token: token})
@@ -69,8 +95,8 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req RegisterRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if err := DecodeJSON(w, r, &req); err != nil {
+		WriteError(w, r, err)
 		return
 	}
 
@@ -80,24 +106,24 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := user.Validate(); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		WriteError(w, r, userValidationError(err))
 		return
 	}
 
 	// Hash password before storage
-	hashedPassword, err := auth.Hash(user.Password)
+	hashedPassword, err := auth.HashContext(r.Context(), user.Password)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		WriteError(w, r, InternalError(err))
 		return
 	}
 	user.Password = hashedPassword
 
 	if err := h.store.Create(r.Context(), user); err != nil {
 		if err == store.ErrDuplicateEmail {
-			http.Error(w, "Email already exists", http.StatusConflict)
+			WriteError(w, r, NewError(http.StatusConflict, CodeEmailTaken, "Email already exists"))
 			return
 		}
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		WriteError(w, r, InternalError(err))
 		return
 	}
 
@@ -40,7 +40,7 @@ func TestRegister_Success(t *testing.T) {
 			return nil
 		},
 	}
-	handler := NewAuthHandler(mockStore)
+	handler := NewAuthHandler(mockStore, testKeys)
 
 	payload := map[string]string{
 		"email":    "test@example.com",
@@ -68,7 +68,7 @@ func TestRegister_InvalidInput(t *testing.T) {
 			return nil
 		},
 	}
-	handler := NewAuthHandler(mockStore)
+	handler := NewAuthHandler(mockStore, testKeys)
 
 	payload := map[string]string{
 		"email":    "invalid-email",
@@ -91,7 +91,7 @@ func TestRegister_Duplicate(t *testing.T) {
 			return store.ErrDuplicateEmail
 		},
 	}
-	handler := NewAuthHandler(mockStore)
+	handler := NewAuthHandler(mockStore, testKeys)
 
 	payload := map[string]string{
 		"email":    "duplicate@example.com",
@@ -119,7 +119,7 @@ func TestLogin_Success(t *testing.T) {
 			}, nil
 		},
 	}
-	handler := NewAuthHandler(mockStore)
+	handler := NewAuthHandler(mockStore, testKeys)
 
 	payload := map[string]string{
 		"email":    "test@example.com",
@@ -151,7 +151,7 @@ func TestLogin_UserNotFound(t *testing.T) {
 			return nil, store.ErrNotFound
 		},
 	}
-	handler := NewAuthHandler(mockStore)
+	handler := NewAuthHandler(mockStore, testKeys)
 
 	payload := map[string]string{
 		"email":    "ghost@example.com",
@@ -179,7 +179,7 @@ func TestLogin_BadPassword(t *testing.T) {
 			}, nil
 		},
 	}
-	handler := NewAuthHandler(mockStore)
+	handler := NewAuthHandler(mockStore, testKeys)
 
 	payload := map[string]string{
 		"email":    "test@example.com",
@@ -195,3 +195,26 @@ func TestLogin_BadPassword(t *testing.T) {
 		t.Errorf("Expected status 401 Unauthorized, got %d", w.Code)
 	}
 }
+
+func TestLogin_DisabledAccount(t *testing.T) {
+	hashedPassword, _ := auth.Hash("password123")
+	mockStore := &MockUserStore{
+		GetByEmailFunc: func(ctx context.Context, email string) (*store.User, error) {
+			return &store.User{ID: "user-123", Email: email, Password: hashedPassword, Disabled: true}, nil
+		},
+	}
+	handler := NewAuthHandler(mockStore, testKeys)
+
+	body, _ := json.Marshal(map[string]string{"email": "test@example.com", "password": "password123"})
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	handler.Login(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 Forbidden, got %d", w.Code)
+	}
+	if p := decodeProblem(t, w); p.Code != CodeAccountDisabled {
+		t.Errorf("Expected code %q, got %q", CodeAccountDisabled, p.Code)
+	}
+}
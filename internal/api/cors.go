@@ -0,0 +1,176 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions configures WithCORS. Origins are exact ("https://app.example.com"),
+// wildcard subdomains ("https://*.example.com") or "*" for any origin.
+type CORSOptions struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// DefaultExposedHeaders are the response headers browser clients may read.
+var DefaultExposedHeaders = []string{
+	RequestIDHeader, "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After",
+	IdempotentReplayedHeader,
+}
+
+// routeMatcher is implemented by *http.ServeMux.
+type routeMatcher interface {
+	Handler(r *http.Request) (h http.Handler, pattern string)
+}
+
+type cors struct {
+	opts           CORSOptions
+	anyOrigin      bool
+	allowedMethods map[string]bool
+	allowedHeaders map[string]bool
+	methods        string
+	headers        string
+	exposed        string
+	maxAge         string
+}
+
+// WithCORS answers preflight requests and adds CORS headers to actual
+// requests from allowed origins. It must wrap the mux, outside WithAuth, so
+// preflights for protected routes never need credentials. When next is a
+// *http.ServeMux, preflights are only answered for registered routes.
+func WithCORS(opts CORSOptions, next http.Handler) http.Handler {
+	c := &cors{
+		opts:           opts,
+		allowedMethods: make(map[string]bool),
+		allowedHeaders: make(map[string]bool),
+		methods:        strings.Join(opts.AllowedMethods, ", "),
+		headers:        strings.Join(opts.AllowedHeaders, ", "),
+		exposed:        strings.Join(opts.ExposedHeaders, ", "),
+		maxAge:         strconv.Itoa(int(opts.MaxAge.Seconds())),
+	}
+	for _, origin := range opts.AllowedOrigins {
+		if origin == "*" {
+			c.anyOrigin = true
+		}
+	}
+	for _, method := range opts.AllowedMethods {
+		c.allowedMethods[strings.ToUpper(method)] = true
+	}
+	for _, header := range opts.AllowedHeaders {
+		c.allowedHeaders[http.CanonicalHeaderKey(header)] = true
+	}
+	matcher, _ := next.(routeMatcher)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			c.preflight(w, r, origin, matcher, next)
+			return
+		}
+
+		if c.originAllowed(origin) {
+			c.setOriginHeaders(w, origin)
+			if c.exposed != "" {
+				w.Header().Set("Access-Control-Expose-Headers", c.exposed)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *cors) preflight(w http.ResponseWriter, r *http.Request, origin string, matcher routeMatcher, next http.Handler) {
+	h := w.Header()
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	if matcher != nil {
+		target := r.Clone(r.Context())
+		target.Method = method
+		if _, pattern := matcher.Handler(target); pattern == "" {
+			// Not a registered route: let the mux answer 404 or 405.
+			next.ServeHTTP(w, r)
+			return
+		}
+	}
+
+	if !c.originAllowed(origin) {
+		WriteError(w, r, NewError(http.StatusForbidden, CodeCORSRejected, "Origin not allowed"))
+		return
+	}
+	if !c.allowedMethods[method] {
+		WriteError(w, r, NewError(http.StatusForbidden, CodeCORSRejected, "Method not allowed: "+method))
+		return
+	}
+	for _, header := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		header = strings.TrimSpace(header)
+		if header != "" && !c.allowedHeaders[http.CanonicalHeaderKey(header)] {
+			WriteError(w, r, NewError(http.StatusForbidden, CodeCORSRejected, "Header not allowed: "+header))
+			return
+		}
+	}
+
+	c.setOriginHeaders(w, origin)
+	h.Set("Access-Control-Allow-Methods", c.methods)
+	if c.headers != "" {
+		h.Set("Access-Control-Allow-Headers", c.headers)
+	}
+	if c.opts.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", c.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *cors) setOriginHeaders(w http.ResponseWriter, origin string) {
+	if c.anyOrigin && !c.opts.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if c.opts.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (c *cors) originAllowed(origin string) bool {
+	if c.anyOrigin {
+		return true
+	}
+	for _, allowed := range c.opts.AllowedOrigins {
+		if originMatches(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// originMatches compares an origin against an allowed entry, where
+// "https://*.example.com" matches any subdomain of example.com but not
+// example.com itself.
+func originMatches(allowed, origin string) bool {
+	if strings.EqualFold(allowed, origin) {
+		return true
+	}
+	scheme, host, ok := strings.Cut(allowed, "://*.")
+	if !ok {
+		return false
+	}
+	u, err := url.Parse(origin)
+	if err != nil || !strings.EqualFold(u.Scheme, scheme) || u.Path != "" {
+		return false
+	}
+	return strings.HasSuffix(strings.ToLower(u.Host), "."+strings.ToLower(host))
+}
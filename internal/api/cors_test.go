@@ -0,0 +1,129 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newCORSHandler(opts CORSOptions) http.Handler {
+	mux := http.NewServeMux()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	mux.Handle("POST /notes", WithAuth(testKeys, ok))
+	mux.Handle("GET /notes", WithAuth(testKeys, ok))
+	return WithCORS(opts, mux)
+}
+
+var testCORSOptions = CORSOptions{
+	AllowedOrigins:   []string{"https://app.example.com", "https://*.preview.example.com"},
+	AllowedMethods:   []string{"GET", "POST"},
+	AllowedHeaders:   []string{"Authorization", "Content-Type"},
+	ExposedHeaders:   DefaultExposedHeaders,
+	AllowCredentials: true,
+	MaxAge:           10 * time.Minute,
+}
+
+func preflight(origin, method, headers string) *http.Request {
+	req := httptest.NewRequest(http.MethodOptions, "/notes", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		req.Header.Set("Access-Control-Request-Headers", headers)
+	}
+	return req
+}
+
+func TestCORS_PreflightForProtectedRoute(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newCORSHandler(testCORSOptions).ServeHTTP(rr, preflight("https://app.example.com", "POST", "authorization, content-type"))
+
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 without credentials, got %d", rr.Code)
+	}
+	h := rr.Header()
+	if h.Get("Access-Control-Allow-Origin") != "https://app.example.com" || h.Get("Access-Control-Allow-Credentials") != "true" {
+		t.Errorf("Unexpected origin headers: %v", h)
+	}
+	if h.Get("Access-Control-Allow-Methods") != "GET, POST" || h.Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("Unexpected preflight headers: %v", h)
+	}
+}
+
+func TestCORS_WildcardSubdomain(t *testing.T) {
+	cases := map[string]int{
+		"https://pr-42.preview.example.com":       http.StatusNoContent,
+		"https://preview.example.com":             http.StatusForbidden,
+		"http://pr-42.preview.example.com":        http.StatusForbidden,
+		"https://preview.example.com.attacker.io": http.StatusForbidden,
+	}
+	for origin, want := range cases {
+		rr := httptest.NewRecorder()
+		newCORSHandler(testCORSOptions).ServeHTTP(rr, preflight(origin, "GET", ""))
+		if rr.Code != want {
+			t.Errorf("%s: expected %d, got %d", origin, want, rr.Code)
+		}
+	}
+}
+
+func TestCORS_RejectsUnlistedMethodAndHeader(t *testing.T) {
+	handler := newCORSHandler(CORSOptions{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET"},
+		AllowedHeaders: []string{"Authorization"},
+	})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, preflight("https://any.example", "POST", ""))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a disallowed method, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, preflight("https://any.example", "GET", "X-Custom"))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a disallowed header, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, preflight("https://any.example", "GET", "Authorization"))
+	if rr.Code != http.StatusNoContent || rr.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("Expected a wildcard preflight response, got %d %v", rr.Code, rr.Header())
+	}
+}
+
+func TestCORS_UnregisteredRouteFallsThrough(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newCORSHandler(testCORSOptions).ServeHTTP(rr, preflight("https://app.example.com", "DELETE", ""))
+
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected the mux to answer 405, got %d", rr.Code)
+	}
+}
+
+func TestCORS_ActualRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/notes", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rr := httptest.NewRecorder()
+	newCORSHandler(testCORSOptions).ServeHTTP(rr, req)
+
+	// WithAuth rejects the request, but the browser must still be able to
+	// read the error.
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401, got %d", rr.Code)
+	}
+	if rr.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Error("Expected CORS headers on error responses")
+	}
+	if rr.Header().Get("Access-Control-Expose-Headers") == "" {
+		t.Error("Expected exposed headers")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/notes", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	rr = httptest.NewRecorder()
+	newCORSHandler(testCORSOptions).ServeHTTP(rr, req)
+	if rr.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("Disallowed origins should not receive CORS headers")
+	}
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/ivan-almanza/notes-api/internal/store"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// serveDocs serves a self-contained page that renders /openapi.json.
+func serveDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(docsPage)
+}
+
+// Route documentation, in route table order.
+var (
+	metricsDoc = &RouteDoc{
+		OperationID: "getMetrics",
+		Summary:     "Prometheus metrics",
+		Tag:         "Observability",
+		Responses: []ResponseDoc{
+			{Status: http.StatusOK, Description: "Metrics in the Prometheus text exposition format", ContentType: "text/plain"},
+		},
+	}
+	healthzDoc = &RouteDoc{
+		OperationID: "getLiveness",
+		Summary:     "Liveness probe",
+		Tag:         "Observability",
+		Responses: []ResponseDoc{
+			jsonResponse(http.StatusOK, "The process is serving HTTP", HealthResponse{}),
+		},
+	}
+	readyzDoc = &RouteDoc{
+		OperationID: "getReadiness",
+		Summary:     "Readiness probe",
+		Description: "Fails while the server drains or when a dependency check fails.",
+		Tag:         "Observability",
+		Responses: []ResponseDoc{
+			jsonResponse(http.StatusOK, "Ready to serve traffic", HealthResponse{}),
+			jsonResponse(http.StatusServiceUnavailable, "Draining or a dependency is unavailable", HealthResponse{}),
+		},
+	}
+	healthDetailsDoc = &RouteDoc{
+		OperationID: "getHealthDetails",
+		Summary:     "Status and latency of every dependency check",
+		Tag:         "Observability",
+		Responses: []ResponseDoc{
+			jsonResponse(http.StatusOK, "All checks passed", HealthResponse{}),
+			jsonResponse(http.StatusServiceUnavailable, "Draining or a check failed", HealthResponse{}),
+		},
+	}
+
+	registerDoc = &RouteDoc{
+		OperationID: "register",
+		Summary:     "Create a user account",
+		Tag:         "Auth",
+		Request:     RegisterRequest{},
+		Responses: []ResponseDoc{
+			jsonResponse(http.StatusCreated, "User created", RegisterResponse{}),
+			problemResponse(http.StatusBadRequest, "Invalid payload or validation failure"),
+			problemResponse(http.StatusConflict, "Email already registered"),
+		},
+	}
+	loginDoc = &RouteDoc{
+		OperationID: "login",
+		Summary:     "Exchange credentials for a bearer token",
+		Tag:         "Auth",
+		Request:     LoginRequest{},
+		Responses: []ResponseDoc{
+			jsonResponse(http.StatusOK, "Credentials accepted", LoginResponse{}),
+			problemResponse(http.StatusBadRequest, "Invalid payload or validation failure"),
+			problemResponse(http.StatusUnauthorized, "Invalid credentials"),
+			problemResponse(http.StatusForbidden, "Account is disabled"),
+		},
+	}
+
+	deviceCodeDoc = &RouteDoc{
+		OperationID: "requestDeviceCode",
+		Summary:     "Start a device authorization (RFC 8628)",
+		Tag:         "OAuth",
+		Form: []ParamDoc{
+			{Name: "client_id", Type: "string", Required: true},
+		},
+		Responses: []ResponseDoc{
+			jsonResponse(http.StatusOK, "Device and user codes", DeviceCodeResponse{}),
+			jsonResponse(http.StatusBadRequest, "Missing client_id", OAuthError{}),
+		},
+	}
+	tokenDoc = &RouteDoc{
+		OperationID: "exchangeDeviceCode",
+		Summary:     "Poll for an access token with a device code",
+		Description: "Returns authorization_pending or slow_down until the user approves or denies the request.",
+		Tag:         "OAuth",
+		Form: []ParamDoc{
+			{Name: "grant_type", Type: "string", Required: true, Enum: []string{DeviceCodeGrantType}},
+			{Name: "device_code", Type: "string", Required: true},
+			{Name: "client_id", Type: "string"},
+		},
+		Responses: []ResponseDoc{
+			jsonResponse(http.StatusOK, "Access token issued", TokenResponse{}),
+			jsonResponse(http.StatusBadRequest, "OAuth error such as authorization_pending or expired_token", OAuthError{}),
+		},
+	}
+	approveDeviceDoc = &RouteDoc{
+		OperationID: "approveDevice",
+		Summary:     "Approve or deny a device by its user code",
+		Tag:         "OAuth",
+		Request:     DeviceApprovalRequest{},
+		Responses: []ResponseDoc{
+			jsonResponse(http.StatusOK, "Decision recorded", DeviceApprovalResponse{}),
+			problemResponse(http.StatusBadRequest, "Invalid payload, or the user code expired or was already used"),
+			problemResponse(http.StatusNotFound, "Unknown user code"),
+		},
+	}
+
+	createNoteDoc = &RouteDoc{
+		OperationID: "createNote",
+		Summary:     "Create a note",
+		Tag:         "Notes",
+		Request:     CreateNoteRequest{},
+		Responses: []ResponseDoc{
+			jsonResponse(http.StatusCreated, "Note created", CreateNoteResponse{}),
+			problemResponse(http.StatusBadRequest, "Invalid payload or validation failure"),
+		},
+	}
+	listNotesDoc = &RouteDoc{
+		OperationID: "listNotes",
+		Summary:     "List the caller's notes",
+		Description: "Without limit or cursor every note is returned. With either, one page is returned oldest first and meta.next_cursor is set when more notes follow.",
+		Tag:         "Notes",
+		Query: []ParamDoc{
+			{Name: "limit", Type: "integer", Description: "Page size, 1 to 100 (default 20)"},
+			{Name: "cursor", Type: "string", Description: "meta.next_cursor from the previous page"},
+		},
+		Responses: []ResponseDoc{
+			jsonResponse(http.StatusOK, "The caller's notes", ListNotesResponse{}),
+			problemResponse(http.StatusBadRequest, "Invalid limit or cursor"),
+		},
+	}
+	searchNotesDoc = &RouteDoc{
+		OperationID: "searchNotes",
+		Summary:     "Search the caller's notes",
+		Description: "Case-insensitive substring match on the content, newest first.",
+		Tag:         "Notes",
+		Query: []ParamDoc{
+			{Name: "q", Type: "string", Description: "Text to search for", Required: true},
+			{Name: "limit", Type: "integer", Description: "Maximum results, 1 to 100 (default 20)"},
+		},
+		Responses: []ResponseDoc{
+			jsonResponse(http.StatusOK, "Matching notes", ListNotesResponse{}),
+			problemResponse(http.StatusBadRequest, "Missing q or invalid limit"),
+		},
+	}
+	getNoteDoc = &RouteDoc{
+		OperationID: "getNote",
+		Summary:     "Get a note",
+		Tag:         "Notes",
+		Responses: []ResponseDoc{
+			jsonResponse(http.StatusOK, "The note", store.Note{}),
+			problemResponse(http.StatusNotFound, "No such note for the caller"),
+		},
+	}
+	updateNoteDoc = &RouteDoc{
+		OperationID: "updateNote",
+		Summary:     "Replace the content of a note",
+		Tag:         "Notes",
+		Request:     UpdateNoteRequest{},
+		Responses: []ResponseDoc{
+			jsonResponse(http.StatusOK, "The updated note", store.Note{}),
+			problemResponse(http.StatusBadRequest, "Invalid payload or validation failure"),
+			problemResponse(http.StatusNotFound, "No such note for the caller"),
+		},
+	}
+	deleteNoteDoc = &RouteDoc{
+		OperationID: "deleteNote",
+		Summary:     "Delete a note",
+		Tag:         "Notes",
+		Responses: []ResponseDoc{
+			{Status: http.StatusNoContent, Description: "Note deleted"},
+			problemResponse(http.StatusNotFound, "No such note for the caller"),
+		},
+	}
+
+	openAPIDoc = &RouteDoc{
+		OperationID: "getOpenAPI",
+		Summary:     "This OpenAPI document",
+		Tag:         "Docs",
+		Responses: []ResponseDoc{
+			{Status: http.StatusOK, Description: "OpenAPI 3.1 document", ContentType: "application/json"},
+		},
+	}
+	docsDoc = &RouteDoc{
+		OperationID: "getDocs",
+		Summary:     "Human-readable API documentation",
+		Tag:         "Docs",
+		Responses: []ResponseDoc{
+			{Status: http.StatusOK, Description: "HTML page rendering the OpenAPI document", ContentType: "text/html"},
+		},
+	}
+)
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ivan-almanza/notes-api/pkg/apitypes"
+)
+
+// Machine-readable error codes, defined in apitypes so clients can branch
+// on them.
+const (
+	CodeInvalidPayload     = apitypes.CodeInvalidPayload
+	CodeInvalidQuery       = apitypes.CodeInvalidQuery
+	CodeValidationFailed   = apitypes.CodeValidationFailed
+	CodeInvalidCredentials = apitypes.CodeInvalidCredentials
+	CodeEmailTaken         = apitypes.CodeEmailTaken
+	CodeUnauthorized       = apitypes.CodeUnauthorized
+	CodeMissingAuthHeader  = apitypes.CodeMissingAuthHeader
+	CodeInvalidAuthHeader  = apitypes.CodeInvalidAuthHeader
+	CodeInvalidToken       = apitypes.CodeInvalidToken
+	CodeTokenRevoked       = apitypes.CodeTokenRevoked
+	CodeAccountDisabled    = apitypes.CodeAccountDisabled
+	CodeNotFound           = apitypes.CodeNotFound
+	CodeInvalidUserCode    = apitypes.CodeInvalidUserCode
+	CodeRateLimited        = apitypes.CodeRateLimited
+	CodeCORSRejected       = apitypes.CodeCORSRejected
+	CodeInternal           = apitypes.CodeInternal
+)
+
+const problemContentType = "application/problem+json"
+
+type (
+	FieldError = apitypes.FieldError
+	Problem    = apitypes.Problem
+)
+
+// Error is returned by handlers and middleware to describe a failed request.
+// Err holds the underlying cause and is never sent to the client.
+type Error struct {
+	Status int
+	Code   string
+	Detail string
+	Fields []FieldError
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Err.Error()
+	}
+	return e.Code + ": " + e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NewError builds an Error with a client-facing detail message.
+func NewError(status int, code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+// InternalError wraps an unexpected failure as a 500 without leaking it.
+func InternalError(err error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Detail: "Internal server error", Err: err}
+}
+
+// ValidationError reports one or more invalid fields as a 400.
+func ValidationError(fields ...FieldError) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeValidationFailed, Detail: "Request validation failed", Fields: fields}
+}
+
+// WriteError writes err as application/problem+json. Errors that are not
+// an *Error are treated as internal errors.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		apiErr = InternalError(err)
+	}
+	if apiErr.Status >= http.StatusInternalServerError {
+		logRequestError(r, apiErr)
+	}
+
+	problem := Problem{
+		Type:      "/problems/" + apiErr.Code,
+		Title:     http.StatusText(apiErr.Status),
+		Status:    apiErr.Status,
+		Detail:    apiErr.Detail,
+		Instance:  r.URL.Path,
+		Code:      apiErr.Code,
+		RequestID: RequestIDFromRequest(r),
+		Errors:    apiErr.Fields,
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// RequestIDFromRequest returns the request ID stored in the context, falling
+// back to the X-Request-ID header sent by the client.
+func RequestIDFromRequest(r *http.Request) string {
+	if id, ok := r.Context().Value(ContextKeyRequestID).(string); ok {
+		return id
+	}
+	return r.Header.Get(RequestIDHeader)
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ivan-almanza/notes-api/internal/store"
+)
+
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Expected Content-Type application/problem+json, got %q", ct)
+	}
+	var problem Problem
+	if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+		t.Fatalf("Failed to decode problem: %v", err)
+	}
+	return problem
+}
+
+func TestWriteError_Problem(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/notes", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	w := httptest.NewRecorder()
+
+	WriteError(w, req, NewError(http.StatusConflict, CodeEmailTaken, "Email already exists"))
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", w.Code)
+	}
+
+	problem := decodeProblem(t, w)
+	if problem.Code != CodeEmailTaken || problem.Status != http.StatusConflict {
+		t.Errorf("Unexpected problem: %+v", problem)
+	}
+	if problem.RequestID != "req-123" {
+		t.Errorf("Expected request_id req-123, got %q", problem.RequestID)
+	}
+	if problem.Instance != "/notes" {
+		t.Errorf("Expected instance /notes, got %q", problem.Instance)
+	}
+}
+
+func TestWriteError_HidesInternalCause(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/notes", nil)
+	w := httptest.NewRecorder()
+
+	WriteError(w, req, errors.New("pq: connection refused"))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "connection refused") {
+		t.Error("Internal error cause must not be exposed")
+	}
+	if problem := decodeProblem(t, w); problem.Code != CodeInternal {
+		t.Errorf("Expected code %q, got %q", CodeInternal, problem.Code)
+	}
+}
+
+func TestRegister_InvalidInput_FieldError(t *testing.T) {
+	handler := NewAuthHandler(&MockUserStore{}, testKeys)
+
+	body, _ := json.Marshal(map[string]string{"email": "invalid-email", "password": "password123"})
+	req := httptest.NewRequest(http.MethodPost, "/auth/register", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	handler.Register(w, req)
+
+	problem := decodeProblem(t, w)
+	if problem.Code != CodeValidationFailed {
+		t.Errorf("Expected code %q, got %q", CodeValidationFailed, problem.Code)
+	}
+	if len(problem.Errors) != 1 || problem.Errors[0].Pointer != "/email" {
+		t.Errorf("Expected a field error for /email, got %+v", problem.Errors)
+	}
+}
+
+func TestRegister_ReportsEveryFieldError(t *testing.T) {
+	handler := NewAuthHandler(&MockUserStore{}, testKeys)
+
+	// 37 characters but 73 bytes, past bcrypt's limit
+	password := strings.Repeat("é", 36) + "x"
+	body, _ := json.Marshal(map[string]string{"email": "invalid-email", "password": password})
+	req := httptest.NewRequest(http.MethodPost, "/auth/register", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	handler.Register(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	problem := decodeProblem(t, w)
+	pointers := map[string]string{}
+	for _, field := range problem.Errors {
+		pointers[field.Pointer] = field.Code
+	}
+	if len(problem.Errors) != 2 || pointers["/email"] != FieldInvalidEmail || pointers["/password"] != FieldTooLong {
+		t.Errorf("Expected errors for /email and /password, got %+v", problem.Errors)
+	}
+}
+
+func TestUserValidationError_ReportsEveryField(t *testing.T) {
+	err := (&store.User{Email: "invalid-email", Password: "123"}).Validate()
+
+	problem := userValidationError(err)
+	if problem.Status != http.StatusBadRequest || len(problem.Fields) != 2 {
+		t.Errorf("Expected a 400 with two field errors, got %d with %+v", problem.Status, problem.Fields)
+	}
+}
+
+func TestRegister_Duplicate_Code(t *testing.T) {
+	mockStore := &MockUserStore{
+		CreateFunc: func(ctx context.Context, user *store.User) error {
+			return store.ErrDuplicateEmail
+		},
+	}
+	handler := NewAuthHandler(mockStore, testKeys)
+
+	body, _ := json.Marshal(map[string]string{"email": "duplicate@example.com", "password": "password123"})
+	req := httptest.NewRequest(http.MethodPost, "/auth/register", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	handler.Register(w, req)
+
+	if problem := decodeProblem(t, w); problem.Code != CodeEmailTaken {
+		t.Errorf("Expected code %q, got %q", CodeEmailTaken, problem.Code)
+	}
+}
+
+func TestAuthMiddleware_ProblemCode(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	w := httptest.NewRecorder()
+
+	WithAuth(testKeys, http.NotFoundHandler()).ServeHTTP(w, req)
+
+	if problem := decodeProblem(t, w); problem.Code != CodeMissingAuthHeader {
+		t.Errorf("Expected code %q, got %q", CodeMissingAuthHeader, problem.Code)
+	}
+}
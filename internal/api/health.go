@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CheckFunc reports whether a dependency is usable. It should honour ctx.
+type CheckFunc func(ctx context.Context) error
+
+// DefaultCheckTimeout bounds checks registered without an explicit timeout.
+const DefaultCheckTimeout = 2 * time.Second
+
+type healthCheck struct {
+	name    string
+	timeout time.Duration
+	check   CheckFunc
+}
+
+// HealthChecker is a registry of dependency checks backing the liveness,
+// readiness and details endpoints. Subsystems add their own checks with
+// Register.
+type HealthChecker struct {
+	mu       sync.RWMutex
+	checks   []healthCheck
+	draining atomic.Bool
+}
+
+func NewHealthChecker() *HealthChecker {
+	return &HealthChecker{}
+}
+
+// Register adds a named check run on every readiness probe. A zero timeout
+// means DefaultCheckTimeout.
+func (h *HealthChecker) Register(name string, timeout time.Duration, check CheckFunc) {
+	if timeout <= 0 {
+		timeout = DefaultCheckTimeout
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, healthCheck{name: name, timeout: timeout, check: check})
+}
+
+// SetDraining makes readiness fail regardless of the checks, so load
+// balancers stop routing new traffic while the server shuts down.
+func (h *HealthChecker) SetDraining(draining bool) {
+	h.draining.Store(draining)
+}
+
+type CheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type HealthResponse struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks,omitempty"`
+}
+
+const (
+	statusOK          = "ok"
+	statusUnavailable = "unavailable"
+	statusDraining    = "draining"
+)
+
+// Run executes every check concurrently and reports whether all passed.
+func (h *HealthChecker) Run(ctx context.Context) (bool, []CheckResult) {
+	h.mu.RLock()
+	checks := append([]healthCheck(nil), h.checks...)
+	h.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runCheck(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	healthy := true
+	for _, r := range results {
+		if r.Status != statusOK {
+			healthy = false
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return healthy, results
+}
+
+func runCheck(ctx context.Context, c healthCheck) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.check(ctx)
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	result := CheckResult{
+		Name:      c.name,
+		Status:    statusOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = statusUnavailable
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Healthz is the liveness probe: it succeeds as long as the process serves HTTP.
+func (h *HealthChecker) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, HealthResponse{Status: statusOK})
+}
+
+// Readyz is the readiness probe: it fails while draining or when any check fails.
+func (h *HealthChecker) Readyz(w http.ResponseWriter, r *http.Request) {
+	if h.draining.Load() {
+		writeHealth(w, http.StatusServiceUnavailable, HealthResponse{Status: statusDraining})
+		return
+	}
+
+	if healthy, _ := h.Run(r.Context()); !healthy {
+		writeHealth(w, http.StatusServiceUnavailable, HealthResponse{Status: statusUnavailable})
+		return
+	}
+	writeHealth(w, http.StatusOK, HealthResponse{Status: statusOK})
+}
+
+// Details reports the status and latency of every check for operators.
+func (h *HealthChecker) Details(w http.ResponseWriter, r *http.Request) {
+	healthy, results := h.Run(r.Context())
+
+	response := HealthResponse{Status: statusOK, Checks: results}
+	status := http.StatusOK
+	switch {
+	case h.draining.Load():
+		response.Status = statusDraining
+		status = http.StatusServiceUnavailable
+	case !healthy:
+		response.Status = statusUnavailable
+		status = http.StatusServiceUnavailable
+	}
+	writeHealth(w, status, response)
+}
+
+func writeHealth(w http.ResponseWriter, status int, response HealthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
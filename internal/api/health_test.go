@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthz_AlwaysOK(t *testing.T) {
+	h := NewHealthChecker()
+	h.Register("db", 0, func(ctx context.Context) error { return errors.New("down") })
+
+	w := httptest.NewRecorder()
+	h.Healthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Liveness should not depend on checks, got %d", w.Code)
+	}
+}
+
+func TestReadyz_Healthy(t *testing.T) {
+	h := NewHealthChecker()
+	h.Register("db", 0, func(ctx context.Context) error { return nil })
+
+	w := httptest.NewRecorder()
+	h.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+}
+
+func TestReadyz_FailingCheck(t *testing.T) {
+	h := NewHealthChecker()
+	h.Register("db", 0, func(ctx context.Context) error { return nil })
+	h.Register("cache", 0, func(ctx context.Context) error { return errors.New("connection refused") })
+
+	w := httptest.NewRecorder()
+	h.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", w.Code)
+	}
+}
+
+func TestReadyz_Draining(t *testing.T) {
+	h := NewHealthChecker()
+	h.SetDraining(true)
+
+	w := httptest.NewRecorder()
+	h.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 while draining, got %d", w.Code)
+	}
+}
+
+func TestHealthDetails_TimeoutAndLatency(t *testing.T) {
+	h := NewHealthChecker()
+	h.Register("slow", 20*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	h.Register("fast", 0, func(ctx context.Context) error { return nil })
+
+	w := httptest.NewRecorder()
+	h.Details(w, httptest.NewRequest(http.MethodGet, "/health/details", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", w.Code)
+	}
+
+	var response HealthResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Checks) != 2 {
+		t.Fatalf("Expected 2 checks, got %+v", response.Checks)
+	}
+
+	fast, slow := response.Checks[0], response.Checks[1]
+	if fast.Name != "fast" || fast.Status != "ok" {
+		t.Errorf("Unexpected fast check: %+v", fast)
+	}
+	if slow.Name != "slow" || slow.Status != "unavailable" || slow.Error == "" {
+		t.Errorf("Unexpected slow check: %+v", slow)
+	}
+	if slow.LatencyMS < 20 {
+		t.Errorf("Expected latency of at least the timeout, got %v", slow.LatencyMS)
+	}
+}
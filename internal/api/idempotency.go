@@ -0,0 +1,234 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/idempotency"
+	"github.com/ivan-almanza/notes-api/internal/metrics"
+	"github.com/ivan-almanza/notes-api/pkg/apitypes"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks a response replayed for a retry.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	CodeInvalidIdempotencyKey = apitypes.CodeInvalidIdempotencyKey
+	CodeIdempotencyKeyInUse   = apitypes.CodeIdempotencyKeyInUse
+	CodeIdempotencyKeyReused  = apitypes.CodeIdempotencyKeyReused
+
+	maxIdempotencyKeyLength = 255
+)
+
+var idempotentReplaysTotal = metrics.NewCounterVec(
+	"notes_http_idempotent_replays_total",
+	"Responses replayed for a retried Idempotency-Key, by route pattern.",
+	"route",
+)
+
+var (
+	errInvalidIdempotencyKey = NewError(http.StatusBadRequest, CodeInvalidIdempotencyKey,
+		"Idempotency-Key must be 1 to 255 printable ASCII characters")
+	errIdempotencyKeyInUse = NewError(http.StatusConflict, CodeIdempotencyKeyInUse,
+		"A request with this Idempotency-Key is still in progress, retry later")
+	errIdempotencyKeyReused = NewError(http.StatusUnprocessableEntity, CodeIdempotencyKeyReused,
+		"This Idempotency-Key was already used for a different request")
+)
+
+// Idempotency answers retries of a request sent with the same
+// Idempotency-Key with the response to the first one. Keys are scoped by
+// user, so it must run inside WithAuth.
+type Idempotency struct {
+	store idempotency.Store
+	ttl   time.Duration
+	wait  time.Duration
+	// lease bounds how long a request may hold its key before a retry can
+	// run it again, for instances that die mid-request
+	lease time.Duration
+	// poll is how often a waiting duplicate checks for the response
+	poll time.Duration
+}
+
+// NewIdempotency keeps responses in store for ttl. A duplicate arriving
+// while the first request is still running waits up to wait for its
+// response and then gets a 409.
+func NewIdempotency(store idempotency.Store, ttl, wait time.Duration) *Idempotency {
+	return &Idempotency{store: store, ttl: ttl, wait: wait, lease: time.Minute, poll: 50 * time.Millisecond}
+}
+
+// Idempotent wraps a handler registered on a ServeMux. Requests without an
+// Idempotency-Key pass through. 5xx responses are not stored, so a retry
+// runs the request again.
+func (i *Idempotency) Idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		userID, _ := r.Context().Value(ContextKeyUserID).(string)
+		if key == "" || userID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !validIdempotencyKey(key) {
+			WriteError(w, r, errInvalidIdempotencyKey)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+		if err != nil {
+			WriteError(w, r, decodeError(err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		key = userID + "|" + key
+		fingerprint := requestFingerprint(r, body)
+		token := rand.Text()
+		record, err := i.claim(r.Context(), key, fingerprint, token)
+		if err != nil {
+			// Fail closed: running the request without its key could
+			// repeat a side effect the client asked to happen once.
+			WriteError(w, r, InternalError(err))
+			return
+		}
+		switch {
+		case record == nil:
+			i.serve(w, r, next, key, token)
+		case record.Fingerprint != fingerprint:
+			WriteError(w, r, errIdempotencyKeyReused)
+		case record.Response == nil:
+			w.Header().Set("Retry-After", "1")
+			WriteError(w, r, errIdempotencyKeyInUse)
+		default:
+			idempotentReplaysTotal.WithLabelValues(r.Pattern).Inc()
+			replay(w, record.Response)
+		}
+	})
+}
+
+// claim claims key, or waits up to i.wait for the request holding it to
+// finish.
+func (i *Idempotency) claim(ctx context.Context, key, fingerprint, token string) (*idempotency.Record, error) {
+	deadline := time.Now().Add(i.wait)
+	for {
+		record, err := i.store.Claim(ctx, key, fingerprint, token, i.lease)
+		if err != nil || record == nil || record.Response != nil || record.Fingerprint != fingerprint {
+			return record, err
+		}
+		if !time.Now().Before(deadline) {
+			return record, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(i.poll):
+		}
+	}
+}
+
+// serve runs the request that claimed key with token and stores its
+// response. The key is released when the response is not stored, including
+// when next panics.
+func (i *Idempotency) serve(w http.ResponseWriter, r *http.Request, next http.Handler, key, token string) {
+	// The response is stored even if the client went away meanwhile: its
+	// retry is what the key is for.
+	ctx := context.WithoutCancel(r.Context())
+	stored := false
+	defer func() {
+		if !stored {
+			if err := i.store.Release(ctx, key, token); err != nil {
+				requestLogger(r).WarnContext(ctx, "failed to release idempotency key", "error", err)
+			}
+		}
+	}()
+
+	rec := newIdempotencyRecorder(w)
+	next.ServeHTTP(rec, r)
+	rec.finish()
+	if rec.status >= http.StatusInternalServerError {
+		return
+	}
+
+	resp := &idempotency.Response{Status: rec.status, Header: rec.header, Body: rec.body.Bytes()}
+	if err := i.store.Complete(ctx, key, token, resp, i.ttl); err != nil {
+		requestLogger(r).WarnContext(ctx, "failed to store idempotent response", "error", err)
+		return
+	}
+	stored = true
+}
+
+func replay(w http.ResponseWriter, resp *idempotency.Response) {
+	h := w.Header()
+	for name, values := range resp.Header {
+		h[name] = values
+	}
+	h.Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
+
+// requestFingerprint identifies a request by its method, path and body, so
+// reusing a key for another request is detected.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// idempotencyRecorder passes a response through while keeping a copy. The
+// handler gets its own header map, so headers set by outer middleware, such
+// as X-Request-ID, are not stored and replayed.
+type idempotencyRecorder struct {
+	w           http.ResponseWriter
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func newIdempotencyRecorder(w http.ResponseWriter) *idempotencyRecorder {
+	return &idempotencyRecorder{w: w, header: make(http.Header), status: http.StatusOK}
+}
+
+func (rec *idempotencyRecorder) Header() http.Header { return rec.header }
+
+func (rec *idempotencyRecorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.status, rec.wroteHeader = status, true
+	h := rec.w.Header()
+	for name, values := range rec.header {
+		h[name] = values
+	}
+	rec.w.WriteHeader(status)
+}
+
+func (rec *idempotencyRecorder) Write(b []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	rec.body.Write(b)
+	return rec.w.Write(b)
+}
+
+// finish sends the headers of a handler that wrote nothing.
+func (rec *idempotencyRecorder) finish() {
+	rec.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/idempotency"
+)
+
+// newIdempotentHandler wraps next with an Idempotency on a fresh memory
+// store, authenticating requests as the user in their X-Test-User header.
+func newIdempotentHandler(wait time.Duration, next http.HandlerFunc) http.Handler {
+	idem := NewIdempotency(idempotency.NewMemoryStore(), time.Hour, wait)
+	idem.poll = time.Millisecond
+	handler := idem.Idempotent(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), ContextKeyUserID, r.Header.Get("X-Test-User"))
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func idempotentRequest(user, key, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/notes", strings.NewReader(body))
+	req.Header.Set("X-Test-User", user)
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	return req
+}
+
+func TestIdempotency_ReplaysFirstResponse(t *testing.T) {
+	var calls atomic.Int32
+	handler := newIdempotentHandler(0, func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Location", "/notes/1")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"call":%d}`, n)
+	})
+
+	first := httptest.NewRecorder()
+	first.Header().Set(RequestIDHeader, "req-1") // set by outer middleware
+	handler.ServeHTTP(first, idempotentRequest("user-1", "key-1", `{"content":"a"}`))
+	retry := httptest.NewRecorder()
+	retry.Header().Set(RequestIDHeader, "req-2")
+	handler.ServeHTTP(retry, idempotentRequest("user-1", "key-1", `{"content":"a"}`))
+
+	if calls.Load() != 1 {
+		t.Fatalf("Expected the handler to run once, ran %d times", calls.Load())
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != `{"call":1}` || retry.Header().Get("Location") != "/notes/1" {
+		t.Errorf("Expected the first response, got %d %q %v", retry.Code, retry.Body.String(), retry.Header())
+	}
+	if retry.Header().Get(IdempotentReplayedHeader) != "true" || first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Error("Expected only the replay to be marked as replayed")
+	}
+	if retry.Header().Get(RequestIDHeader) != "req-2" {
+		t.Errorf("Expected the retry's own request ID, got %q", retry.Header().Get(RequestIDHeader))
+	}
+}
+
+func TestIdempotency_ScopesKeysByUser(t *testing.T) {
+	var calls atomic.Int32
+	handler := newIdempotentHandler(0, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	})
+
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("user-1", "key-1", `{}`))
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("user-2", "key-1", `{}`))
+
+	if calls.Load() != 2 {
+		t.Errorf("Expected both users' requests to run, ran %d", calls.Load())
+	}
+}
+
+func TestIdempotency_RejectsKeyReusedForAnotherRequest(t *testing.T) {
+	handler := newIdempotentHandler(0, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("user-1", "key-1", `{"content":"a"}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, idempotentRequest("user-1", "key-1", `{"content":"b"}`))
+
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), CodeIdempotencyKeyReused) {
+		t.Errorf("Expected 422 %s, got %d %s", CodeIdempotencyKeyReused, w.Code, w.Body.String())
+	}
+}
+
+func TestIdempotency_ConcurrentDuplicates(t *testing.T) {
+	tests := []struct {
+		name       string
+		wait       time.Duration
+		wantStatus int
+	}{
+		{"without waiting", 0, http.StatusConflict},
+		{"waiting for the first response", 5 * time.Second, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started, release := make(chan struct{}), make(chan struct{})
+			handler := newIdempotentHandler(tt.wait, func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				<-release
+				w.WriteHeader(http.StatusCreated)
+			})
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("user-1", "key-1", `{}`))
+			}()
+			<-started
+
+			if tt.wait > 0 {
+				time.AfterFunc(20*time.Millisecond, func() { close(release) })
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, idempotentRequest("user-1", "key-1", `{}`))
+			if tt.wait == 0 {
+				close(release)
+			}
+			<-done
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusConflict && w.Header().Get("Retry-After") == "" {
+				t.Error("Expected a Retry-After header on 409")
+			}
+		})
+	}
+}
+
+func TestIdempotency_ServerErrorsAreNotStored(t *testing.T) {
+	var calls atomic.Int32
+	handler := newIdempotentHandler(0, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("user-1", "key-1", `{}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, idempotentRequest("user-1", "key-1", `{}`))
+
+	if calls.Load() != 2 || w.Code != http.StatusCreated {
+		t.Errorf("Expected the retry to run again and succeed, got %d calls and status %d", calls.Load(), w.Code)
+	}
+}
+
+func TestIdempotency_WithoutKey(t *testing.T) {
+	var calls atomic.Int32
+	handler := newIdempotentHandler(0, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	})
+
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("user-1", "", `{}`))
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("user-1", "", `{}`))
+
+	if calls.Load() != 2 {
+		t.Errorf("Expected requests without a key to always run, ran %d", calls.Load())
+	}
+}
+
+func TestIdempotency_InvalidKey(t *testing.T) {
+	handler := newIdempotentHandler(0, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not run with an invalid key")
+	})
+
+	for _, key := range []string{strings.Repeat("k", maxIdempotencyKeyLength+1), "key\x7f"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, idempotentRequest("user-1", key, `{}`))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), CodeInvalidIdempotencyKey) {
+			t.Errorf("Expected 400 %s for %q, got %d", CodeInvalidIdempotencyKey, key, w.Code)
+		}
+	}
+}
+
+func TestIdempotency_HandlerReadsBody(t *testing.T) {
+	handler := newIdempotentHandler(0, func(w http.ResponseWriter, r *http.Request) {
+		var req CreateNoteRequest
+		if err := DecodeJSON(w, r, &req); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		w.Write([]byte(req.Content))
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, idempotentRequest("user-1", "key-1", `{"content":"hello"}`))
+	if w.Code != http.StatusOK || w.Body.String() != "hello" {
+		t.Errorf("Expected the handler to decode the body, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/tracing"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+
+	maxRequestIDLength = 128
+)
+
+// requestState is shared by every layer handling a request so the access log
+// can report facts learned further down the chain, like the user ID.
+type requestState struct {
+	logger    *slog.Logger
+	userID    string
+	serviceID string
+	err       error
+	// route is the pattern the mux matched, for middleware outside of it
+	route string
+}
+
+const contextKeyRequestState contextKey = "requestState"
+
+func stateFromContext(ctx context.Context) *requestState {
+	state, _ := ctx.Value(contextKeyRequestState).(*requestState)
+	return state
+}
+
+// withRequestState returns the request state in ctx, adding one when there
+// is none yet.
+func withRequestState(ctx context.Context) (*requestState, context.Context) {
+	if state := stateFromContext(ctx); state != nil {
+		return state, ctx
+	}
+	state := &requestState{logger: slog.Default()}
+	return state, context.WithValue(ctx, contextKeyRequestState, state)
+}
+
+// matchedRoute returns the route pattern the mux matched for r, or "" when
+// none did. The mux sets r.Pattern only on the request it was given, so the
+// pattern is kept on the request state for middleware further out.
+func matchedRoute(r *http.Request) string {
+	state := stateFromContext(r.Context())
+	if state == nil {
+		return r.Pattern
+	}
+	if state.route == "" {
+		state.route = r.Pattern
+	}
+	return state.route
+}
+
+// LoggerFromContext returns the request-scoped logger, or slog.Default()
+// outside of WithRequestLogging.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if state := stateFromContext(ctx); state != nil {
+		return state.logger
+	}
+	return slog.Default()
+}
+
+// requestLogger returns the request-scoped logger annotated with the
+// matched route, which is only known once the mux has dispatched.
+func requestLogger(r *http.Request) *slog.Logger {
+	logger := LoggerFromContext(r.Context())
+	if route := matchedRoute(r); route != "" {
+		logger = logger.With("route", route)
+	}
+	return logger
+}
+
+// setRequestUser records the authenticated user on the request state and
+// adds it to the request-scoped logger.
+func setRequestUser(ctx context.Context, userID string) {
+	if state := stateFromContext(ctx); state != nil {
+		state.userID = userID
+		state.logger = state.logger.With("user_id", userID)
+	}
+}
+
+// ServiceIDFromContext returns the identity of the service that called with
+// a verified client certificate, or "" if there is none.
+func ServiceIDFromContext(ctx context.Context) string {
+	if state := stateFromContext(ctx); state != nil {
+		return state.serviceID
+	}
+	return ""
+}
+
+// setRequestService records the calling service on the request state and
+// adds it to the request-scoped logger.
+func setRequestService(ctx context.Context, serviceID string) {
+	if state := stateFromContext(ctx); state != nil {
+		state.serviceID = serviceID
+		state.logger = state.logger.With("service_id", serviceID)
+	}
+}
+
+// logRequestError logs the cause of a server error and keeps it for the
+// access log line.
+func logRequestError(r *http.Request, err error) {
+	if state := stateFromContext(r.Context()); state != nil {
+		state.err = err
+	}
+	tracing.SpanFromContext(r.Context()).SetError(err)
+	requestLogger(r).ErrorContext(r.Context(), "request failed", "error", err)
+}
+
+// WithRequestID accepts a client-provided X-Request-ID or generates one,
+// stores it in the context and echoes it in the response.
+func WithRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, requestID)
+		ctx := context.WithValue(r.Context(), ContextKeyRequestID, requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithRequestLogging injects a request-scoped logger into the context and
+// writes one access log line per request.
+func WithRequestLogging(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		reqLogger := logger.With("request_id", RequestIDFromRequest(r))
+		if sc := tracing.SpanContextFromContext(r.Context()); sc.IsValid() {
+			reqLogger = reqLogger.With("trace_id", sc.TraceID.String(), "span_id", sc.SpanID.String())
+		}
+		state, ctx := withRequestState(r.Context())
+		state.logger = reqLogger
+		r = r.WithContext(ctx)
+
+		rec := newResponseRecorder(w)
+		next.ServeHTTP(rec, r)
+
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"route", matchedRoute(r),
+			"status", rec.status,
+			"bytes", rec.bytes,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"remote_addr", r.RemoteAddr,
+			"user_agent", r.UserAgent(),
+		}
+		if state.userID != "" {
+			attrs = append(attrs, "user_id", state.userID)
+		}
+		if state.serviceID != "" {
+			attrs = append(attrs, "service_id", state.serviceID)
+		}
+		if sc := tracing.SpanContextFromContext(r.Context()); sc.IsValid() {
+			attrs = append(attrs, "trace_id", sc.TraceID.String())
+		}
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+			if state.err != nil {
+				attrs = append(attrs, "error", state.err)
+			}
+		}
+		logger.Log(ctx, level, "request", append(attrs, "request_id", RequestIDFromRequest(r))...)
+	})
+}
+
+// responseRecorder captures the status code and body size of a response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (rw *responseRecorder) WriteHeader(status int) {
+	if !rw.wroteHeader {
+		rw.status = status
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *responseRecorder) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rw *responseRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
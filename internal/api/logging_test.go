@@ -0,0 +1,149 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ivan-almanza/notes-api/internal/store"
+	"github.com/ivan-almanza/notes-api/internal/tracing"
+)
+
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Invalid JSON log line %q: %v", line, err)
+		}
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestRequestID_Generated(t *testing.T) {
+	var ctxID string
+	handler := WithRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxID, _ = r.Context().Value(ContextKeyRequestID).(string)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/notes", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if ctxID == "" {
+		t.Fatal("Request ID should be stored in the context")
+	}
+	if w.Header().Get(RequestIDHeader) != ctxID {
+		t.Errorf("Expected response header %q, got %q", ctxID, w.Header().Get(RequestIDHeader))
+	}
+}
+
+func TestRequestID_Propagated(t *testing.T) {
+	handler := WithRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/notes", nil)
+	req.Header.Set(RequestIDHeader, "upstream-123")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if got := w.Header().Get(RequestIDHeader); got != "upstream-123" {
+		t.Errorf("Expected upstream-123, got %q", got)
+	}
+}
+
+func TestRequestLogging_AccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	token, _ := testKeys.GenerateToken("user-123", 0)
+	mux := http.NewServeMux()
+	mux.Handle("GET /notes", WithAuth(testKeys, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})))
+	handler := WithRequestID(WithRequestLogging(logger, mux))
+
+	req := httptest.NewRequest(http.MethodGet, "/notes", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(RequestIDHeader, "req-123")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := decodeLogLines(t, &buf)
+	if len(lines) != 1 {
+		t.Fatalf("Expected 1 access log line, got %d", len(lines))
+	}
+
+	entry := lines[0]
+	if entry["status"] != float64(200) || entry["bytes"] != float64(5) {
+		t.Errorf("Unexpected status/bytes: %v", entry)
+	}
+	if entry["route"] != "GET /notes" || entry["user_id"] != "user-123" || entry["request_id"] != "req-123" {
+		t.Errorf("Unexpected route/user_id/request_id: %v", entry)
+	}
+	if _, ok := entry["latency_ms"]; !ok {
+		t.Error("Access log should contain latency_ms")
+	}
+}
+
+func TestRequestLogging_LeavesCallersRequestAlone(t *testing.T) {
+	exporter := &spanRecorder{}
+	tracer := tracing.NewTracer(tracing.Options{SampleRatio: 1, Exporter: exporter})
+	tracing.SetTracer(tracer)
+	defer tracing.SetTracer(nil)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /route-test/{id}", recordRoute(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	// Every layer passes a request with its own context down.
+	inner := WithRequestLogging(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r.WithContext(r.Context()))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/route-test/1", nil)
+	WithTracing(inner).ServeHTTP(httptest.NewRecorder(), req)
+	tracer.Shutdown(context.Background())
+
+	if req.Pattern != "" {
+		t.Errorf("Expected the caller's request to be left alone, got pattern %q", req.Pattern)
+	}
+	if len(exporter.spans) != 1 || exporter.spans[0].Name != "GET /route-test/{id}" {
+		t.Errorf("Expected the server span to be named after the route, got %+v", exporter.spans)
+	}
+}
+
+func TestRequestLogging_LogsInternalError(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	mockStore := &MockNoteStore{
+		ListNotesFunc: func(ctx context.Context, userID string) ([]*store.Note, error) {
+			return nil, errors.New("connection reset by peer")
+		},
+	}
+	notesHandler := NewNotesHandler(mockStore)
+	handler := WithRequestLogging(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), ContextKeyUserID, "user-123")
+		notesHandler.GetNotes(w, r.WithContext(ctx))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/notes", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "connection reset") {
+		t.Error("Cause must not be sent to the client")
+	}
+	if !strings.Contains(buf.String(), "connection reset by peer") {
+		t.Errorf("Expected the cause to be logged, got %s", buf.String())
+	}
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/metrics"
+)
+
+var (
+	httpRequestsTotal = metrics.NewCounterVec(
+		"notes_http_requests_total",
+		"HTTP requests processed, by method, route pattern and status code.",
+		"method", "route", "status",
+	)
+	httpRequestDuration = metrics.NewHistogramVec(
+		"notes_http_request_duration_seconds",
+		"HTTP request latency, by method, route pattern and status code.",
+		metrics.DefBuckets,
+		"method", "route", "status",
+	)
+	loginsTotal = metrics.NewCounterVec(
+		"notes_auth_logins_total",
+		"Login attempts, by result (success, failure or error).",
+		"result",
+	)
+)
+
+// WithMetrics records request counts and latencies. Requests that did not
+// match a route are grouped under "unmatched" to bound label cardinality.
+func WithMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := newResponseRecorder(w)
+
+		next.ServeHTTP(rec, r)
+
+		route := matchedRoute(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(rec.status)
+		httpRequestsTotal.WithLabelValues(r.Method, route, status).Inc()
+		httpRequestDuration.WithLabelValues(r.Method, route, status).ObserveDuration(start)
+	})
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ivan-almanza/notes-api/internal/metrics"
+)
+
+func TestWithMetrics_LabelsByRoutePattern(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics-test/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := WithMetrics(mux)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics-test/1", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics-test/2", nil))
+
+	var sb strings.Builder
+	metrics.Default.WriteTo(&sb)
+
+	expected := `notes_http_requests_total{method="GET",route="GET /metrics-test/{id}",status="418"} 2`
+	if !strings.Contains(sb.String(), expected) {
+		t.Errorf("Expected %q in exposition:\n%s", expected, sb.String())
+	}
+	if !strings.Contains(sb.String(), `notes_http_request_duration_seconds_count{method="GET",route="GET /metrics-test/{id}",status="418"} 2`) {
+		t.Error("Expected latency histogram for the route")
+	}
+}
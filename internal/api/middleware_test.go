@@ -1,13 +1,28 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/ivan-almanza/notes-api/internal/auth"
+	"github.com/ivan-almanza/notes-api/internal/metrics"
+	"github.com/ivan-almanza/notes-api/internal/store"
+	"github.com/ivan-almanza/notes-api/internal/store/memory"
+	"github.com/ivan-almanza/notes-api/internal/tracing"
 )
 
+// testKeys signs and verifies the tokens of every test in the package.
+var testKeys = auth.NewKeys("test-secret")
+
 func TestAuthMiddleware_NoHeader(t *testing.T) {
 	dummyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		t.Error("Dummy handler should not be executed")
@@ -16,7 +31,7 @@ func TestAuthMiddleware_NoHeader(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
 	w := httptest.NewRecorder()
 
-	WithAuth(dummyHandler).ServeHTTP(w, req)
+	WithAuth(testKeys, dummyHandler).ServeHTTP(w, req)
 
 	if w.Code != http.StatusUnauthorized {
 		t.Errorf("Expected status 401, got %d", w.Code)
@@ -32,7 +47,7 @@ func TestAuthMiddleware_BadFormat(t *testing.T) {
 	req.Header.Set("Authorization", "Token xyz")
 	w := httptest.NewRecorder()
 
-	WithAuth(dummyHandler).ServeHTTP(w, req)
+	WithAuth(testKeys, dummyHandler).ServeHTTP(w, req)
 
 	if w.Code != http.StatusUnauthorized {
 		t.Errorf("Expected status 401, got %d", w.Code)
@@ -48,7 +63,7 @@ func TestAuthMiddleware_InvalidToken(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer invalid-token")
 	w := httptest.NewRecorder()
 
-	WithAuth(dummyHandler).ServeHTTP(w, req)
+	WithAuth(testKeys, dummyHandler).ServeHTTP(w, req)
 
 	if w.Code != http.StatusUnauthorized {
 		t.Errorf("Expected status 401, got %d", w.Code)
@@ -57,7 +72,7 @@ func TestAuthMiddleware_InvalidToken(t *testing.T) {
 
 func TestAuthMiddleware_Success(t *testing.T) {
 	userID := "user-123"
-	token, _ := auth.GenerateToken(userID)
+	token, _ := testKeys.GenerateToken(userID, 0)
 
 	dummyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctxUserID := r.Context().Value(ContextKeyUserID)
@@ -74,9 +89,173 @@ func TestAuthMiddleware_Success(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer "+token)
 	w := httptest.NewRecorder()
 
-	WithAuth(dummyHandler).ServeHTTP(w, req)
+	WithAuth(testKeys, dummyHandler).ServeHTTP(w, req)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status 200, got %d", w.Code)
 	}
 }
+
+// userStatusFunc adapts a function to store.UserStatusStorer.
+type userStatusFunc func(ctx context.Context, userID string) (*store.UserStatus, error)
+
+func (f userStatusFunc) GetUserStatus(ctx context.Context, userID string) (*store.UserStatus, error) {
+	return f(ctx, userID)
+}
+
+func TestActiveUserMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		generation int64 // of the token
+		status     *store.UserStatus
+		err        error
+		wantCode   int
+		wantErr    string
+	}{
+		{"never revoked", 0, &store.UserStatus{}, nil, http.StatusOK, ""},
+		{"current generation", 2, &store.UserStatus{TokenGeneration: 2}, nil, http.StatusOK, ""},
+		{"revoked after issue", 1, &store.UserStatus{TokenGeneration: 2}, nil, http.StatusUnauthorized, CodeTokenRevoked},
+		{"token without generation, revoked", 0, &store.UserStatus{TokenGeneration: 1}, nil, http.StatusUnauthorized, CodeTokenRevoked},
+		{"disabled", 0, &store.UserStatus{Disabled: true}, nil, http.StatusForbidden, CodeAccountDisabled},
+		{"deleted user", 0, nil, store.ErrNotFound, http.StatusUnauthorized, CodeInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, _ := testKeys.GenerateToken("user-123", tt.generation)
+			users := userStatusFunc(func(ctx context.Context, userID string) (*store.UserStatus, error) {
+				if userID != "user-123" {
+					t.Errorf("Expected user-123, got %q", userID)
+				}
+				return tt.status, tt.err
+			})
+			ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			WithAuth(testKeys, WithActiveUser(users, ok)).ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("Expected status %d, got %d", tt.wantCode, w.Code)
+			}
+			if tt.wantErr != "" {
+				if p := decodeProblem(t, w); p.Code != tt.wantErr {
+					t.Errorf("Expected code %q, got %q", tt.wantErr, p.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestActiveUserMiddleware_LoginRightAfterRevocation(t *testing.T) {
+	ctx := context.Background()
+	users := memory.New()
+	hash, _ := auth.Hash("password123")
+	users.Create(ctx, &store.User{Email: "ada@example.com", Password: hash})
+	handler := NewAuthHandler(users, testKeys)
+	login := func() string {
+		w := httptest.NewRecorder()
+		handler.Login(w, httptest.NewRequest(http.MethodPost, "/auth/login",
+			strings.NewReader(`{"email":"ada@example.com","password":"password123"}`)))
+		var resp LoginResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp.Token
+	}
+	status := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		WithAuth(testKeys, WithActiveUser(users, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))).ServeHTTP(w, req)
+		return w.Code
+	}
+
+	old := login()
+	if err := users.SetPassword(ctx, "ada@example.com", hash); err != nil {
+		t.Fatalf("SetPassword failed: %v", err)
+	}
+	// Within the same second as the revocation.
+	fresh := login()
+
+	if code := status(old); code != http.StatusUnauthorized {
+		t.Errorf("Expected the token issued before the reset to be rejected, got %d", code)
+	}
+	if code := status(fresh); code != http.StatusOK {
+		t.Errorf("Expected the token issued after the reset to be accepted, got %d", code)
+	}
+}
+
+func TestClientIdentityMiddleware(t *testing.T) {
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+		{Subject: pkix.Name{CommonName: "billing"}},
+	}}}
+	unverified := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+		{Subject: pkix.Name{CommonName: "billing"}},
+	}}
+
+	tests := []struct {
+		name string
+		tls  *tls.ConnectionState
+		want string
+	}{
+		{"verified certificate", verified, "billing"},
+		{"unverified certificate", unverified, ""},
+		{"plain HTTP", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, nil))
+			var got string
+			handler := WithRequestLogging(logger, WithClientIdentity(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ServiceIDFromContext(r.Context())
+			})))
+
+			req := httptest.NewRequest(http.MethodGet, "/notes", nil)
+			req.TLS = tt.tls
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("Expected service %q, got %q", tt.want, got)
+			}
+			entry := decodeLogLines(t, &buf)[0]
+			if logged, _ := entry["service_id"].(string); logged != tt.want {
+				t.Errorf("Expected service_id %q in the access log, got %q", tt.want, logged)
+			}
+		})
+	}
+}
+
+func TestClientIdentityMiddleware_KeepsRouteForOuterMiddleware(t *testing.T) {
+	exporter := &spanRecorder{}
+	tracer := tracing.NewTracer(tracing.Options{SampleRatio: 1, Exporter: exporter})
+	tracing.SetTracer(tracer)
+	defer tracing.SetTracer(nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /mtls-test/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	handler := WithRequestID(WithTracing(WithRequestLogging(logger, WithMetrics(WithClientIdentity(mux)))))
+
+	req := httptest.NewRequest(http.MethodGet, "/mtls-test/1", nil)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+		{Subject: pkix.Name{CommonName: "billing"}},
+	}}}
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	tracer.Shutdown(context.Background())
+
+	entry := decodeLogLines(t, &buf)[0]
+	if entry["route"] != "GET /mtls-test/{id}" || entry["service_id"] != "billing" {
+		t.Errorf("Expected the route and service in the access log, got %v", entry)
+	}
+	var sb strings.Builder
+	metrics.Default.WriteTo(&sb)
+	expected := `notes_http_requests_total{method="GET",route="GET /mtls-test/{id}",status="200"} 1`
+	if !strings.Contains(sb.String(), expected) {
+		t.Errorf("Expected %q in exposition:\n%s", expected, sb.String())
+	}
+	if len(exporter.spans) != 1 || exporter.spans[0].Name != "GET /mtls-test/{id}" {
+		t.Errorf("Expected the server span to be named after the route, got %+v", exporter.spans)
+	}
+}
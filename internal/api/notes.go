@@ -1,10 +1,17 @@
 package api
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ivan-almanza/notes-api/internal/store"
+	"github.com/ivan-almanza/notes-api/pkg/apitypes"
 )
 
 type NotesHandler struct {
@@ -15,26 +22,31 @@ func NewNotesHandler(store store.NoteStorer) *NotesHandler {
 	return &NotesHandler{store: store}
 }
 
-type CreateNoteRequest struct {
-	Content string `json:"content"`
-}
+type (
+	CreateNoteRequest  = apitypes.CreateNoteRequest
+	CreateNoteResponse = apitypes.CreateNoteResponse
+	UpdateNoteRequest  = apitypes.UpdateNoteRequest
+	ListNotesResponse  = apitypes.ListNotesResponse
+	ListNotesMeta      = apitypes.ListNotesMeta
+)
 
-type CreateNoteResponse struct {
-	ID string `json:"id"`
-}
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
 
 func (h *NotesHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
 	// 1. Get UserID from context (middleware injected)
 	userID, ok := r.Context().Value(ContextKeyUserID).(string)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		WriteError(w, r, NewError(http.StatusUnauthorized, CodeUnauthorized, "Unauthorized"))
 		return
 	}
 
 	// 2. Parse body
 	var req CreateNoteRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if err := DecodeJSON(w, r, &req); err != nil {
+		WriteError(w, r, err)
 		return
 	}
 
@@ -46,7 +58,7 @@ func (h *NotesHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
 
 	// 4. Save to Store
 	if err := h.store.CreateNote(r.Context(), note); err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		WriteError(w, r, InternalError(err))
 		return
 	}
 
@@ -56,26 +68,198 @@ func (h *NotesHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(CreateNoteResponse{ID: note.ID})
 }
 
+// GetNotes lists the caller's notes. With ?limit= or ?cursor= it returns one
+// page, oldest first, and meta.next_cursor when more notes follow; without
+// them it returns every note.
 func (h *NotesHandler) GetNotes(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(ContextKeyUserID).(string)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		WriteError(w, r, NewError(http.StatusUnauthorized, CodeUnauthorized, "Unauthorized"))
+		return
+	}
+
+	query := r.URL.Query()
+	if !query.Has("limit") && !query.Has("cursor") {
+		notes, err := h.store.ListNotes(r.Context(), userID)
+		if err != nil {
+			WriteError(w, r, InternalError(err))
+			return
+		}
+		writeNotes(w, notes, "")
 		return
 	}
 
-	notes, err := h.store.ListNotes(r.Context(), userID)
+	limit := DefaultPageSize
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > MaxPageSize {
+			WriteError(w, r, NewError(http.StatusBadRequest, CodeInvalidQuery, fmt.Sprintf("limit must be an integer between 1 and %d", MaxPageSize)))
+			return
+		}
+		limit = n
+	}
+
+	var after *store.NoteCursor
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := decodeNoteCursor(value)
+		if err != nil {
+			WriteError(w, r, NewError(http.StatusBadRequest, CodeInvalidQuery, "cursor is invalid"))
+			return
+		}
+		after = cursor
+	}
+
+	// Fetch one extra note to learn whether another page follows.
+	notes, err := h.store.ListNotesPage(r.Context(), userID, after, limit+1)
+	if err != nil {
+		WriteError(w, r, InternalError(err))
+		return
+	}
+
+	var next string
+	if len(notes) > limit {
+		notes = notes[:limit]
+		last := notes[limit-1]
+		next = encodeNoteCursor(store.NoteCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	writeNotes(w, notes, next)
+}
+
+func writeNotes(w http.ResponseWriter, notes []*store.Note, next string) {
+	if notes == nil {
+		notes = []*store.Note{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ListNotesResponse{
+		Data: notes,
+		Meta: ListNotesMeta{Count: len(notes), NextCursor: next},
+	})
+}
+
+// Cursors are opaque to clients: base64url of "<created_at>,<id>".
+func encodeNoteCursor(c store.NoteCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.CreatedAt.UTC().Format(time.RFC3339Nano) + "," + c.ID))
+}
+
+func decodeNoteCursor(s string) (*store.NoteCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, err
+	}
+	createdAt, id, ok := strings.Cut(string(raw), ",")
+	if !ok || id == "" {
+		return nil, errors.New("malformed cursor")
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	return &store.NoteCursor{CreatedAt: t, ID: id}, nil
+}
+
+var errNoteNotFound = NewError(http.StatusNotFound, CodeNotFound, "Note not found")
+
+// GetNote returns one of the caller's notes. Notes of other users are
+// reported as not found.
+func (h *NotesHandler) GetNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(ContextKeyUserID).(string)
+	if !ok {
+		WriteError(w, r, NewError(http.StatusUnauthorized, CodeUnauthorized, "Unauthorized"))
 		return
 	}
 
-	response := map[string]interface{}{
-		"data": notes,
-		"meta": map[string]interface{}{
-			"count": len(notes),
-		},
+	note, err := h.store.GetNote(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		if err == store.ErrNotFound {
+			WriteError(w, r, errNoteNotFound)
+			return
+		}
+		WriteError(w, r, InternalError(err))
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(note)
+}
+
+// UpdateNote replaces the content of one of the caller's notes.
+func (h *NotesHandler) UpdateNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(ContextKeyUserID).(string)
+	if !ok {
+		WriteError(w, r, NewError(http.StatusUnauthorized, CodeUnauthorized, "Unauthorized"))
+		return
+	}
+
+	var req UpdateNoteRequest
+	if err := DecodeJSON(w, r, &req); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	note := &store.Note{ID: r.PathValue("id"), UserID: userID, Content: req.Content}
+	if err := h.store.UpdateNote(r.Context(), note); err != nil {
+		if err == store.ErrNotFound {
+			WriteError(w, r, errNoteNotFound)
+			return
+		}
+		WriteError(w, r, InternalError(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(note)
+}
+
+// DeleteNote deletes one of the caller's notes.
+func (h *NotesHandler) DeleteNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(ContextKeyUserID).(string)
+	if !ok {
+		WriteError(w, r, NewError(http.StatusUnauthorized, CodeUnauthorized, "Unauthorized"))
+		return
+	}
+
+	if err := h.store.DeleteNote(r.Context(), userID, r.PathValue("id")); err != nil {
+		if err == store.ErrNotFound {
+			WriteError(w, r, errNoteNotFound)
+			return
+		}
+		WriteError(w, r, InternalError(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SearchNotes returns the caller's notes containing ?q=, newest first.
+func (h *NotesHandler) SearchNotes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(ContextKeyUserID).(string)
+	if !ok {
+		WriteError(w, r, NewError(http.StatusUnauthorized, CodeUnauthorized, "Unauthorized"))
+		return
+	}
+
+	query := r.URL.Query()
+	search := strings.TrimSpace(query.Get("q"))
+	if search == "" {
+		WriteError(w, r, NewError(http.StatusBadRequest, CodeInvalidQuery, "q is required"))
+		return
+	}
+
+	limit := DefaultPageSize
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > MaxPageSize {
+			WriteError(w, r, NewError(http.StatusBadRequest, CodeInvalidQuery, fmt.Sprintf("limit must be an integer between 1 and %d", MaxPageSize)))
+			return
+		}
+		limit = n
+	}
+
+	notes, err := h.store.SearchNotes(r.Context(), userID, search, limit)
+	if err != nil {
+		WriteError(w, r, InternalError(err))
+		return
+	}
+	writeNotes(w, notes, "")
 }
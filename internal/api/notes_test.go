@@ -16,6 +16,11 @@ import (
 type MockNoteStore struct {
 	CreateNoteFunc func(ctx context.Context, note *store.Note) error
 	ListNotesFunc  func(ctx context.Context, userID string) ([]*store.Note, error)
+	ListPageFunc   func(ctx context.Context, userID string, after *store.NoteCursor, limit int) ([]*store.Note, error)
+	GetNoteFunc    func(ctx context.Context, userID, id string) (*store.Note, error)
+	UpdateNoteFunc func(ctx context.Context, note *store.Note) error
+	DeleteNoteFunc func(ctx context.Context, userID, id string) error
+	SearchFunc     func(ctx context.Context, userID, query string, limit int) ([]*store.Note, error)
 }
 
 func (m *MockNoteStore) CreateNote(ctx context.Context, note *store.Note) error {
@@ -32,6 +37,41 @@ func (m *MockNoteStore) ListNotes(ctx context.Context, userID string) ([]*store.
 	return nil, nil
 }
 
+func (m *MockNoteStore) ListNotesPage(ctx context.Context, userID string, after *store.NoteCursor, limit int) ([]*store.Note, error) {
+	if m.ListPageFunc != nil {
+		return m.ListPageFunc(ctx, userID, after, limit)
+	}
+	return nil, nil
+}
+
+func (m *MockNoteStore) GetNote(ctx context.Context, userID, id string) (*store.Note, error) {
+	if m.GetNoteFunc != nil {
+		return m.GetNoteFunc(ctx, userID, id)
+	}
+	return nil, store.ErrNotFound
+}
+
+func (m *MockNoteStore) UpdateNote(ctx context.Context, note *store.Note) error {
+	if m.UpdateNoteFunc != nil {
+		return m.UpdateNoteFunc(ctx, note)
+	}
+	return nil
+}
+
+func (m *MockNoteStore) DeleteNote(ctx context.Context, userID, id string) error {
+	if m.DeleteNoteFunc != nil {
+		return m.DeleteNoteFunc(ctx, userID, id)
+	}
+	return nil
+}
+
+func (m *MockNoteStore) SearchNotes(ctx context.Context, userID, query string, limit int) ([]*store.Note, error) {
+	if m.SearchFunc != nil {
+		return m.SearchFunc(ctx, userID, query, limit)
+	}
+	return nil, nil
+}
+
 func TestCreateNote_Authorized(t *testing.T) {
 	userID := "user-123"
 	mockStore := &MockNoteStore{
@@ -117,3 +157,164 @@ func TestGetNotes_Format(t *testing.T) {
 		t.Errorf("Expected 2 notes, got %d", len(data))
 	}
 }
+
+func TestGetNotes_Pagination(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	all := []*store.Note{
+		{ID: "1", CreatedAt: base},
+		{ID: "2", CreatedAt: base.Add(time.Second)},
+		{ID: "3", CreatedAt: base.Add(2 * time.Second)},
+	}
+	mockStore := &MockNoteStore{
+		ListPageFunc: func(ctx context.Context, userID string, after *store.NoteCursor, limit int) ([]*store.Note, error) {
+			start := 0
+			if after != nil {
+				for i, n := range all {
+					if n.ID == after.ID && n.CreatedAt.Equal(after.CreatedAt) {
+						start = i + 1
+					}
+				}
+			}
+			return all[start:min(start+limit, len(all))], nil
+		},
+	}
+	handler := NewNotesHandler(mockStore)
+
+	get := func(query string) ListNotesResponse {
+		req := httptest.NewRequest(http.MethodGet, "/notes"+query, nil)
+		req = req.WithContext(context.WithValue(req.Context(), ContextKeyUserID, "user-123"))
+		w := httptest.NewRecorder()
+		handler.GetNotes(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+		}
+		var response ListNotesResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		return response
+	}
+
+	page := get("?limit=2")
+	if len(page.Data) != 2 || page.Meta.NextCursor == "" {
+		t.Fatalf("Expected 2 notes and a next cursor, got %+v", page)
+	}
+
+	page = get("?limit=2&cursor=" + page.Meta.NextCursor)
+	if len(page.Data) != 1 || page.Data[0].ID != "3" || page.Meta.NextCursor != "" {
+		t.Errorf("Expected the last note without a next cursor, got %+v", page)
+	}
+}
+
+func TestGetNotes_InvalidPageParams(t *testing.T) {
+	handler := NewNotesHandler(&MockNoteStore{})
+
+	for _, query := range []string{"?limit=0", "?limit=101", "?limit=abc", "?cursor=!!", "?cursor=bm9jb21tYQ"} {
+		req := httptest.NewRequest(http.MethodGet, "/notes"+query, nil)
+		req = req.WithContext(context.WithValue(req.Context(), ContextKeyUserID, "user-123"))
+		w := httptest.NewRecorder()
+		handler.GetNotes(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, w.Code)
+		}
+	}
+}
+
+// serveNotes routes req through a mux so r.PathValue works, with the user
+// already authenticated.
+func serveNotes(handler *NotesHandler, req *http.Request) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /notes/search", handler.SearchNotes)
+	mux.HandleFunc("GET /notes/{id}", handler.GetNote)
+	mux.HandleFunc("PUT /notes/{id}", handler.UpdateNote)
+	mux.HandleFunc("DELETE /notes/{id}", handler.DeleteNote)
+
+	req = req.WithContext(context.WithValue(req.Context(), ContextKeyUserID, "user-123"))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetNote_OtherUsersNoteIsNotFound(t *testing.T) {
+	handler := NewNotesHandler(&MockNoteStore{
+		GetNoteFunc: func(ctx context.Context, userID, id string) (*store.Note, error) {
+			if userID == "user-123" && id == "note-1" {
+				return &store.Note{ID: id, UserID: userID, Content: "mine"}, nil
+			}
+			return nil, store.ErrNotFound
+		},
+	})
+
+	w := serveNotes(handler, httptest.NewRequest(http.MethodGet, "/notes/note-1", nil))
+	var note store.Note
+	json.NewDecoder(w.Body).Decode(&note)
+	if w.Code != http.StatusOK || note.Content != "mine" {
+		t.Errorf("Expected the note, got %d %+v", w.Code, note)
+	}
+
+	w = serveNotes(handler, httptest.NewRequest(http.MethodGet, "/notes/note-2", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestUpdateNote(t *testing.T) {
+	handler := NewNotesHandler(&MockNoteStore{
+		UpdateNoteFunc: func(ctx context.Context, note *store.Note) error {
+			if note.ID != "note-1" || note.UserID != "user-123" || note.Content != "edited" {
+				t.Errorf("Unexpected update: %+v", note)
+			}
+			note.UpdatedAt = time.Now()
+			return nil
+		},
+	})
+
+	w := serveNotes(handler, httptest.NewRequest(http.MethodPut, "/notes/note-1", bytes.NewBufferString(`{"content":"edited"}`)))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	w = serveNotes(handler, httptest.NewRequest(http.MethodPut, "/notes/note-1", bytes.NewBufferString(`{"content":""}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for empty content, got %d", w.Code)
+	}
+}
+
+func TestDeleteNote(t *testing.T) {
+	handler := NewNotesHandler(&MockNoteStore{
+		DeleteNoteFunc: func(ctx context.Context, userID, id string) error {
+			if id != "note-1" {
+				return store.ErrNotFound
+			}
+			return nil
+		},
+	})
+
+	if w := serveNotes(handler, httptest.NewRequest(http.MethodDelete, "/notes/note-1", nil)); w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+	if w := serveNotes(handler, httptest.NewRequest(http.MethodDelete, "/notes/note-2", nil)); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestSearchNotes(t *testing.T) {
+	handler := NewNotesHandler(&MockNoteStore{
+		SearchFunc: func(ctx context.Context, userID, query string, limit int) ([]*store.Note, error) {
+			if query != "milk" || limit != DefaultPageSize {
+				t.Errorf("Unexpected search %q limit %d", query, limit)
+			}
+			return []*store.Note{{ID: "1", Content: "buy milk"}}, nil
+		},
+	})
+
+	w := serveNotes(handler, httptest.NewRequest(http.MethodGet, "/notes/search?q=milk", nil))
+	var response ListNotesResponse
+	json.NewDecoder(w.Body).Decode(&response)
+	if w.Code != http.StatusOK || len(response.Data) != 1 {
+		t.Errorf("Expected one match, got %d %+v", w.Code, response)
+	}
+
+	if w := serveNotes(handler, httptest.NewRequest(http.MethodGet, "/notes/search", nil)); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without q, got %d", w.Code)
+	}
+}
@@ -0,0 +1,284 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/auth"
+	"github.com/ivan-almanza/notes-api/internal/store"
+	"github.com/ivan-almanza/notes-api/pkg/apitypes"
+)
+
+const (
+	DeviceCodeGrantType = apitypes.DeviceCodeGrantType
+
+	deviceCodeTTL      = 10 * time.Minute
+	deviceCodeInterval = 5 // seconds
+	slowDownIncrement  = 5 // seconds, RFC 8628 section 3.5
+)
+
+// OAuthHandler implements the OAuth2 device authorization grant (RFC 8628)
+// so clients without a browser can obtain a token usable with WithAuth.
+type OAuthHandler struct {
+	store           OAuthStore
+	keys            *auth.Keys
+	verificationURI string
+	now             func() time.Time
+}
+
+// OAuthStore is what OAuthHandler needs: device codes, and the status of
+// the approving user so the token joins their current token generation.
+type OAuthStore interface {
+	store.DeviceCodeStorer
+	store.UserStatusStorer
+}
+
+func NewOAuthHandler(store OAuthStore, keys *auth.Keys, verificationURI string) *OAuthHandler {
+	return &OAuthHandler{store: store, keys: keys, verificationURI: verificationURI, now: time.Now}
+}
+
+type (
+	DeviceCodeResponse     = apitypes.DeviceCodeResponse
+	DeviceApprovalRequest  = apitypes.DeviceApprovalRequest
+	DeviceApprovalResponse = apitypes.DeviceApprovalResponse
+	TokenResponse          = apitypes.TokenResponse
+	OAuthError             = apitypes.OAuthError
+)
+
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(OAuthError{Error: code, ErrorDescription: description})
+}
+
+// DeviceCode starts a device authorization and returns the codes the client
+// shows to the user and polls with.
+func (h *OAuthHandler) DeviceCode(w http.ResponseWriter, r *http.Request) {
+	clientID := r.PostFormValue("client_id")
+	if clientID == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "client_id is required")
+		return
+	}
+
+	deviceCode, err := auth.GenerateDeviceCode()
+	if err != nil {
+		logRequestError(r, err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	// User codes are short, so retry on the rare collision.
+	var code *store.DeviceCode
+	for attempt := 0; attempt < 3; attempt++ {
+		userCode, err := auth.GenerateUserCode()
+		if err != nil {
+			logRequestError(r, err)
+			writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+			return
+		}
+
+		code = &store.DeviceCode{
+			DeviceCode: deviceCode,
+			UserCode:   userCode,
+			ClientID:   clientID,
+			Status:     store.DeviceCodePending,
+			Interval:   deviceCodeInterval,
+			ExpiresAt:  h.now().Add(deviceCodeTTL),
+		}
+		err = h.store.CreateDeviceCode(r.Context(), code)
+		if err == nil {
+			break
+		}
+		if err != store.ErrDuplicateCode || attempt == 2 {
+			logRequestError(r, err)
+			writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(DeviceCodeResponse{
+		DeviceCode:              code.DeviceCode,
+		UserCode:                code.UserCode,
+		VerificationURI:         h.verificationURI,
+		VerificationURIComplete: h.verificationURI + "?user_code=" + code.UserCode,
+		ExpiresIn:               int(deviceCodeTTL.Seconds()),
+		Interval:                code.Interval,
+	})
+}
+
+// ApproveDevice lets an authenticated user approve (or deny) a user code.
+// It must be wrapped with WithAuth.
+func (h *OAuthHandler) ApproveDevice(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(ContextKeyUserID).(string)
+	if !ok {
+		WriteError(w, r, NewError(http.StatusUnauthorized, CodeUnauthorized, "Unauthorized"))
+		return
+	}
+
+	var req DeviceApprovalRequest
+	if err := DecodeJSON(w, r, &req); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	userCode := auth.NormalizeUserCode(req.UserCode)
+
+	code, err := h.store.GetDeviceCodeByUserCode(r.Context(), userCode)
+	if err != nil {
+		if err == store.ErrNotFound {
+			WriteError(w, r, NewError(http.StatusNotFound, CodeNotFound, "Unknown user code"))
+			return
+		}
+		WriteError(w, r, InternalError(err))
+		return
+	}
+
+	if code.Expired(h.now()) || code.Status != store.DeviceCodePending {
+		WriteError(w, r, NewError(http.StatusBadRequest, CodeInvalidUserCode, "User code is no longer valid"))
+		return
+	}
+
+	status := store.DeviceCodeApproved
+	if req.Deny {
+		status = store.DeviceCodeDenied
+	}
+
+	if err := h.store.UpdateDeviceCodeStatus(r.Context(), userCode, status, userID); err != nil {
+		if err == store.ErrNotFound {
+			WriteError(w, r, NewError(http.StatusBadRequest, CodeInvalidUserCode, "User code is no longer valid"))
+			return
+		}
+		WriteError(w, r, InternalError(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DeviceApprovalResponse{Status: status})
+}
+
+// Token exchanges an approved device code for an access token. Clients poll
+// it and receive authorization_pending or slow_down until the user decides.
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	if grantType := r.PostFormValue("grant_type"); grantType != DeviceCodeGrantType {
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "")
+		return
+	}
+
+	deviceCode := r.PostFormValue("device_code")
+	if deviceCode == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "device_code is required")
+		return
+	}
+
+	code, err := h.store.GetDeviceCode(r.Context(), deviceCode)
+	if err != nil {
+		if err == store.ErrNotFound {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "")
+			return
+		}
+		logRequestError(r, err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	if clientID := r.PostFormValue("client_id"); clientID != code.ClientID {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "")
+		return
+	}
+
+	now := h.now()
+	if code.Expired(now) {
+		h.store.DeleteDeviceCode(r.Context(), deviceCode)
+		writeOAuthError(w, http.StatusBadRequest, "expired_token", "")
+		return
+	}
+
+	switch code.Status {
+	case store.DeviceCodeDenied:
+		h.store.DeleteDeviceCode(r.Context(), deviceCode)
+		writeOAuthError(w, http.StatusBadRequest, "access_denied", "")
+		return
+	case store.DeviceCodePending:
+		interval := code.Interval
+		tooFast := now.Sub(code.LastPolledAt) < time.Duration(interval)*time.Second
+		if tooFast {
+			interval += slowDownIncrement
+		}
+		if err := h.store.TouchDeviceCode(r.Context(), deviceCode, now, interval); err != nil {
+			logRequestError(r, err)
+			writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+			return
+		}
+		if tooFast {
+			writeOAuthError(w, http.StatusBadRequest, "slow_down", "")
+			return
+		}
+		writeOAuthError(w, http.StatusBadRequest, "authorization_pending", "")
+		return
+	}
+
+	// Approved: the device code is single-use.
+	if err := h.store.DeleteDeviceCode(r.Context(), deviceCode); err != nil {
+		if err == store.ErrNotFound {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "")
+			return
+		}
+		logRequestError(r, err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	status, err := h.store.GetUserStatus(r.Context(), code.UserID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "")
+			return
+		}
+		logRequestError(r, err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	token, err := h.keys.GenerateToken(code.UserID, status.TokenGeneration)
+	if err != nil {
+		logRequestError(r, err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(auth.TokenTTL.Seconds()),
+	})
+}
+
+// PurgeExpiredDeviceCodes deletes expired device codes every interval until
+// ctx is cancelled. Run it in its own goroutine.
+func (h *OAuthHandler) PurgeExpiredDeviceCodes(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := h.store.DeleteExpiredDeviceCodes(ctx, h.now())
+			if err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Failed to purge expired device codes", "error", err)
+				continue
+			}
+			if n > 0 {
+				slog.DebugContext(ctx, "Purged expired device codes", "count", n)
+			}
+		}
+	}
+}
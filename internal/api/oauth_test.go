@@ -0,0 +1,358 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/store"
+)
+
+// MockDeviceCodeStore implements OAuthStore for testing
+type MockDeviceCodeStore struct {
+	CreateDeviceCodeFunc        func(ctx context.Context, code *store.DeviceCode) error
+	GetDeviceCodeFunc           func(ctx context.Context, deviceCode string) (*store.DeviceCode, error)
+	GetDeviceCodeByUserCodeFunc func(ctx context.Context, userCode string) (*store.DeviceCode, error)
+	UpdateDeviceCodeStatusFunc  func(ctx context.Context, userCode, status, userID string) error
+	TouchDeviceCodeFunc         func(ctx context.Context, deviceCode string, polledAt time.Time, interval int) error
+	DeleteDeviceCodeFunc        func(ctx context.Context, deviceCode string) error
+	DeleteExpiredFunc           func(ctx context.Context, before time.Time) (int64, error)
+	GetUserStatusFunc           func(ctx context.Context, userID string) (*store.UserStatus, error)
+}
+
+func (m *MockDeviceCodeStore) CreateDeviceCode(ctx context.Context, code *store.DeviceCode) error {
+	if m.CreateDeviceCodeFunc != nil {
+		return m.CreateDeviceCodeFunc(ctx, code)
+	}
+	return nil
+}
+
+func (m *MockDeviceCodeStore) GetDeviceCode(ctx context.Context, deviceCode string) (*store.DeviceCode, error) {
+	if m.GetDeviceCodeFunc != nil {
+		return m.GetDeviceCodeFunc(ctx, deviceCode)
+	}
+	return nil, store.ErrNotFound
+}
+
+func (m *MockDeviceCodeStore) GetDeviceCodeByUserCode(ctx context.Context, userCode string) (*store.DeviceCode, error) {
+	if m.GetDeviceCodeByUserCodeFunc != nil {
+		return m.GetDeviceCodeByUserCodeFunc(ctx, userCode)
+	}
+	return nil, store.ErrNotFound
+}
+
+func (m *MockDeviceCodeStore) UpdateDeviceCodeStatus(ctx context.Context, userCode, status, userID string) error {
+	if m.UpdateDeviceCodeStatusFunc != nil {
+		return m.UpdateDeviceCodeStatusFunc(ctx, userCode, status, userID)
+	}
+	return nil
+}
+
+func (m *MockDeviceCodeStore) TouchDeviceCode(ctx context.Context, deviceCode string, polledAt time.Time, interval int) error {
+	if m.TouchDeviceCodeFunc != nil {
+		return m.TouchDeviceCodeFunc(ctx, deviceCode, polledAt, interval)
+	}
+	return nil
+}
+
+func (m *MockDeviceCodeStore) DeleteDeviceCode(ctx context.Context, deviceCode string) error {
+	if m.DeleteDeviceCodeFunc != nil {
+		return m.DeleteDeviceCodeFunc(ctx, deviceCode)
+	}
+	return nil
+}
+
+func (m *MockDeviceCodeStore) DeleteExpiredDeviceCodes(ctx context.Context, before time.Time) (int64, error) {
+	if m.DeleteExpiredFunc != nil {
+		return m.DeleteExpiredFunc(ctx, before)
+	}
+	return 0, nil
+}
+
+func (m *MockDeviceCodeStore) GetUserStatus(ctx context.Context, userID string) (*store.UserStatus, error) {
+	if m.GetUserStatusFunc != nil {
+		return m.GetUserStatusFunc(ctx, userID)
+	}
+	return &store.UserStatus{}, nil
+}
+
+func newFormRequest(path string, form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func newTokenRequest(deviceCode string) *http.Request {
+	return newFormRequest("/oauth/token", url.Values{
+		"grant_type":  {DeviceCodeGrantType},
+		"device_code": {deviceCode},
+		"client_id":   {"cli"},
+	})
+}
+
+func decodeOAuthError(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body OAuthError
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode error body: %v", err)
+	}
+	return body.Error
+}
+
+func TestDeviceCode_Success(t *testing.T) {
+	var created *store.DeviceCode
+	mockStore := &MockDeviceCodeStore{
+		CreateDeviceCodeFunc: func(ctx context.Context, code *store.DeviceCode) error {
+			created = code
+			return nil
+		},
+	}
+	handler := NewOAuthHandler(mockStore, testKeys, "https://example.com/device")
+
+	w := httptest.NewRecorder()
+	handler.DeviceCode(w, newFormRequest("/oauth/device/code", url.Values{"client_id": {"cli"}}))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response DeviceCodeResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if created == nil || response.DeviceCode != created.DeviceCode || response.UserCode != created.UserCode {
+		t.Error("Response should contain the stored codes")
+	}
+	if created.Status != store.DeviceCodePending || created.ClientID != "cli" {
+		t.Errorf("Unexpected stored code: %+v", created)
+	}
+	if response.VerificationURI != "https://example.com/device" {
+		t.Errorf("Unexpected verification_uri %q", response.VerificationURI)
+	}
+	if response.Interval <= 0 || response.ExpiresIn <= 0 {
+		t.Errorf("Expected positive interval and expires_in, got %d and %d", response.Interval, response.ExpiresIn)
+	}
+}
+
+func TestDeviceCode_MissingClientID(t *testing.T) {
+	handler := NewOAuthHandler(&MockDeviceCodeStore{}, testKeys, "https://example.com/device")
+
+	w := httptest.NewRecorder()
+	handler.DeviceCode(w, newFormRequest("/oauth/device/code", url.Values{}))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	if code := decodeOAuthError(t, w); code != "invalid_request" {
+		t.Errorf("Expected invalid_request, got %q", code)
+	}
+}
+
+func TestApproveDevice_Success(t *testing.T) {
+	mockStore := &MockDeviceCodeStore{
+		GetDeviceCodeByUserCodeFunc: func(ctx context.Context, userCode string) (*store.DeviceCode, error) {
+			return &store.DeviceCode{UserCode: userCode, Status: store.DeviceCodePending, ExpiresAt: time.Now().Add(time.Minute)}, nil
+		},
+		UpdateDeviceCodeStatusFunc: func(ctx context.Context, userCode, status, userID string) error {
+			if userCode != "BCDF-GHJK" || status != store.DeviceCodeApproved || userID != "user-123" {
+				t.Errorf("Unexpected update: %s %s %s", userCode, status, userID)
+			}
+			return nil
+		},
+	}
+	handler := NewOAuthHandler(mockStore, testKeys, "https://example.com/device")
+
+	req := httptest.NewRequest(http.MethodPost, "/oauth/device/approve", strings.NewReader(`{"user_code":"bcdfghjk"}`))
+	req = req.WithContext(context.WithValue(req.Context(), ContextKeyUserID, "user-123"))
+	w := httptest.NewRecorder()
+
+	handler.ApproveDevice(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+}
+
+func TestApproveDevice_Expired(t *testing.T) {
+	mockStore := &MockDeviceCodeStore{
+		GetDeviceCodeByUserCodeFunc: func(ctx context.Context, userCode string) (*store.DeviceCode, error) {
+			return &store.DeviceCode{UserCode: userCode, Status: store.DeviceCodePending, ExpiresAt: time.Now().Add(-time.Minute)}, nil
+		},
+		UpdateDeviceCodeStatusFunc: func(ctx context.Context, userCode, status, userID string) error {
+			t.Error("UpdateDeviceCodeStatus should not be called")
+			return nil
+		},
+	}
+	handler := NewOAuthHandler(mockStore, testKeys, "https://example.com/device")
+
+	req := httptest.NewRequest(http.MethodPost, "/oauth/device/approve", strings.NewReader(`{"user_code":"BCDF-GHJK"}`))
+	req = req.WithContext(context.WithValue(req.Context(), ContextKeyUserID, "user-123"))
+	w := httptest.NewRecorder()
+
+	handler.ApproveDevice(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestToken_AuthorizationPending(t *testing.T) {
+	mockStore := &MockDeviceCodeStore{
+		GetDeviceCodeFunc: func(ctx context.Context, deviceCode string) (*store.DeviceCode, error) {
+			return &store.DeviceCode{DeviceCode: deviceCode, ClientID: "cli", Status: store.DeviceCodePending, Interval: 5, ExpiresAt: time.Now().Add(time.Minute)}, nil
+		},
+	}
+	handler := NewOAuthHandler(mockStore, testKeys, "https://example.com/device")
+
+	w := httptest.NewRecorder()
+	handler.Token(w, newTokenRequest("device-123"))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	if code := decodeOAuthError(t, w); code != "authorization_pending" {
+		t.Errorf("Expected authorization_pending, got %q", code)
+	}
+}
+
+func TestToken_SlowDown(t *testing.T) {
+	var newInterval int
+	mockStore := &MockDeviceCodeStore{
+		GetDeviceCodeFunc: func(ctx context.Context, deviceCode string) (*store.DeviceCode, error) {
+			return &store.DeviceCode{
+				DeviceCode:   deviceCode,
+				ClientID:     "cli",
+				Status:       store.DeviceCodePending,
+				Interval:     5,
+				ExpiresAt:    time.Now().Add(time.Minute),
+				LastPolledAt: time.Now().Add(-time.Second),
+			}, nil
+		},
+		TouchDeviceCodeFunc: func(ctx context.Context, deviceCode string, polledAt time.Time, interval int) error {
+			newInterval = interval
+			return nil
+		},
+	}
+	handler := NewOAuthHandler(mockStore, testKeys, "https://example.com/device")
+
+	w := httptest.NewRecorder()
+	handler.Token(w, newTokenRequest("device-123"))
+
+	if code := decodeOAuthError(t, w); code != "slow_down" {
+		t.Errorf("Expected slow_down, got %q", code)
+	}
+	if newInterval != 10 {
+		t.Errorf("Expected interval to grow to 10, got %d", newInterval)
+	}
+}
+
+func TestToken_Approved(t *testing.T) {
+	deleted := false
+	mockStore := &MockDeviceCodeStore{
+		GetDeviceCodeFunc: func(ctx context.Context, deviceCode string) (*store.DeviceCode, error) {
+			return &store.DeviceCode{DeviceCode: deviceCode, ClientID: "cli", Status: store.DeviceCodeApproved, UserID: "user-123", ExpiresAt: time.Now().Add(time.Minute)}, nil
+		},
+		DeleteDeviceCodeFunc: func(ctx context.Context, deviceCode string) error {
+			deleted = true
+			return nil
+		},
+	}
+	handler := NewOAuthHandler(mockStore, testKeys, "https://example.com/device")
+
+	w := httptest.NewRecorder()
+	handler.Token(w, newTokenRequest("device-123"))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if !deleted {
+		t.Error("Device code should be consumed")
+	}
+
+	var response TokenResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.TokenType != "Bearer" {
+		t.Errorf("Expected Bearer token type, got %q", response.TokenType)
+	}
+
+	// The issued token must be accepted by WithAuth
+	protected := WithAuth(testKeys, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Context().Value(ContextKeyUserID) != "user-123" {
+			t.Errorf("Expected userID user-123, got %v", r.Context().Value(ContextKeyUserID))
+		}
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/notes", nil)
+	req.Header.Set("Authorization", "Bearer "+response.AccessToken)
+	rec := httptest.NewRecorder()
+	protected.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected WithAuth to accept the token, got %d", rec.Code)
+	}
+}
+
+func TestToken_Expired(t *testing.T) {
+	mockStore := &MockDeviceCodeStore{
+		GetDeviceCodeFunc: func(ctx context.Context, deviceCode string) (*store.DeviceCode, error) {
+			return &store.DeviceCode{DeviceCode: deviceCode, ClientID: "cli", Status: store.DeviceCodeApproved, ExpiresAt: time.Now().Add(-time.Minute)}, nil
+		},
+	}
+	handler := NewOAuthHandler(mockStore, testKeys, "https://example.com/device")
+
+	w := httptest.NewRecorder()
+	handler.Token(w, newTokenRequest("device-123"))
+
+	if code := decodeOAuthError(t, w); code != "expired_token" {
+		t.Errorf("Expected expired_token, got %q", code)
+	}
+}
+
+func TestToken_UnsupportedGrant(t *testing.T) {
+	handler := NewOAuthHandler(&MockDeviceCodeStore{}, testKeys, "https://example.com/device")
+
+	w := httptest.NewRecorder()
+	handler.Token(w, newFormRequest("/oauth/token", url.Values{"grant_type": {"password"}}))
+
+	if code := decodeOAuthError(t, w); code != "unsupported_grant_type" {
+		t.Errorf("Expected unsupported_grant_type, got %q", code)
+	}
+}
+
+func TestPurgeExpiredDeviceCodes_StopsOnCancel(t *testing.T) {
+	calls := make(chan time.Time, 10)
+	mockStore := &MockDeviceCodeStore{
+		DeleteExpiredFunc: func(ctx context.Context, before time.Time) (int64, error) {
+			calls <- before
+			return 1, nil
+		},
+	}
+	handler := NewOAuthHandler(mockStore, testKeys, "https://example.com/device")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		handler.PurgeExpiredDeviceCodes(ctx, 5*time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case <-calls:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a purge within the interval")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("PurgeExpiredDeviceCodes should return once the context is cancelled")
+	}
+}
@@ -0,0 +1,395 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	openAPIVersion = "3.1.0"
+	apiTitle       = "Notes API"
+	apiVersion     = "1.0.0"
+
+	formContentType = "application/x-www-form-urlencoded"
+)
+
+// RouteDoc describes a route for the OpenAPI document. JSON request and
+// response bodies are given as zero values of their Go types, e.g.
+// RegisterRequest{}; form bodies, which the handlers read field by field, are
+// listed in Form instead.
+type RouteDoc struct {
+	OperationID string
+	Summary     string
+	Description string
+	Tag         string
+	Query       []ParamDoc
+	Request     any
+	Form        []ParamDoc
+	Responses   []ResponseDoc
+}
+
+type ParamDoc struct {
+	Name        string
+	Description string
+	Type        string // "string" or "integer"
+	Required    bool
+	Enum        []string
+}
+
+type ResponseDoc struct {
+	Status      int
+	Description string
+	Body        any
+	// ContentType defaults to application/json when Body is set.
+	ContentType string
+}
+
+func jsonResponse(status int, description string, body any) ResponseDoc {
+	return ResponseDoc{Status: status, Description: description, Body: body}
+}
+
+func problemResponse(status int, description string) ResponseDoc {
+	return ResponseDoc{Status: status, Description: description, Body: Problem{}, ContentType: problemContentType}
+}
+
+// OpenAPI 3.1 document model, limited to what the API uses.
+type OpenAPIDocument struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       OpenAPIInfo                      `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Operation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary"`
+	Description string                     `json:"description,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Parameters  []Parameter                `json:"parameters,omitempty"`
+	RequestBody *RequestBody               `json:"requestBody,omitempty"`
+	Responses   map[string]*ResponseObject `json:"responses"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type ResponseObject struct {
+	Description string               `json:"description"`
+	Headers     map[string]*Header   `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Maximum              *int               `json:"maximum,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+var pathParamRegex = regexp.MustCompile(`\{([^}.]+)(\.\.\.)?\}`)
+
+// BuildOpenAPI generates the OpenAPI document for routes. It fails when a
+// route has no Doc, so undocumented routes cannot ship.
+func BuildOpenAPI(routes []Route) (*OpenAPIDocument, error) {
+	doc := &OpenAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info:    OpenAPIInfo{Title: apiTitle, Version: apiVersion},
+		Paths:   make(map[string]map[string]*Operation),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+			SecuritySchemes: map[string]SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+	schemas := &schemaBuilder{schemas: doc.Components.Schemas}
+
+	for _, route := range routes {
+		if route.Doc == nil {
+			return nil, fmt.Errorf("route %q has no OpenAPI documentation", route.Pattern)
+		}
+		path := pathParamRegex.ReplaceAllString(route.Path(), "{$1}")
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*Operation)
+		}
+		doc.Paths[path][strings.ToLower(route.Method())] = buildOperation(route, schemas)
+	}
+	return doc, nil
+}
+
+func buildOperation(route Route, schemas *schemaBuilder) *Operation {
+	d := route.Doc
+	op := &Operation{
+		OperationID: d.OperationID,
+		Summary:     d.Summary,
+		Description: d.Description,
+		Responses:   make(map[string]*ResponseObject),
+	}
+	if d.Tag != "" {
+		op.Tags = []string{d.Tag}
+	}
+
+	for _, match := range pathParamRegex.FindAllStringSubmatch(route.Path(), -1) {
+		op.Parameters = append(op.Parameters, Parameter{Name: match[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	for _, q := range d.Query {
+		op.Parameters = append(op.Parameters, Parameter{Name: q.Name, In: "query", Description: q.Description, Required: q.Required, Schema: &Schema{Type: q.Type, Enum: q.Enum}})
+	}
+
+	switch {
+	case d.Request != nil:
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: schemas.schemaFor(reflect.TypeOf(d.Request))}},
+		}
+	case len(d.Form) > 0:
+		form := &Schema{Type: "object", Properties: make(map[string]*Schema, len(d.Form))}
+		for _, f := range d.Form {
+			form.Properties[f.Name] = &Schema{Type: f.Type, Enum: f.Enum}
+			if f.Required {
+				form.Required = append(form.Required, f.Name)
+			}
+		}
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{formContentType: {Schema: form}},
+		}
+	}
+
+	responses := d.Responses[:len(d.Responses):len(d.Responses)]
+	if route.Idempotent {
+		maxKey := maxIdempotencyKeyLength
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:        IdempotencyKeyHeader,
+			In:          "header",
+			Description: "Retries with the same key get the first response, marked " + IdempotentReplayedHeader + ": true",
+			Schema:      &Schema{Type: "string", MaxLength: &maxKey},
+		})
+		responses = append(responses,
+			problemResponse(http.StatusConflict, "A request with the same Idempotency-Key is still in progress"),
+			problemResponse(http.StatusUnprocessableEntity, "The Idempotency-Key was used for a different request"))
+	}
+	if route.Auth {
+		op.Security = []map[string][]string{{"bearerAuth": {}}}
+		responses = append(responses,
+			problemResponse(http.StatusUnauthorized, "Missing, invalid or revoked bearer token"),
+			problemResponse(http.StatusForbidden, "Account is disabled"))
+	}
+	if route.RateLimited {
+		responses = append(responses, problemResponse(http.StatusTooManyRequests, "Rate limit exceeded"))
+	}
+	for _, resp := range responses {
+		// WithAuth and WithActiveUser reject requests before the rate
+		// limiter sees them.
+		rejected := resp.Status == http.StatusUnauthorized || resp.Status == http.StatusForbidden
+		limited := route.RateLimited && !(route.Auth && rejected)
+		op.Responses[strconv.Itoa(resp.Status)] = buildResponse(resp, limited, schemas)
+	}
+	return op
+}
+
+func buildResponse(resp ResponseDoc, rateLimited bool, schemas *schemaBuilder) *ResponseObject {
+	obj := &ResponseObject{Description: resp.Description}
+	if resp.Body != nil {
+		contentType := resp.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		obj.Content = map[string]MediaType{contentType: {Schema: schemas.schemaFor(reflect.TypeOf(resp.Body))}}
+	} else if resp.ContentType != "" {
+		obj.Content = map[string]MediaType{resp.ContentType: {Schema: &Schema{Type: "string"}}}
+	}
+
+	if rateLimited {
+		integer := &Schema{Type: "integer"}
+		obj.Headers = map[string]*Header{
+			"RateLimit-Limit":     {Description: "Requests allowed in a burst", Schema: integer},
+			"RateLimit-Remaining": {Description: "Requests left before throttling", Schema: integer},
+			"RateLimit-Reset":     {Description: "Seconds until the limit fully resets", Schema: integer},
+		}
+		if resp.Status == http.StatusTooManyRequests {
+			obj.Headers["Retry-After"] = &Header{Description: "Seconds to wait before retrying", Schema: integer}
+		}
+	}
+	return obj
+}
+
+type schemaBuilder struct {
+	schemas map[string]*Schema
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaFor returns the schema of t, registering named structs as
+// components and referencing them.
+func (b *schemaBuilder) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		if _, ok := b.schemas[t.Name()]; !ok {
+			b.schemas[t.Name()] = &Schema{} // placeholder for recursive types
+			b.schemas[t.Name()] = b.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return b.structSchema(t)
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: b.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schemaFor(t.Elem())}
+	}
+	return &Schema{}
+}
+
+// structSchema maps exported fields by their JSON names and translates
+// `validate` rules into schema constraints. A field is required unless it is
+// omitempty or has validation rules without "required".
+func (b *schemaBuilder) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := jsonName(f)
+		if name == "-" {
+			continue
+		}
+
+		prop := b.schemaFor(f.Type)
+		rules := f.Tag.Get("validate")
+		applyRules(prop, rules)
+		s.Properties[name] = prop
+
+		_, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		required := !strings.Contains(opts, "omitempty")
+		if rules != "" {
+			required = containsRule(rules, "required")
+		}
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+func applyRules(s *Schema, rules string) {
+	if rules == "" || s.Ref != "" {
+		return
+	}
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		n, _ := strconv.Atoi(arg)
+
+		switch {
+		case name == "email":
+			s.Format = "email"
+		case name == "oneof":
+			s.Enum = strings.Fields(arg)
+		case name == "min" && s.Type == "string":
+			s.MinLength = &n
+		case name == "max" && s.Type == "string":
+			s.MaxLength = &n
+		case name == "maxbytes" && s.Type == "string":
+			// Characters are at least a byte each.
+			s.MaxLength = &n
+		case name == "min" && s.Type == "array":
+			s.MinItems = &n
+		case name == "max" && s.Type == "array":
+			s.MaxItems = &n
+		case name == "min" && s.Type == "integer":
+			s.Minimum = &n
+		case name == "max" && s.Type == "integer":
+			s.Maximum = &n
+		}
+	}
+}
+
+func containsRule(rules, rule string) bool {
+	for _, r := range strings.Split(rules, ",") {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func (rt *Router) serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	doc, err := BuildOpenAPI(rt.Routes())
+	if err != nil {
+		WriteError(w, r, InternalError(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(doc)
+}
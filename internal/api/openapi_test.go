@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestRouter registers the real route table. Handlers are never invoked,
+// so they may have nil dependencies.
+func newTestRouter() *Router {
+	router := NewRouter(testKeys, nil, nil, nil)
+	router.Handle(Handlers{
+		Auth:    &AuthHandler{},
+		Notes:   &NotesHandler{},
+		OAuth:   &OAuthHandler{},
+		Health:  NewHealthChecker(),
+		Metrics: http.NotFoundHandler(),
+	}.Routes()...)
+	return router
+}
+
+func TestOpenAPI_DocumentsEveryRoute(t *testing.T) {
+	router := newTestRouter()
+
+	doc, err := BuildOpenAPI(router.Routes())
+	if err != nil {
+		t.Fatalf("Every registered route needs a Doc: %v", err)
+	}
+
+	for _, route := range router.Routes() {
+		op := doc.Paths[route.Path()][strings.ToLower(route.Method())]
+		if op == nil {
+			t.Errorf("%s: missing from the OpenAPI document", route.Pattern)
+			continue
+		}
+		if op.OperationID == "" || op.Summary == "" || len(op.Responses) == 0 {
+			t.Errorf("%s: operationId, summary and responses are required", route.Pattern)
+		}
+		if route.Auth && len(op.Security) == 0 {
+			t.Errorf("%s: protected routes must declare bearer security", route.Pattern)
+		}
+	}
+}
+
+func TestOpenAPI_RejectsUndocumentedRoute(t *testing.T) {
+	router := NewRouter(testKeys, nil, nil, nil)
+	router.Handle(Route{Pattern: "GET /secret", Handler: http.NotFoundHandler()})
+
+	if _, err := BuildOpenAPI(router.Routes()); err == nil {
+		t.Error("Expected an error for a route without documentation")
+	}
+}
+
+func TestOpenAPI_Schemas(t *testing.T) {
+	doc, err := BuildOpenAPI(newTestRouter().Routes())
+	if err != nil {
+		t.Fatalf("BuildOpenAPI failed: %v", err)
+	}
+
+	register := doc.Components.Schemas["RegisterRequest"]
+	if register == nil {
+		t.Fatal("RegisterRequest should be a component schema")
+	}
+	email := register.Properties["email"]
+	if email.Format != "email" || email.MaxLength == nil || *email.MaxLength != 254 {
+		t.Errorf("Expected validate tags to become constraints, got %+v", email)
+	}
+	if len(register.Required) != 2 {
+		t.Errorf("Expected email and password to be required, got %v", register.Required)
+	}
+
+	if approval := doc.Components.Schemas["DeviceApprovalRequest"]; len(approval.Required) != 1 {
+		t.Errorf("omitempty fields should be optional, got %v", approval.Required)
+	}
+
+	note := doc.Components.Schemas["Note"]
+	if note == nil || note.Properties["created_at"].Format != "date-time" {
+		t.Error("Nested store types should be components with date-time timestamps")
+	}
+
+	token := doc.Paths["/oauth/token"]["post"]
+	if form, ok := token.RequestBody.Content[formContentType]; !ok {
+		t.Error("OAuth endpoints should document form bodies")
+	} else if len(form.Schema.Required) != 2 || form.Schema.Properties["grant_type"].Enum[0] != DeviceCodeGrantType {
+		t.Errorf("Expected grant_type and device_code to be required form fields, got %v", form.Schema.Required)
+	}
+	if _, ok := token.Responses["429"]; !ok {
+		t.Error("Rate-limited routes should document 429")
+	}
+
+	create := doc.Paths["/notes"]["post"]
+	var hasKey bool
+	for _, p := range create.Parameters {
+		hasKey = hasKey || (p.In == "header" && p.Name == IdempotencyKeyHeader)
+	}
+	if _, ok := create.Responses["409"]; !hasKey || !ok {
+		t.Error("Idempotent routes should document the Idempotency-Key header and 409")
+	}
+}
+
+func TestOpenAPI_ServesSpecAndDocs(t *testing.T) {
+	router := newTestRouter()
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+	var doc OpenAPIDocument
+	if err := json.NewDecoder(rr.Body).Decode(&doc); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if doc.OpenAPI != "3.1.0" || doc.Paths["/notes"]["get"] == nil || doc.Paths["/openapi.json"]["get"] == nil {
+		t.Errorf("Unexpected document: %+v", doc.Paths)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("Expected the docs page, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/metrics"
+	"github.com/ivan-almanza/notes-api/internal/ratelimit"
+)
+
+var rateLimitedTotal = metrics.NewCounterVec(
+	"notes_http_rate_limited_total",
+	"Requests rejected by the rate limiter, by route pattern.",
+	"route",
+)
+
+// RateLimiter throttles requests per route. Authenticated requests are keyed
+// by user, so it must run inside WithAuth; anonymous ones by client IP.
+type RateLimiter struct {
+	limiter      ratelimit.Limiter
+	defaultLimit ratelimit.Limit
+	routes       map[string]ratelimit.Limit
+	// trustedProxies is how many proxies append to X-Forwarded-For
+	trustedProxies int
+}
+
+// NewRateLimiter applies routes[pattern] to matching requests and
+// defaultLimit to every other wrapped route. A zero defaultLimit leaves
+// unlisted routes unlimited.
+func NewRateLimiter(limiter ratelimit.Limiter, defaultLimit ratelimit.Limit, routes map[string]ratelimit.Limit) *RateLimiter {
+	return &RateLimiter{limiter: limiter, defaultLimit: defaultLimit, routes: routes}
+}
+
+// TrustProxies makes the limiter key anonymous requests by X-Forwarded-For,
+// as appended to by the given number of proxies: the client is the address
+// that many entries from the end. Entries before it come from the client and
+// are ignored, since anyone can send them. Zero ignores the header.
+func (rl *RateLimiter) TrustProxies(proxies int) {
+	rl.trustedProxies = proxies
+}
+
+func (rl *RateLimiter) limitFor(pattern string) (ratelimit.Limit, bool) {
+	if limit, ok := rl.routes[pattern]; ok {
+		return limit, true
+	}
+	return rl.defaultLimit, rl.defaultLimit.Rate > 0 && rl.defaultLimit.Burst > 0
+}
+
+// Limit wraps a handler registered on a ServeMux, which sets r.Pattern
+// before the handler runs.
+func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, ok := rl.limitFor(r.Pattern)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := r.Pattern + "|" + rl.clientKey(r)
+		result, err := rl.limiter.Allow(r.Context(), key, limit)
+		if err != nil {
+			// Fail open: an unavailable limiter backend should not take
+			// the API down with it.
+			requestLogger(r).WarnContext(r.Context(), "rate limiter unavailable", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		h.Set("RateLimit-Reset", ceilSeconds(result.ResetAfter))
+
+		if !result.Allowed {
+			rateLimitedTotal.WithLabelValues(r.Pattern).Inc()
+			h.Set("Retry-After", ceilSeconds(result.RetryAfter))
+			WriteError(w, r, NewError(http.StatusTooManyRequests, CodeRateLimited, "Too many requests, retry later"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (rl *RateLimiter) clientKey(r *http.Request) string {
+	if userID, ok := r.Context().Value(ContextKeyUserID).(string); ok && userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + rl.clientIP(r)
+}
+
+func (rl *RateLimiter) clientIP(r *http.Request) string {
+	if rl.trustedProxies > 0 {
+		// Proxies may append a header of their own instead of extending
+		// the last one.
+		forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+		if i := len(forwarded) - rl.trustedProxies; i >= 0 {
+			if ip := strings.TrimSpace(forwarded[i]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ceilSeconds formats d as whole seconds, rounding up so clients never retry
+// too early.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/ratelimit"
+)
+
+func newLimitedMux(rl *RateLimiter) *http.ServeMux {
+	mux := http.NewServeMux()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	mux.Handle("POST /auth/login", rl.Limit(ok))
+	mux.Handle("GET /notes", rl.Limit(ok))
+	return mux
+}
+
+func TestRateLimiter_PerRouteLimitAndHeaders(t *testing.T) {
+	rl := NewRateLimiter(ratelimit.NewMemoryLimiter(), ratelimit.Limit{}, map[string]ratelimit.Limit{
+		"POST /auth/login": ratelimit.Per(2, time.Minute),
+	})
+	mux := newLimitedMux(rl)
+
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/auth/login", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i, rr.Code)
+		}
+		if rr.Header().Get("RateLimit-Limit") != "2" {
+			t.Errorf("Expected RateLimit-Limit 2, got %q", rr.Header().Get("RateLimit-Limit"))
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/auth/login", nil))
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "30" || rr.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("Unexpected headers: %v", rr.Header())
+	}
+	if problem := decodeProblem(t, rr); problem.Code != CodeRateLimited {
+		t.Errorf("Expected code %q, got %q", CodeRateLimited, problem.Code)
+	}
+
+	// Routes without a limit are unaffected when there is no default.
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/notes", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("Expected an unlimited route, got %d %v", rr.Code, rr.Header())
+	}
+}
+
+func TestRateLimiter_KeysByUserThenIP(t *testing.T) {
+	rl := NewRateLimiter(ratelimit.NewMemoryLimiter(), ratelimit.Per(1, time.Minute), nil)
+	mux := newLimitedMux(rl)
+
+	serve := func(userID, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/notes", nil)
+		req.RemoteAddr = remoteAddr
+		if userID != "" {
+			req = req.WithContext(context.WithValue(req.Context(), ContextKeyUserID, userID))
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if serve("user-a", "10.0.0.1:1234") != http.StatusOK {
+		t.Fatal("First request for user-a should pass")
+	}
+	if serve("user-b", "10.0.0.1:1234") != http.StatusOK {
+		t.Error("Users sharing an IP should have separate buckets")
+	}
+	if serve("user-a", "10.0.0.2:1234") != http.StatusTooManyRequests {
+		t.Error("A user should be limited across IPs")
+	}
+	if serve("", "10.0.0.3:1234") != http.StatusOK {
+		t.Fatal("First anonymous request should pass")
+	}
+	if serve("", "10.0.0.3:5678") != http.StatusTooManyRequests {
+		t.Error("Anonymous requests should be limited by IP, ignoring the port")
+	}
+}
+
+func TestRateLimiter_TrustProxies(t *testing.T) {
+	tests := []struct {
+		name      string
+		proxies   int
+		forwarded []string
+		want      string
+	}{
+		{"one proxy", 1, []string{"203.0.113.7"}, "ip:203.0.113.7"},
+		{"spoofed leading entry", 1, []string{"198.51.100.1, 203.0.113.7"}, "ip:203.0.113.7"},
+		{"two proxies", 2, []string{"198.51.100.1, 203.0.113.7, 10.0.0.1"}, "ip:203.0.113.7"},
+		{"one header per proxy", 2, []string{"198.51.100.1, 203.0.113.7", "10.0.0.1"}, "ip:203.0.113.7"},
+		{"fewer entries than proxies", 2, []string{"203.0.113.7"}, "ip:192.0.2.1"},
+		{"no header", 1, nil, "ip:192.0.2.1"},
+		{"proxies not trusted", 0, []string{"203.0.113.7"}, "ip:192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl := NewRateLimiter(ratelimit.NewMemoryLimiter(), ratelimit.Per(1, time.Minute), nil)
+			rl.TrustProxies(tt.proxies)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, forwarded := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", forwarded)
+			}
+			if key := rl.clientKey(req); key != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, key)
+			}
+		})
+	}
+}
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func TestRateLimiter_FailsOpen(t *testing.T) {
+	mux := newLimitedMux(NewRateLimiter(failingLimiter{}, ratelimit.Per(1, time.Minute), nil))
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/notes", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected the request through when the limiter fails, got %d", rr.Code)
+	}
+}
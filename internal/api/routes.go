@@ -0,0 +1,136 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ivan-almanza/notes-api/internal/auth"
+	"github.com/ivan-almanza/notes-api/internal/store"
+)
+
+// Route is one entry of the route table. The table drives both the mux and
+// the OpenAPI document, so every route must carry a Doc.
+type Route struct {
+	// Pattern is a ServeMux pattern with a method, e.g. "GET /notes".
+	Pattern string
+	Handler http.Handler
+	// Auth wraps the handler with WithAuth and documents bearer security.
+	Auth bool
+	// RateLimited applies the router's RateLimiter inside WithAuth.
+	RateLimited bool
+	// Idempotent honors an Idempotency-Key header with the router's
+	// Idempotency. Keys are scoped by user, so it needs Auth.
+	Idempotent bool
+	Doc        *RouteDoc
+}
+
+// Method and Path split the pattern.
+func (rt Route) Method() string {
+	method, _, _ := strings.Cut(rt.Pattern, " ")
+	return method
+}
+
+func (rt Route) Path() string {
+	_, path, _ := strings.Cut(rt.Pattern, " ")
+	return path
+}
+
+// Handlers groups everything served by the API.
+type Handlers struct {
+	Auth    *AuthHandler
+	Notes   *NotesHandler
+	OAuth   *OAuthHandler
+	Health  *HealthChecker
+	Metrics http.Handler
+}
+
+// Routes returns the route table of the API, excluding the documentation
+// routes added by NewRouter.
+func (h Handlers) Routes() []Route {
+	return []Route{
+		// Observability
+		{Pattern: "GET /metrics", Handler: h.Metrics, Doc: metricsDoc},
+		{Pattern: "GET /healthz", Handler: http.HandlerFunc(h.Health.Healthz), Doc: healthzDoc},
+		{Pattern: "GET /readyz", Handler: http.HandlerFunc(h.Health.Readyz), Doc: readyzDoc},
+		{Pattern: "GET /health/details", Handler: http.HandlerFunc(h.Health.Details), Doc: healthDetailsDoc},
+
+		// Auth
+		{Pattern: "POST /auth/register", Handler: http.HandlerFunc(h.Auth.Register), RateLimited: true, Doc: registerDoc},
+		{Pattern: "POST /auth/login", Handler: http.HandlerFunc(h.Auth.Login), RateLimited: true, Doc: loginDoc},
+
+		// OAuth2 Device Authorization Grant
+		{Pattern: "POST /oauth/device/code", Handler: http.HandlerFunc(h.OAuth.DeviceCode), RateLimited: true, Doc: deviceCodeDoc},
+		{Pattern: "POST /oauth/token", Handler: http.HandlerFunc(h.OAuth.Token), RateLimited: true, Doc: tokenDoc},
+		{Pattern: "POST /oauth/device/approve", Handler: http.HandlerFunc(h.OAuth.ApproveDevice), Auth: true, RateLimited: true, Idempotent: true, Doc: approveDeviceDoc},
+
+		// Notes
+		{Pattern: "POST /notes", Handler: http.HandlerFunc(h.Notes.CreateNote), Auth: true, RateLimited: true, Idempotent: true, Doc: createNoteDoc},
+		{Pattern: "GET /notes", Handler: http.HandlerFunc(h.Notes.GetNotes), Auth: true, RateLimited: true, Doc: listNotesDoc},
+		{Pattern: "GET /notes/search", Handler: http.HandlerFunc(h.Notes.SearchNotes), Auth: true, RateLimited: true, Doc: searchNotesDoc},
+		{Pattern: "GET /notes/{id}", Handler: http.HandlerFunc(h.Notes.GetNote), Auth: true, RateLimited: true, Doc: getNoteDoc},
+		{Pattern: "PUT /notes/{id}", Handler: http.HandlerFunc(h.Notes.UpdateNote), Auth: true, RateLimited: true, Idempotent: true, Doc: updateNoteDoc},
+		{Pattern: "DELETE /notes/{id}", Handler: http.HandlerFunc(h.Notes.DeleteNote), Auth: true, RateLimited: true, Idempotent: true, Doc: deleteNoteDoc},
+	}
+}
+
+// Router is a ServeMux that remembers its route table. It serves the
+// OpenAPI document at /openapi.json and the docs page at /docs.
+type Router struct {
+	*http.ServeMux
+	keys        *auth.Keys
+	limiter     *RateLimiter
+	idempotency *Idempotency
+	users       store.UserStatusStorer
+	routes      []Route
+}
+
+// NewRouter returns a Router that verifies tokens on authenticated routes
+// with keys, applies limiter to rate-limited routes, idempotency to
+// idempotent routes and checks users on authenticated routes with
+// WithActiveUser. A nil limiter disables rate limiting; a nil idempotency
+// ignores Idempotency-Key; nil users skips the account check.
+func NewRouter(keys *auth.Keys, limiter *RateLimiter, idempotency *Idempotency, users store.UserStatusStorer) *Router {
+	rt := &Router{ServeMux: http.NewServeMux(), keys: keys, limiter: limiter, idempotency: idempotency, users: users}
+	rt.Handle(
+		Route{Pattern: "GET /openapi.json", Handler: http.HandlerFunc(rt.serveOpenAPI), Doc: openAPIDoc},
+		Route{Pattern: "GET /docs", Handler: http.HandlerFunc(serveDocs), Doc: docsDoc},
+	)
+	return rt
+}
+
+// Handle registers routes, wrapping them with idempotency, rate limiting,
+// WithActiveUser and WithAuth as requested. Replays count against the rate
+// limit.
+func (rt *Router) Handle(routes ...Route) {
+	for _, route := range routes {
+		handler := route.Handler
+		if route.Idempotent && rt.idempotency != nil {
+			handler = rt.idempotency.Idempotent(handler)
+		}
+		if route.RateLimited && rt.limiter != nil {
+			handler = rt.limiter.Limit(handler)
+		}
+		if route.Auth {
+			if rt.users != nil {
+				handler = WithActiveUser(rt.users, handler)
+			}
+			handler = WithAuth(rt.keys, handler)
+		}
+		rt.ServeMux.Handle(route.Pattern, recordRoute(handler))
+		rt.routes = append(rt.routes, route)
+	}
+}
+
+// Routes returns every registered route in registration order.
+func (rt *Router) Routes() []Route {
+	return append([]Route(nil), rt.routes...)
+}
+
+// recordRoute keeps the pattern the mux matched on the request state before
+// middleware in between replaces the request.
+func recordRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		matchedRoute(r)
+		next.ServeHTTP(w, r)
+	})
+}
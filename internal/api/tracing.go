@@ -0,0 +1,40 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ivan-almanza/notes-api/internal/tracing"
+)
+
+// WithTracing continues the caller's W3C trace (or starts a new one) and
+// wraps the request in a server span named after the matched route.
+func WithTracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracing.Extract(r.Context(), r.Header)
+		ctx, span := tracing.Start(ctx, r.Method,
+			tracing.WithSpanKind(tracing.SpanKindServer),
+			tracing.WithAttributes(
+				tracing.String("http.request.method", r.Method),
+				tracing.String("url.path", r.URL.Path),
+				tracing.String("user_agent.original", r.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		// The state carries the matched route back out of the mux.
+		_, ctx = withRequestState(ctx)
+		r = r.WithContext(ctx)
+		rec := newResponseRecorder(w)
+		next.ServeHTTP(rec, r)
+
+		if route := matchedRoute(r); route != "" {
+			span.SetName(route)
+			span.SetAttributes(tracing.String("http.route", route))
+		}
+		span.SetAttributes(tracing.Int("http.response.status_code", rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetError(fmt.Errorf("HTTP %d", rec.status))
+		}
+	})
+}
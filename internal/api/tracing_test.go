@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ivan-almanza/notes-api/internal/tracing"
+)
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (e *spanRecorder) ExportSpans(ctx context.Context, resource tracing.Resource, spans []tracing.SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *spanRecorder) Shutdown(ctx context.Context) error { return nil }
+
+func TestWithTracing_ServerSpan(t *testing.T) {
+	exporter := &spanRecorder{}
+	tracer := tracing.NewTracer(tracing.Options{SampleRatio: 1, Exporter: exporter})
+	tracing.SetTracer(tracer)
+	defer tracing.SetTracer(nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /notes", func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.Start(r.Context(), "child")
+		span.End()
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/notes", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	WithTracing(mux).ServeHTTP(httptest.NewRecorder(), req)
+	tracer.Shutdown(context.Background())
+
+	if len(exporter.spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(exporter.spans))
+	}
+	child, server := exporter.spans[0], exporter.spans[1]
+
+	if server.Name != "GET /notes" || server.Kind != tracing.SpanKindServer {
+		t.Errorf("Unexpected server span: %+v", server)
+	}
+	if server.SpanContext.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || server.ParentSpanID.String() != "00f067aa0ba902b7" {
+		t.Error("Server span should continue the incoming trace")
+	}
+	if server.StatusCode != tracing.StatusError {
+		t.Error("5xx responses should mark the span as failed")
+	}
+	if child.ParentSpanID != server.SpanContext.SpanID {
+		t.Error("Handler spans should be children of the server span")
+	}
+}
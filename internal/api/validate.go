@@ -0,0 +1,219 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ivan-almanza/notes-api/pkg/apitypes"
+)
+
+// MaxBodyBytes caps the size of JSON request bodies.
+const MaxBodyBytes = 1 << 20
+
+const (
+	CodePayloadTooLarge = apitypes.CodePayloadTooLarge
+
+	FieldRequired     = apitypes.FieldRequired
+	FieldTooShort     = apitypes.FieldTooShort
+	FieldTooLong      = apitypes.FieldTooLong
+	FieldInvalidEmail = apitypes.FieldInvalidEmail
+	FieldInvalidType  = apitypes.FieldInvalidType
+	FieldUnknown      = apitypes.FieldUnknown
+	FieldNotOneOf     = apitypes.FieldNotOneOf
+)
+
+var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
+// DecodeJSON reads a single JSON object from the request body into dst and
+// validates it against the `validate` struct tags. Unknown fields, oversized
+// bodies and trailing data are rejected. Every violation is reported at once.
+//
+// Supported rules, comma separated: required, min=N, max=N, maxbytes=N, email, oneof=a b c.
+// For strings min and max count characters; for slices, elements; for numbers, the value.
+// maxbytes limits the UTF-8 length of strings, for values such as bcrypt input.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxBodyBytes)
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return NewError(http.StatusBadRequest, CodeInvalidPayload, "Request body must contain a single JSON object")
+	}
+
+	if fields := Validate(dst); len(fields) > 0 {
+		return ValidationError(fields...)
+	}
+	return nil
+}
+
+func decodeError(err error) *Error {
+	var maxBytesErr *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return NewError(http.StatusRequestEntityTooLarge, CodePayloadTooLarge, fmt.Sprintf("Request body must not exceed %d bytes", maxBytesErr.Limit))
+	case errors.As(err, &typeErr):
+		return ValidationError(FieldError{
+			Pointer: jsonPointer(strings.Split(typeErr.Field, ".")...),
+			Code:    FieldInvalidType,
+			Detail:  "must be of type " + typeErr.Type.String(),
+		})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		name, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		return ValidationError(FieldError{Pointer: jsonPointer(name), Code: FieldUnknown, Detail: "unknown field"})
+	}
+	return NewError(http.StatusBadRequest, CodeInvalidPayload, "Invalid request payload")
+}
+
+// Validate checks v (a struct or pointer to struct) against its `validate`
+// tags and returns one FieldError per violation.
+func Validate(v any) []FieldError {
+	var fields []FieldError
+	validateValue(reflect.ValueOf(v), nil, &fields)
+	return fields
+}
+
+func validateValue(v reflect.Value, path []string, fields *[]FieldError) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name := jsonName(f)
+			if name == "-" {
+				continue
+			}
+			fieldPath := append(path[:len(path):len(path)], name)
+			checkRules(v.Field(i), f.Tag.Get("validate"), fieldPath, fields)
+			validateValue(v.Field(i), fieldPath, fields)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), append(path[:len(path):len(path)], strconv.Itoa(i)), fields)
+		}
+	}
+}
+
+func checkRules(v reflect.Value, tag string, path []string, fields *[]FieldError) {
+	if tag == "" {
+		return
+	}
+
+	add := func(code, detail string) {
+		*fields = append(*fields, FieldError{Pointer: jsonPointer(path...), Code: code, Detail: detail})
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+
+		switch name {
+		case "required":
+			if v.IsZero() {
+				add(FieldRequired, "is required")
+				return
+			}
+		case "min", "max":
+			limit, err := strconv.Atoi(arg)
+			if err != nil {
+				panic(fmt.Sprintf("api: invalid %s rule %q", name, rule))
+			}
+			n, ok := measure(v)
+			if !ok {
+				continue
+			}
+			if name == "min" && n < limit {
+				add(FieldTooShort, fmt.Sprintf("must be at least %d", limit))
+			}
+			if name == "max" && n > limit {
+				add(FieldTooLong, fmt.Sprintf("must be at most %d", limit))
+			}
+		case "maxbytes":
+			limit, err := strconv.Atoi(arg)
+			if err != nil {
+				panic(fmt.Sprintf("api: invalid %s rule %q", name, rule))
+			}
+			if v.Kind() == reflect.String && len(v.String()) > limit {
+				add(FieldTooLong, fmt.Sprintf("must be at most %d bytes", limit))
+			}
+		case "email":
+			if v.Kind() == reflect.String && v.String() != "" && !emailRegex.MatchString(v.String()) {
+				add(FieldInvalidEmail, "must be a valid email address")
+			}
+		case "oneof":
+			if v.Kind() == reflect.String && v.String() != "" && !containsWord(arg, v.String()) {
+				add(FieldNotOneOf, "must be one of: "+strings.ReplaceAll(arg, " ", ", "))
+			}
+		default:
+			panic(fmt.Sprintf("api: unknown validation rule %q", rule))
+		}
+	}
+}
+
+// measure returns the length of strings and collections, or the value of numbers.
+func measure(v reflect.Value) (int, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(v.String()), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(v.Uint()), true
+	}
+	return 0, false
+}
+
+func containsWord(list, word string) bool {
+	for _, w := range strings.Fields(list) {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// jsonPointer builds an RFC 6901 pointer from path segments.
+func jsonPointer(segments ...string) string {
+	var sb strings.Builder
+	for _, s := range segments {
+		if s == "" {
+			continue
+		}
+		sb.WriteByte('/')
+		s = strings.ReplaceAll(s, "~", "~0")
+		sb.WriteString(strings.ReplaceAll(s, "/", "~1"))
+	}
+	return sb.String()
+}
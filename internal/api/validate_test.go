@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ivan-almanza/notes-api/internal/store"
+)
+
+func TestValidate_ReportsAllViolations(t *testing.T) {
+	fields := Validate(&RegisterRequest{Email: "not-an-email", Password: "123"})
+
+	if len(fields) != 2 {
+		t.Fatalf("Expected 2 violations, got %+v", fields)
+	}
+	if fields[0].Pointer != "/email" || fields[0].Code != FieldInvalidEmail {
+		t.Errorf("Unexpected email violation: %+v", fields[0])
+	}
+	if fields[1].Pointer != "/password" || fields[1].Code != FieldTooShort {
+		t.Errorf("Unexpected password violation: %+v", fields[1])
+	}
+}
+
+func TestValidate_MaxBytes(t *testing.T) {
+	type request struct {
+		Secret string `json:"secret" validate:"maxbytes=4"`
+	}
+
+	if fields := Validate(&request{Secret: "éé"}); len(fields) != 0 {
+		t.Errorf("Expected 4 bytes to pass, got %+v", fields)
+	}
+	fields := Validate(&request{Secret: "ééé"})
+	if len(fields) != 1 || fields[0].Code != FieldTooLong {
+		t.Errorf("Expected 3 characters of 6 bytes to be too long, got %+v", fields)
+	}
+}
+
+func TestValidate_NestedPointers(t *testing.T) {
+	type item struct {
+		Name string `json:"name" validate:"required"`
+	}
+	type request struct {
+		Items []item `json:"items" validate:"max=2"`
+	}
+
+	fields := Validate(&request{Items: []item{{Name: "a"}, {}, {Name: "c"}}})
+
+	if len(fields) != 2 {
+		t.Fatalf("Expected 2 violations, got %+v", fields)
+	}
+	if fields[0].Pointer != "/items" || fields[0].Code != FieldTooLong {
+		t.Errorf("Unexpected violation: %+v", fields[0])
+	}
+	if fields[1].Pointer != "/items/1/name" || fields[1].Code != FieldRequired {
+		t.Errorf("Unexpected violation: %+v", fields[1])
+	}
+}
+
+func TestDecodeJSON_UnknownField(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/notes", strings.NewReader(`{"content":"hi","pinned":true}`))
+	w := httptest.NewRecorder()
+
+	var dst CreateNoteRequest
+	err := DecodeJSON(w, req, &dst)
+
+	apiErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("Expected *Error, got %v", err)
+	}
+	if len(apiErr.Fields) != 1 || apiErr.Fields[0].Pointer != "/pinned" || apiErr.Fields[0].Code != FieldUnknown {
+		t.Errorf("Expected unknown field /pinned, got %+v", apiErr.Fields)
+	}
+}
+
+func TestDecodeJSON_TooLarge(t *testing.T) {
+	body := `{"content":"` + strings.Repeat("a", MaxBodyBytes) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/notes", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	var dst CreateNoteRequest
+	err := DecodeJSON(w, req, &dst)
+
+	apiErr, ok := err.(*Error)
+	if !ok || apiErr.Status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 error, got %v", err)
+	}
+}
+
+func TestDecodeJSON_WrongType(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/notes", strings.NewReader(`{"content":42}`))
+	w := httptest.NewRecorder()
+
+	var dst CreateNoteRequest
+	err := DecodeJSON(w, req, &dst)
+
+	apiErr, ok := err.(*Error)
+	if !ok || len(apiErr.Fields) != 1 || apiErr.Fields[0].Pointer != "/content" || apiErr.Fields[0].Code != FieldInvalidType {
+		t.Errorf("Expected invalid_type on /content, got %v", err)
+	}
+}
+
+func TestCreateNote_EmptyContent(t *testing.T) {
+	mockStore := &MockNoteStore{
+		CreateNoteFunc: func(ctx context.Context, note *store.Note) error {
+			t.Error("CreateNote should not be called")
+			return nil
+		},
+	}
+	handler := NewNotesHandler(mockStore)
+
+	req := httptest.NewRequest(http.MethodPost, "/notes", strings.NewReader(`{"content":""}`))
+	req = req.WithContext(context.WithValue(req.Context(), ContextKeyUserID, "user-123"))
+	w := httptest.NewRecorder()
+
+	handler.CreateNote(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	if problem := decodeProblem(t, w); len(problem.Errors) != 1 || problem.Errors[0].Pointer != "/content" {
+		t.Errorf("Expected a field error for /content, got %+v", problem.Errors)
+	}
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"strings"
+)
+
+// userCodeAlphabet omits vowels and look-alike characters so codes are easy
+// to type and never spell words (RFC 8628, section 6.1).
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+// userCodeByteLimit is the largest multiple of len(userCodeAlphabet) a byte
+// can hold. Random bytes at or above it are discarded, so every character
+// is equally likely.
+const userCodeByteLimit = 256 - 256%len(userCodeAlphabet)
+
+// GenerateDeviceCode returns an unguessable code for the polling client.
+func GenerateDeviceCode() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// GenerateUserCode returns a short code in the form "XXXX-XXXX" for the user to enter.
+func GenerateUserCode() (string, error) {
+	return userCode(rand.Reader)
+}
+
+func userCode(random io.Reader) (string, error) {
+	var sb strings.Builder
+	b := make([]byte, 16)
+	for n := 0; n < 8; {
+		if _, err := io.ReadFull(random, b); err != nil {
+			return "", err
+		}
+		for _, c := range b {
+			if int(c) >= userCodeByteLimit || n == 8 {
+				continue
+			}
+			if n == 4 {
+				sb.WriteByte('-')
+			}
+			sb.WriteByte(userCodeAlphabet[int(c)%len(userCodeAlphabet)])
+			n++
+		}
+	}
+	return sb.String(), nil
+}
+
+// NormalizeUserCode uppercases a user-entered code and restores the dash.
+func NormalizeUserCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(userCodeAlphabet, r) {
+			return r
+		}
+		return -1
+	}, code)
+
+	if len(code) == 8 {
+		return code[:4] + "-" + code[4:]
+	}
+	return code
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+func TestGenerateDeviceCode_Unique(t *testing.T) {
+	a, err := GenerateDeviceCode()
+	if err != nil {
+		t.Fatalf("GenerateDeviceCode failed: %v", err)
+	}
+	b, _ := GenerateDeviceCode()
+
+	if a == "" || a == b {
+		t.Errorf("Expected distinct non-empty codes, got %q and %q", a, b)
+	}
+}
+
+func TestGenerateUserCode_Format(t *testing.T) {
+	code, err := GenerateUserCode()
+	if err != nil {
+		t.Fatalf("GenerateUserCode failed: %v", err)
+	}
+
+	if !regexp.MustCompile(`^[BCDFGHJKLMNPQRSTVWXZ]{4}-[BCDFGHJKLMNPQRSTVWXZ]{4}$`).MatchString(code) {
+		t.Errorf("Unexpected user code format: %q", code)
+	}
+}
+
+func TestUserCode_DiscardsBiasedBytes(t *testing.T) {
+	// 240 and above would favour the first 16 characters; 20 and 39 map to
+	// the first and last ones.
+	random := bytes.NewReader([]byte{240, 255, 0, 20, 39, 1, 250, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+
+	code, err := userCode(random)
+	if err != nil {
+		t.Fatalf("userCode failed: %v", err)
+	}
+	if code != "BBZC-DFGH" {
+		t.Errorf("Expected BBZC-DFGH, got %q", code)
+	}
+}
+
+func TestUserCode_RejectionBound(t *testing.T) {
+	if userCodeByteLimit != 240 {
+		t.Fatalf("Expected the cutoff at 240, got %d", userCodeByteLimit)
+	}
+
+	// A whole chunk at or above the cutoff is skipped and another read; 239,
+	// the last byte below it, is kept.
+	chunk := bytes.Repeat([]byte{byte(userCodeByteLimit)}, 8)
+	chunk = append(chunk, bytes.Repeat([]byte{255}, 8)...)
+	random := bytes.NewReader(append(chunk, bytes.Repeat([]byte{byte(userCodeByteLimit - 1)}, 16)...))
+
+	code, err := userCode(random)
+	if err != nil {
+		t.Fatalf("userCode failed: %v", err)
+	}
+	if code != "ZZZZ-ZZZZ" {
+		t.Errorf("Expected ZZZZ-ZZZZ, got %q", code)
+	}
+	if random.Len() != 0 {
+		t.Errorf("Expected both chunks to be read, %d bytes left", random.Len())
+	}
+}
+
+func TestNormalizeUserCode(t *testing.T) {
+	if got := NormalizeUserCode("bcdf ghjk"); got != "BCDF-GHJK" {
+		t.Errorf("Expected BCDF-GHJK, got %q", got)
+	}
+}
@@ -2,43 +2,97 @@ package auth
 
 import (
 	"errors"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var Secret = []byte("default-secret")
+// TokenTTL is how long a generated token stays valid
+const TokenTTL = 24 * time.Hour
 
-// SetSecret updates the global JWT secret
-func SetSecret(secret string) {
-	Secret = []byte(secret)
+// Keys holds the secret that signs and verifies tokens. Set replaces it
+// while requests are in flight, so a rotated secret takes effect without a
+// restart.
+//
+// After a rotation, tokens signed with the previous secret still verify for
+// TokenTTL, so users are not all logged out at once. When the old secret
+// leaked, call DropPrevious and run `api tokens revoke-all`.
+type Keys struct {
+	secrets atomic.Pointer[secrets]
+}
+
+type secrets struct {
+	current, previous []byte
+	// previousUntil is when tokens signed with previous stop verifying
+	previousUntil time.Time
+}
+
+// now is replaced in tests.
+var now = time.Now
+
+func NewKeys(secret string) *Keys {
+	k := &Keys{}
+	k.secrets.Store(&secrets{current: []byte(secret)})
+	return k
+}
+
+// Set makes secret the signing secret. Setting the current secret again
+// does nothing.
+func (k *Keys) Set(secret string) {
+	old := k.secrets.Load()
+	if string(old.current) == secret {
+		return
+	}
+	k.secrets.Store(&secrets{current: []byte(secret), previous: old.current, previousUntil: now().Add(TokenTTL)})
+}
+
+// DropPrevious stops accepting tokens signed with the previous secret
+// before its grace period ends.
+func (k *Keys) DropPrevious() {
+	k.secrets.Store(&secrets{current: k.secrets.Load().current})
 }
 
 // Claims embeds standard claims
 type Claims struct {
 	jwt.RegisteredClaims
+	// Generation is the user's token generation when the token was issued;
+	// revoking the user's tokens starts a new one.
+	Generation int64 `json:"gen,omitempty"`
 }
 
-// GenerateToken creates a signed JWT for a user
-func GenerateToken(userID string) (string, error) {
+// GenerateToken creates a signed JWT for a user in their current token
+// generation
+func (k *Keys) GenerateToken(userID string, generation int64) (string, error) {
 	claims := Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject:   userID,
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
+		Generation: generation,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(Secret)
+	return token.SignedString(k.secrets.Load().current)
+}
+
+// ValidateToken parses and validates the token string against the current
+// secret, then the previous one while its grace period lasts
+func (k *Keys) ValidateToken(tokenString string) (*jwt.Token, error) {
+	s := k.secrets.Load()
+	token, err := parse(tokenString, s.current)
+	if err != nil && s.previous != nil && now().Before(s.previousUntil) && errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+		return parse(tokenString, s.previous)
+	}
+	return token, err
 }
 
-// ValidateToken parses and validates the token string
-func ValidateToken(tokenString string) (*jwt.Token, error) {
+func parse(tokenString string, secret []byte) (*jwt.Token, error) {
 	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
-		return Secret, nil
+		return secret, nil
 	})
 }
@@ -9,13 +9,11 @@ import (
 
 var testSecret = []byte("test-secret")
 
-func init() {
-	Secret = testSecret
-}
+var testKeys = NewKeys(string(testSecret))
 
 func TestGenerateToken_ContainsClaims(t *testing.T) {
 	userID := "user-123"
-	tokenString, err := GenerateToken(userID)
+	tokenString, err := testKeys.GenerateToken(userID, 3)
 	if err != nil {
 		t.Fatalf("GenerateToken failed: %v", err)
 	}
@@ -32,6 +30,9 @@ func TestGenerateToken_ContainsClaims(t *testing.T) {
 	if sub, ok := claims["sub"].(string); !ok || sub != userID {
 		t.Errorf("Expected sub %v, got %v", userID, sub)
 	}
+	if gen, ok := claims["gen"].(float64); !ok || gen != 3 {
+		t.Errorf("Expected gen 3, got %v", claims["gen"])
+	}
 
 	exp, ok := claims["exp"].(float64)
 	if !ok {
@@ -45,12 +46,12 @@ func TestGenerateToken_ContainsClaims(t *testing.T) {
 
 func TestValidateToken_Valid(t *testing.T) {
 	userID := "user-123"
-	tokenString, err := GenerateToken(userID)
+	tokenString, err := testKeys.GenerateToken(userID, 0)
 	if err != nil {
 		t.Fatalf("Setup failed: %v", err)
 	}
 
-	token, err := ValidateToken(tokenString)
+	token, err := testKeys.ValidateToken(tokenString)
 	if err != nil {
 		t.Errorf("ValidateToken failed for valid token: %v", err)
 	}
@@ -69,7 +70,7 @@ func TestValidateToken_Expired(t *testing.T) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, _ := token.SignedString(testSecret)
 
-	_, err := ValidateToken(tokenString)
+	_, err := testKeys.ValidateToken(tokenString)
 	if err == nil {
 		t.Error("Expected error for expired token, got nil")
 	}
@@ -84,8 +85,71 @@ func TestValidateToken_TamperedSignature(t *testing.T) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, _ := token.SignedString([]byte("wrong-secret"))
 
-	_, err := ValidateToken(tokenString)
+	_, err := testKeys.ValidateToken(tokenString)
 	if err == nil {
 		t.Error("Expected error for tampered token, got nil")
 	}
 }
+
+func TestKeys_SetRotatesSecret(t *testing.T) {
+	keys := NewKeys("old-secret")
+	oldToken, _ := keys.GenerateToken("user-123", 0)
+
+	keys.Set("new-secret")
+	newToken, _ := keys.GenerateToken("user-123", 0)
+
+	if _, err := jwt.Parse(newToken, func(*jwt.Token) (interface{}, error) { return []byte("new-secret"), nil }); err != nil {
+		t.Errorf("Expected new tokens to be signed with the new secret: %v", err)
+	}
+	if _, err := keys.ValidateToken(oldToken); err != nil {
+		t.Errorf("Expected tokens signed with the previous secret to verify: %v", err)
+	}
+
+	keys.Set("newer-secret")
+	if _, err := keys.ValidateToken(oldToken); err == nil {
+		t.Error("Expected tokens signed two secrets ago to be rejected")
+	}
+	if _, err := keys.ValidateToken(newToken); err != nil {
+		t.Errorf("Expected tokens signed with the previous secret to verify: %v", err)
+	}
+}
+
+func TestKeys_SetSameSecretKeepsPrevious(t *testing.T) {
+	keys := NewKeys("old-secret")
+	oldToken, _ := keys.GenerateToken("user-123", 0)
+
+	keys.Set("new-secret")
+	keys.Set("new-secret")
+	if _, err := keys.ValidateToken(oldToken); err != nil {
+		t.Errorf("Expected reloading an unchanged secret to keep the previous one: %v", err)
+	}
+}
+
+func TestKeys_PreviousSecretExpiresAfterGracePeriod(t *testing.T) {
+	keys := NewKeys("old-secret")
+	oldToken, _ := keys.GenerateToken("user-123", 0)
+	keys.Set("new-secret")
+
+	// The token itself is still valid; only the rotation is old.
+	defer func(orig func() time.Time) { now = orig }(now)
+	now = func() time.Time { return time.Now().Add(TokenTTL + time.Minute) }
+
+	if _, err := keys.ValidateToken(oldToken); err == nil {
+		t.Error("Expected tokens signed with the previous secret to be rejected after the grace period")
+	}
+}
+
+func TestKeys_DropPrevious(t *testing.T) {
+	keys := NewKeys("old-secret")
+	oldToken, _ := keys.GenerateToken("user-123", 0)
+	keys.Set("new-secret")
+	newToken, _ := keys.GenerateToken("user-123", 0)
+
+	keys.DropPrevious()
+	if _, err := keys.ValidateToken(oldToken); err == nil {
+		t.Error("Expected tokens signed with the dropped secret to be rejected")
+	}
+	if _, err := keys.ValidateToken(newToken); err != nil {
+		t.Errorf("Expected tokens signed with the current secret to verify: %v", err)
+	}
+}
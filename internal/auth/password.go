@@ -1,14 +1,53 @@
 package auth
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"context"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/metrics"
+	"github.com/ivan-almanza/notes-api/internal/tracing"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var bcryptDuration = metrics.NewHistogramVec(
+	"notes_auth_bcrypt_duration_seconds",
+	"Time spent in bcrypt, by operation (hash or compare).",
+	[]float64{.01, .025, .05, .1, .25, .5, 1, 2.5},
+	"op",
+)
+
+// DummyHash is a bcrypt hash at the default cost that matches no password a
+// client will send. Comparing against it when a user does not exist makes
+// that path cost as much as a real password check, so login latency does not
+// reveal which emails are registered.
+const DummyHash = "$2a$10$rotZLwVfuNl9pBVRwizwGuDr2vNyunmGOvM.RMW/n8wFZAMjihJlG"
 
 // Hash generates a bcrypt hash of the password.
 func Hash(password string) (string, error) {
+	return HashContext(context.Background(), password)
+}
+
+// HashContext is Hash with a span recorded as a child of ctx.
+func HashContext(ctx context.Context, password string) (string, error) {
+	_, span := tracing.Start(ctx, "auth.Hash", tracing.WithAttributes(tracing.Int("bcrypt.cost", bcrypt.DefaultCost)))
+	defer span.End()
+	defer bcryptDuration.WithLabelValues("hash").ObserveDuration(time.Now())
+
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	span.SetError(err)
 	return string(bytes), err
 }
 
 // Compare compares a bcrypt hashed password with its possible plaintext equivalent.
 func Compare(password, hash string) error {
+	return CompareContext(context.Background(), password, hash)
+}
+
+// CompareContext is Compare with a span recorded as a child of ctx.
+func CompareContext(ctx context.Context, password, hash string) error {
+	_, span := tracing.Start(ctx, "auth.Compare")
+	defer span.End()
+	defer bcryptDuration.WithLabelValues("compare").ObserveDuration(time.Now())
+
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
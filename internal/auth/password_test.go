@@ -48,3 +48,16 @@ func TestComparePassword_Failure(t *testing.T) {
 		t.Errorf("Compare() expected ErrMismatchedHashAndPassword, got %v", err)
 	}
 }
+
+func TestDummyHash_UsesDefaultCost(t *testing.T) {
+	cost, err := bcrypt.Cost([]byte(DummyHash))
+	if err != nil {
+		t.Fatalf("Expected a valid bcrypt hash, got %v", err)
+	}
+	if cost != bcrypt.DefaultCost {
+		t.Errorf("Expected cost %d, got %d", bcrypt.DefaultCost, cost)
+	}
+	if err := Compare("", DummyHash); err == nil {
+		t.Error("Expected the empty password not to match")
+	}
+}
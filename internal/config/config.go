@@ -1,35 +1,494 @@
+// Package config loads the API configuration. Every setting has a default,
+// which a YAML, JSON or TOML file, an environment variable and a
+// command-line flag override in that order. The loaded Config remembers
+// where each value came from for `api config print`.
 package config
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
 	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/ratelimit"
+	"github.com/ivan-almanza/notes-api/internal/store"
 )
 
+// defaultRouteLimits protects the unauthenticated, brute-forceable endpoints
+// when RATE_LIMITS is not set.
+const defaultRouteLimits = "POST /auth/login=10/1m;POST /auth/register=5/1m;POST /oauth/device/code=10/1m"
+
+// MemoryDBURL selects the in-memory store instead of Postgres. Data is lost
+// on exit, so it is meant for demos and local development.
+const MemoryDBURL = "memory://"
+
+// SQLiteDBURLPrefix selects the SQLite store. The rest of DB_URL is the path
+// of the database file: sqlite://notes.db or sqlite:///var/lib/notes.db.
+const SQLiteDBURLPrefix = "sqlite://"
+
+// Config is the effective configuration. Its sections match the top-level
+// keys of the configuration file.
 type Config struct {
-	DBURL     string
+	Server      ServerConfig
+	Auth        AuthConfig
+	DB          DBConfig
+	Log         LogConfig
+	Tracing     TracingConfig
+	Shutdown    ShutdownConfig
+	RateLimit   RateLimitConfig
+	Idempotency IdempotencyConfig
+	CORS        CORSConfig
+	TLS         TLSConfig
+
+	// sources maps setting keys to where their value came from
+	sources map[string]string
+	// secretFiles maps the keys of secrets read through a *_FILE variant
+	// to the file
+	secretFiles map[string]string
+}
+
+type ServerConfig struct {
+	Port string
+}
+
+type AuthConfig struct {
 	JWTSecret string
-	Port      string
+	// DropPreviousSecret rejects tokens signed with the previous secret as
+	// soon as JWTSecret rotates, instead of after auth.TokenTTL
+	DropPreviousSecret bool
+	// DeviceVerificationURI is where users enter the code shown by device clients
+	DeviceVerificationURI string
+}
+
+type DBConfig struct {
+	// URL is a Postgres connection string, MemoryDBURL or a sqlite:// URL
+	URL string
+
+	// Pool sizes the primary and replica connection pools
+	Pool store.PoolConfig
+	// ConnectTimeout is how long startup waits for the database
+	ConnectTimeout time.Duration
+	// QueryTimeout bounds each store call; zero disables it
+	QueryTimeout time.Duration
+
+	// ReplicaURLs are read-only Postgres replicas that serve read queries
+	ReplicaURLs []string
+	// ReplicaWindow keeps a user's reads on the primary for this long after
+	// they write, hiding replication lag from them
+	ReplicaWindow time.Duration
+
+	// AutoMigrate applies pending schema migrations on startup
+	AutoMigrate bool
+}
+
+type LogConfig struct {
+	Level slog.Level
+}
+
+type TracingConfig struct {
+	// Exporter is one of "none", "stdout", "file" or "otlp"
+	Exporter     string
+	File         string
+	OTLPEndpoint string
+	OTLPHeaders  map[string]string
+	SampleRatio  float64
+	ServiceName  string
+}
+
+type ShutdownConfig struct {
+	// Timeout bounds how long in-flight requests and background workers may
+	// take to finish after SIGTERM
+	Timeout time.Duration
+	// DrainDelay is how long readiness fails before the listener closes,
+	// giving load balancers time to stop routing traffic
+	DrainDelay time.Duration
 }
 
-func LoadConfig() (*Config, error) {
-	dbURL := os.Getenv("DB_URL")
-	if dbURL == "" {
-		return nil, fmt.Errorf("DB_URL environment variable is not set")
+type RateLimitConfig struct {
+	// Backend is "memory" (per instance) or "postgres" (shared)
+	Backend string
+	// Default applies to routes without an entry in Routes; a zero Limit
+	// disables it
+	Default ratelimit.Limit
+	// Routes maps route patterns such as "POST /auth/login" to limits
+	Routes map[string]ratelimit.Limit
+	// TrustProxyHeaders keys anonymous clients by X-Forwarded-For
+	TrustProxyHeaders bool
+	// TrustedProxies is how many proxies in front of the server append to
+	// X-Forwarded-For
+	TrustedProxies int
+
+	// the specs Default and Routes were parsed from, for printing
+	defaultSpec, routesSpec string
+}
+
+type IdempotencyConfig struct {
+	// Backend is "memory" (per instance) or "postgres" (shared)
+	Backend string
+	// TTL is how long the response to a request with an Idempotency-Key is
+	// replayed to retries
+	TTL time.Duration
+	// Wait is how long a retry of a request still in progress waits for
+	// its response before getting a 409
+	Wait time.Duration
+}
+
+type CORSConfig struct {
+	// AllowedOrigins enables CORS when non-empty. Entries may be exact
+	// origins, "https://*.example.com" or "*"
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+type TLSConfig struct {
+	// CertFile and KeyFile are PEM files that switch the server to HTTPS,
+	// with HTTP/2. They are read again when they change.
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is the PEM bundle client certificates are verified against
+	ClientCAFile string
+	// ClientAuth is "none", "optional" (verify a certificate if one is
+	// sent) or "require"
+	ClientAuth string
+	// ClientIdentity maps the subject common name of a verified client
+	// certificate to a service identity
+	ClientIdentity bool
+}
+
+// Enabled reports whether the server speaks HTTPS.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// Flags is the command-line layer of the configuration: one flag per
+// setting plus -config. Flag values are recorded while the command line is
+// parsed and applied after the file and the environment.
+type Flags struct {
+	file string
+	set  []flagValue
+}
+
+type flagValue struct {
+	key, name, value string
+}
+
+// RegisterFlags defines -config and a flag for every setting on fs, named
+// after the setting's key: -db.url, -db.max-open-conns and so on. Secrets
+// also get a -file variant such as -auth.jwt-secret-file.
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{}
+	fs.StringVar(&f.file, "config", "", "YAML, JSON or TOML configuration `file` (env CONFIG_FILE)")
+	for _, s := range (&Config{}).settings() {
+		_, isBool := s.value.(*boolValue)
+		usage := fmt.Sprintf("%s (env %s)", s.usage, s.env)
+		fs.Var(&flagRecorder{flags: f, key: s.key, name: s.flagName(), def: s.def, isBool: isBool}, s.flagName(), usage)
+		if s.secret() {
+			usage := fmt.Sprintf("file to read %s from (env %s_FILE)", s.key, s.env)
+			fs.Var(&flagRecorder{flags: f, key: s.key + fileSuffix, name: s.flagName() + "-file"}, s.flagName()+"-file", usage)
+		}
 	}
+	return f
+}
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		return nil, fmt.Errorf("JWT_SECRET environment variable is not set")
+// flagRecorder is the flag.Value of a setting's flag.
+type flagRecorder struct {
+	flags  *Flags
+	key    string
+	name   string
+	def    string
+	isBool bool
+}
+
+func (r *flagRecorder) Set(value string) error {
+	r.flags.set = append(r.flags.set, flagValue{key: r.key, name: r.name, value: value})
+	return nil
+}
+
+func (r *flagRecorder) String() string   { return r.def }
+func (r *flagRecorder) IsBoolFlag() bool { return r.isBool }
+
+// LoadConfig layers the defaults, the file named by -config or CONFIG_FILE,
+// the environment and flags, then validates the result. flags may be nil,
+// for commands that take no configuration flags. Every invalid setting is
+// reported, not just the first.
+//
+// Secrets can instead be read from a file, such as a mounted Kubernetes
+// secret, named by JWT_SECRET_FILE, -auth.jwt-secret-file or
+// auth.jwt_secret_file. Call LoadConfig again to pick up a rotated file.
+func LoadConfig(flags *Flags) (*Config, error) {
+	cfg, err := load(flags)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadDBConfig loads the configuration but only validates the db settings,
+// for commands such as `api migrate` that do not need the rest.
+func LoadDBConfig() (*DBConfig, error) {
+	cfg, err := load(nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.validateDB(); err != nil {
+		return nil, err
+	}
+	return &cfg.DB, nil
+}
+
+func load(flags *Flags) (*Config, error) {
+	if flags == nil {
+		flags = &Flags{}
 	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	cfg := &Config{sources: make(map[string]string), secretFiles: make(map[string]string)}
+	settings := cfg.settings()
+	byKey := make(map[string]*setting, len(settings))
+	for i := range settings {
+		byKey[settings[i].key] = &settings[i]
+	}
+	// secretFile returns the secret whose *_file variant key is.
+	secretFile := func(key string) *setting {
+		if base, ok := strings.CutSuffix(key, fileSuffix); ok && byKey[base] != nil && byKey[base].secret() {
+			return byKey[base]
+		}
+		return nil
 	}
 
-	return &Config{
-		DBURL:     dbURL,
-		JWTSecret: jwtSecret,
-		Port:      port,
-	}, nil
+	var errs []error
+	set := func(s *setting, value, source string) {
+		if err := s.value.Set(value); err != nil {
+			if s.secret() {
+				errs = append(errs, fmt.Errorf("invalid %s from %s: %w", s.key, source, err))
+			} else {
+				errs = append(errs, fmt.Errorf("invalid %s %q from %s: %w", s.key, value, source, err))
+			}
+			return
+		}
+		cfg.sources[s.key] = source
+		delete(cfg.secretFiles, s.key)
+	}
+	setFromFile := func(s *setting, path, source string) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reading %s from %s: %w", s.key, source, err))
+			return
+		}
+		set(s, strings.TrimSpace(string(data)), fmt.Sprintf("%s (%s)", source, path))
+		cfg.secretFiles[s.key] = path
+	}
+
+	for i := range settings {
+		cfg.sources[settings[i].key] = "default"
+		if settings[i].def != "" {
+			set(&settings[i], settings[i].def, "default")
+		}
+	}
+
+	file := flags.file
+	if file == "" {
+		file = os.Getenv("CONFIG_FILE")
+	}
+	if file != "" {
+		values, err := readFile(file, func(key string) bool { return byKey[key] != nil })
+		if err != nil {
+			return nil, err
+		}
+		source := "file " + file
+		for _, key := range sortedKeys(values) {
+			s, fromFile := byKey[key], false
+			if s == nil {
+				s, fromFile = secretFile(key), true
+			}
+			if s == nil {
+				errs = append(errs, fmt.Errorf("unknown setting %s in %s", key, source))
+				continue
+			}
+			value, err := fileString(values[key], s.pairSep)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s in %s: %w", key, source, err))
+				continue
+			}
+			if fromFile {
+				setFromFile(s, value, source+" "+key)
+			} else {
+				set(s, value, source)
+			}
+		}
+	}
+
+	for i := range settings {
+		s := &settings[i]
+		value, path := os.Getenv(s.env), ""
+		if s.secret() {
+			path = os.Getenv(s.env + "_FILE")
+		}
+		switch {
+		case value != "" && path != "":
+			errs = append(errs, fmt.Errorf("set only one of %s and %s_FILE", s.env, s.env))
+		case path != "":
+			setFromFile(s, path, "env "+s.env+"_FILE")
+		case value != "":
+			set(s, value, "env "+s.env)
+		}
+	}
+
+	for _, f := range flags.set {
+		if s := byKey[f.key]; s != nil {
+			set(s, f.value, "flag -"+f.name)
+		} else {
+			setFromFile(secretFile(f.key), f.value, "flag -"+f.name)
+		}
+	}
+
+	// Defaults that follow other settings.
+	if cfg.sources["auth.device_verification_uri"] == "default" {
+		cfg.Auth.DeviceVerificationURI = "http://localhost:" + cfg.Server.Port + "/device"
+	}
+	if cfg.sources["db.max_idle_conns"] == "default" {
+		cfg.DB.Pool.MaxIdleConns = cfg.DB.Pool.MaxOpenConns
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+// validate checks the rules that involve more than one setting, and the
+// required ones.
+func (c *Config) validate() error {
+	var errs []error
+	if err := c.validateDB(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Auth.JWTSecret == "" {
+		errs = append(errs, errors.New("auth.jwt_secret is required: set JWT_SECRET, -auth.jwt-secret or auth.jwt_secret in the config file"))
+	}
+
+	postgres := c.DB.URL != MemoryDBURL && !strings.HasPrefix(c.DB.URL, SQLiteDBURLPrefix)
+	if c.RateLimit.Backend == "postgres" && !postgres {
+		errs = append(errs, errors.New("rate_limit.backend postgres requires a Postgres db.url"))
+	}
+	if c.Idempotency.Backend == "postgres" && !postgres {
+		errs = append(errs, errors.New("idempotency.backend postgres requires a Postgres db.url"))
+	}
+	if c.RateLimit.TrustProxyHeaders && c.RateLimit.TrustedProxies < 1 {
+		errs = append(errs, errors.New("rate_limit.trusted_proxies must be at least 1 with rate_limit.trust_proxy_headers"))
+	}
+	if c.Idempotency.TTL <= 0 {
+		errs = append(errs, errors.New("idempotency.ttl must be positive"))
+	}
+	if len(c.DB.ReplicaURLs) > 0 && !postgres {
+		errs = append(errs, errors.New("db.replica_urls requires a Postgres db.url"))
+	}
+	if c.CORS.AllowCredentials && slices.Contains(c.CORS.AllowedOrigins, "*") {
+		errs = append(errs, errors.New("cors.allowed_origins * cannot be combined with cors.allow_credentials"))
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("tls.cert_file and tls.key_file must be set together"))
+	}
+	if c.TLS.ClientAuth != "none" && !c.TLS.Enabled() {
+		errs = append(errs, errors.New("tls.client_auth requires tls.cert_file and tls.key_file"))
+	}
+	if c.TLS.ClientAuth != "none" && c.TLS.ClientCAFile == "" {
+		errs = append(errs, errors.New("tls.client_auth requires tls.client_ca_file"))
+	}
+	if c.TLS.ClientIdentity && c.TLS.ClientAuth == "none" {
+		errs = append(errs, errors.New("tls.client_identity requires tls.client_auth optional or require"))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Config) validateDB() error {
+	var errs []error
+	if c.DB.URL == "" {
+		errs = append(errs, errors.New("db.url is required: set DB_URL, -db.url or db.url in the config file"))
+	}
+	if path, ok := SQLitePath(c.DB.URL); ok && path == "" {
+		errs = append(errs, errors.New("invalid db.url: expected sqlite://<path>"))
+	}
+	if pool := c.DB.Pool; pool.MaxIdleConns > pool.MaxOpenConns && pool.MaxOpenConns > 0 {
+		errs = append(errs, fmt.Errorf("db.max_idle_conns (%d) must not exceed db.max_open_conns (%d)", pool.MaxIdleConns, pool.MaxOpenConns))
+	}
+	return errors.Join(errs...)
+}
+
+// Setting is one line of `api config print`.
+type Setting struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Source is "default", "file <path>", "env <NAME>" or "flag -<name>"
+	Source string `json:"source"`
+}
+
+// Settings lists every setting with its effective value, secrets redacted,
+// and where the value came from.
+func (c *Config) Settings() []Setting {
+	var list []Setting
+	for _, s := range c.settings() {
+		value := s.value.String()
+		if s.redact != nil && value != "" {
+			value = s.redact(value)
+		}
+		list = append(list, Setting{Key: s.key, Value: value, Source: c.sources[s.key]})
+	}
+	return list
+}
+
+// SecretFiles returns the files that secrets were read from through the
+// *_FILE variants of their settings, so they can be watched for rotation.
+func (c *Config) SecretFiles() []string {
+	var files []string
+	for _, path := range c.secretFiles {
+		if !slices.Contains(files, path) {
+			files = append(files, path)
+		}
+	}
+	slices.Sort(files)
+	return files
+}
+
+// SQLitePath returns the database file of a sqlite:// DB_URL.
+func SQLitePath(dbURL string) (string, bool) {
+	return strings.CutPrefix(dbURL, SQLiteDBURLPrefix)
+}
+
+// parseRouteLimits parses "<route>=<limit>;..." such as
+// "POST /auth/login=5/1m;POST /notes=30/1m:10". An empty value selects
+// defaultRouteLimits and "none" disables per-route limits.
+func parseRouteLimits(value string) (map[string]ratelimit.Limit, error) {
+	limits := make(map[string]ratelimit.Limit)
+	switch value {
+	case "none":
+		return limits, nil
+	case "":
+		value = defaultRouteLimits
+	}
+
+	for _, entry := range strings.Split(value, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		route, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("entry %q: expected <route>=<count>/<period>[:<burst>]", entry)
+		}
+		limit, err := ratelimit.ParseLimit(spec)
+		if err != nil {
+			return nil, fmt.Errorf("entry for %q: %w", strings.TrimSpace(route), err)
+		}
+		limits[strings.TrimSpace(route)] = limit
+	}
+	return limits, nil
 }
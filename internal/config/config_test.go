@@ -0,0 +1,428 @@
+package config
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/ratelimit"
+)
+
+// setenv clears every setting's environment variable, so the host
+// environment does not leak into the test, then sets env.
+func setenv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, s := range (&Config{}).settings() {
+		t.Setenv(s.env, "")
+		t.Setenv(s.env+"_FILE", "")
+	}
+	t.Setenv("CONFIG_FILE", "")
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func parseFlags(t *testing.T, args ...string) *Flags {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return flags
+}
+
+func source(cfg *Config, key string) string {
+	for _, s := range cfg.Settings() {
+		if s.Key == key {
+			return s.Source
+		}
+	}
+	return ""
+}
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	setenv(t, map[string]string{"DB_URL": "memory://", "JWT_SECRET": "secret"})
+
+	cfg, err := LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.Server.Port != "8080" || cfg.Auth.DeviceVerificationURI != "http://localhost:8080/device" {
+		t.Errorf("Unexpected server defaults: %+v, %+v", cfg.Server, cfg.Auth)
+	}
+	if cfg.DB.Pool.MaxOpenConns != 25 || cfg.DB.Pool.MaxIdleConns != 25 || cfg.DB.Pool.ConnMaxLifetime != 5*time.Minute {
+		t.Errorf("Unexpected pool defaults: %+v", cfg.DB.Pool)
+	}
+	if cfg.Log.Level != slog.LevelInfo || cfg.Tracing.Exporter != "none" || cfg.Tracing.SampleRatio != 1 {
+		t.Errorf("Unexpected log or tracing defaults: %v, %+v", cfg.Log.Level, cfg.Tracing)
+	}
+	if cfg.RateLimit.Default != ratelimit.Per(120, time.Minute) || len(cfg.RateLimit.Routes) != 3 {
+		t.Errorf("Unexpected rate limit defaults: %+v", cfg.RateLimit)
+	}
+	if len(cfg.CORS.AllowedOrigins) != 0 || len(cfg.CORS.AllowedMethods) != 5 {
+		t.Errorf("Unexpected CORS defaults: %+v", cfg.CORS)
+	}
+	if got := source(cfg, "server.port"); got != "default" {
+		t.Errorf("Expected server.port to come from the default, got %q", got)
+	}
+}
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	file := writeFile(t, "notes.yaml", `
+server:
+  port: 7000
+db:
+  url: memory://
+  max_open_conns: 10
+log:
+  level: debug
+`)
+	setenv(t, map[string]string{"CONFIG_FILE": file, "JWT_SECRET": "secret", "PORT": "7001", "DB_MAX_OPEN_CONNS": "20"})
+
+	cfg, err := LoadConfig(parseFlags(t, "-server.port", "7002"))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.Server.Port != "7002" {
+		t.Errorf("Expected the flag to win, got port %s", cfg.Server.Port)
+	}
+	if cfg.DB.Pool.MaxOpenConns != 20 {
+		t.Errorf("Expected the environment to beat the file, got %d", cfg.DB.Pool.MaxOpenConns)
+	}
+	if cfg.Log.Level != slog.LevelDebug {
+		t.Errorf("Expected the file to beat the default, got %v", cfg.Log.Level)
+	}
+	if cfg.Auth.DeviceVerificationURI != "http://localhost:7002/device" {
+		t.Errorf("Expected the verification URI to follow the port, got %s", cfg.Auth.DeviceVerificationURI)
+	}
+
+	for key, want := range map[string]string{
+		"server.port":       "flag -server.port",
+		"db.max_open_conns": "env DB_MAX_OPEN_CONNS",
+		"log.level":         "file " + file,
+		"db.query_timeout":  "default",
+	} {
+		if got := source(cfg, key); got != want {
+			t.Errorf("Expected %s to come from %q, got %q", key, want, got)
+		}
+	}
+}
+
+func TestLoadConfig_ConfigFlagBeatsEnv(t *testing.T) {
+	envFile := writeFile(t, "env.json", `{"server": {"port": 7000}}`)
+	flagFile := writeFile(t, "flag.json", `{"server": {"port": 7001}}`)
+	setenv(t, map[string]string{"CONFIG_FILE": envFile, "DB_URL": "memory://", "JWT_SECRET": "secret"})
+
+	cfg, err := LoadConfig(parseFlags(t, "-config", flagFile))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Server.Port != "7001" {
+		t.Errorf("Expected -config to select the file, got port %s", cfg.Server.Port)
+	}
+}
+
+func TestLoadConfig_FileFormats(t *testing.T) {
+	files := map[string]string{
+		"notes.yaml": `
+db:
+  url: memory://
+  replica_window: 1s
+cors:
+  allowed_origins: [https://a.example, https://b.example]
+tracing:
+  otlp_headers:
+    Authorization: Bearer token
+rate_limit:
+  routes:
+    POST /notes: 30/1m
+`,
+		"notes.json": `{
+  "db": {"url": "memory://", "replica_window": "1s"},
+  "cors": {"allowed_origins": ["https://a.example", "https://b.example"]},
+  "tracing": {"otlp_headers": {"Authorization": "Bearer token"}},
+  "rate_limit": {"routes": {"POST /notes": "30/1m"}}
+}`,
+		"notes.toml": `
+[db]
+url = "memory://"
+replica_window = "1s"
+
+[cors]
+allowed_origins = ["https://a.example", "https://b.example"]
+
+[tracing.otlp_headers]
+Authorization = "Bearer token"
+
+[rate_limit.routes]
+"POST /notes" = "30/1m"
+`,
+	}
+
+	for name, content := range files {
+		t.Run(filepath.Ext(name), func(t *testing.T) {
+			setenv(t, map[string]string{"CONFIG_FILE": writeFile(t, name, content), "JWT_SECRET": "secret"})
+
+			cfg, err := LoadConfig(nil)
+			if err != nil {
+				t.Fatalf("LoadConfig failed: %v", err)
+			}
+			if cfg.DB.URL != MemoryDBURL || cfg.DB.ReplicaWindow != time.Second {
+				t.Errorf("Unexpected db section: %+v", cfg.DB)
+			}
+			if strings.Join(cfg.CORS.AllowedOrigins, " ") != "https://a.example https://b.example" {
+				t.Errorf("Unexpected origins: %v", cfg.CORS.AllowedOrigins)
+			}
+			if cfg.Tracing.OTLPHeaders["Authorization"] != "Bearer token" {
+				t.Errorf("Unexpected headers: %v", cfg.Tracing.OTLPHeaders)
+			}
+			if len(cfg.RateLimit.Routes) != 1 || cfg.RateLimit.Routes["POST /notes"] != ratelimit.Per(30, time.Minute) {
+				t.Errorf("Unexpected route limits: %v", cfg.RateLimit.Routes)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_FileErrors(t *testing.T) {
+	tests := []struct {
+		name, file, content, want string
+	}{
+		{"unknown key", "notes.yaml", "db:\n  ur1: memory://\n", "unknown setting db.ur1"},
+		{"map for a scalar", "notes.yaml", "server:\n  port:\n    number: 1\n", "expected a single value"},
+		{"unknown format", "notes.ini", "port=1\n", "unknown format"},
+		{"syntax error", "notes.json", "{", "config file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setenv(t, map[string]string{"CONFIG_FILE": writeFile(t, tt.file, tt.content), "DB_URL": "memory://", "JWT_SECRET": "secret"})
+
+			_, err := LoadConfig(nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_ReportsEveryInvalidSetting(t *testing.T) {
+	setenv(t, map[string]string{
+		"PORT":                 "http",
+		"DB_MAX_OPEN_CONNS":    "-1",
+		"TRACING_EXPORTER":     "jaeger",
+		"TRACING_SAMPLE_RATIO": "2",
+		"RATE_LIMITS":          "POST /notes",
+	})
+
+	_, err := LoadConfig(parseFlags(t, "-shutdown.timeout", "soon"))
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for _, want := range []string{
+		`invalid server.port "http" from env PORT`,
+		`invalid db.max_open_conns "-1" from env DB_MAX_OPEN_CONNS`,
+		`invalid tracing.exporter "jaeger" from env TRACING_EXPORTER: must be one of none, stdout, file, otlp`,
+		`invalid tracing.sample_ratio "2"`,
+		`invalid rate_limit.routes "POST /notes"`,
+		`invalid shutdown.timeout "soon" from flag -shutdown.timeout`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to contain %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadConfig_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{"required", map[string]string{}, []string{"db.url is required", "auth.jwt_secret is required"}},
+		{"empty sqlite path", map[string]string{"DB_URL": "sqlite://", "JWT_SECRET": "s"}, []string{"expected sqlite://<path>"}},
+		{"postgres rate limits", map[string]string{"DB_URL": "memory://", "JWT_SECRET": "s", "RATE_LIMIT_BACKEND": "postgres"}, []string{"requires a Postgres db.url"}},
+		{"replicas", map[string]string{"DB_URL": "sqlite://notes.db", "JWT_SECRET": "s", "DB_REPLICA_URLS": "postgres://replica"}, []string{"db.replica_urls requires a Postgres db.url"}},
+		{"idle connections", map[string]string{"DB_URL": "postgres://db", "JWT_SECRET": "s", "DB_MAX_OPEN_CONNS": "5", "DB_MAX_IDLE_CONNS": "10"}, []string{"must not exceed db.max_open_conns"}},
+		{"cors credentials", map[string]string{"DB_URL": "memory://", "JWT_SECRET": "s", "CORS_ALLOWED_ORIGINS": "*", "CORS_ALLOW_CREDENTIALS": "true"}, []string{"cannot be combined"}},
+		{"postgres idempotency keys", map[string]string{"DB_URL": "memory://", "JWT_SECRET": "s", "IDEMPOTENCY_BACKEND": "postgres"}, []string{"idempotency.backend postgres requires a Postgres db.url"}},
+		{"idempotency ttl", map[string]string{"DB_URL": "memory://", "JWT_SECRET": "s", "IDEMPOTENCY_TTL": "0s"}, []string{"idempotency.ttl must be positive"}},
+		{"trusted proxies", map[string]string{"DB_URL": "memory://", "JWT_SECRET": "s", "TRUST_PROXY_HEADERS": "true", "TRUSTED_PROXIES": "0"}, []string{"rate_limit.trusted_proxies must be at least 1"}},
+		{"tls key without cert", map[string]string{"DB_URL": "memory://", "JWT_SECRET": "s", "TLS_KEY_FILE": "key.pem"}, []string{"must be set together"}},
+		{"client auth without tls", map[string]string{"DB_URL": "memory://", "JWT_SECRET": "s", "TLS_CLIENT_AUTH": "require", "TLS_CLIENT_CA_FILE": "ca.pem"}, []string{"tls.client_auth requires tls.cert_file"}},
+		{"client auth without ca", map[string]string{"DB_URL": "memory://", "JWT_SECRET": "s", "TLS_CERT_FILE": "cert.pem", "TLS_KEY_FILE": "key.pem", "TLS_CLIENT_AUTH": "optional"}, []string{"requires tls.client_ca_file"}},
+		{"client identity without client auth", map[string]string{"DB_URL": "memory://", "JWT_SECRET": "s", "TLS_CERT_FILE": "cert.pem", "TLS_KEY_FILE": "key.pem", "TLS_CLIENT_IDENTITY": "true"}, []string{"tls.client_identity requires"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setenv(t, tt.env)
+
+			_, err := LoadConfig(nil)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected the error to contain %q, got %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfig_NoneDisablesRateLimits(t *testing.T) {
+	setenv(t, map[string]string{"DB_URL": "memory://", "JWT_SECRET": "s", "RATE_LIMIT_DEFAULT": "none", "RATE_LIMITS": "none"})
+
+	cfg, err := LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.RateLimit.Default != (ratelimit.Limit{}) || len(cfg.RateLimit.Routes) != 0 {
+		t.Errorf("Expected no limits, got %+v", cfg.RateLimit)
+	}
+}
+
+func TestLoadDBConfig_IgnoresOtherRequiredSettings(t *testing.T) {
+	setenv(t, map[string]string{"DB_URL": "postgres://db", "DB_MAX_OPEN_CONNS": "7"})
+
+	db, err := LoadDBConfig()
+	if err != nil {
+		t.Fatalf("LoadDBConfig failed: %v", err)
+	}
+	if db.URL != "postgres://db" || db.Pool.MaxOpenConns != 7 {
+		t.Errorf("Expected postgres://db with 7 connections, got %q, %d", db.URL, db.Pool.MaxOpenConns)
+	}
+}
+
+func TestSettings_RedactsSecrets(t *testing.T) {
+	setenv(t, map[string]string{
+		"DB_URL":               "postgres://notes:hunter2@db/notes?sslmode=disable",
+		"DB_REPLICA_URLS":      "host=replica password=hunter2 dbname=notes",
+		"JWT_SECRET":           "hunter2",
+		"TRACING_OTLP_HEADERS": "Authorization=Bearer hunter2",
+	})
+
+	cfg, err := LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	want := map[string]string{
+		"db.url":               "postgres://notes:xxxxx@db/notes?sslmode=disable",
+		"db.replica_urls":      "host=replica password=xxxxx dbname=notes",
+		"auth.jwt_secret":      "xxxxx",
+		"tracing.otlp_headers": "Authorization=xxxxx",
+	}
+	for _, s := range cfg.Settings() {
+		if strings.Contains(s.Value, "hunter2") {
+			t.Errorf("Expected %s to be redacted, got %q", s.Key, s.Value)
+		}
+		if w, ok := want[s.Key]; ok && s.Value != w {
+			t.Errorf("Expected %s to print as %q, got %q", s.Key, w, s.Value)
+		}
+	}
+}
+
+func TestLoadConfig_BoolFlagWithoutValue(t *testing.T) {
+	setenv(t, map[string]string{"DB_URL": "memory://", "JWT_SECRET": "s"})
+
+	cfg, err := LoadConfig(parseFlags(t, "-db.auto-migrate", "-cors.allowed-origins", "https://a.example"))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if !cfg.DB.AutoMigrate || len(cfg.CORS.AllowedOrigins) != 1 {
+		t.Errorf("Expected the flags to apply, got %+v, %+v", cfg.DB, cfg.CORS)
+	}
+}
+
+func TestLoadConfig_SecretFiles(t *testing.T) {
+	jwtFile := writeFile(t, "jwt", "from-env-file\n")
+	dbFile := writeFile(t, "db", "postgres://notes:hunter2@db/notes")
+	headersFile := writeFile(t, "headers", "Authorization=Bearer token")
+	configFile := writeFile(t, "notes.yaml", "tracing:\n  otlp_headers_file: "+headersFile+"\n")
+	setenv(t, map[string]string{"CONFIG_FILE": configFile, "JWT_SECRET_FILE": jwtFile})
+
+	cfg, err := LoadConfig(parseFlags(t, "-db.url-file", dbFile))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.Auth.JWTSecret != "from-env-file" {
+		t.Errorf("Expected the secret without its trailing newline, got %q", cfg.Auth.JWTSecret)
+	}
+	if cfg.DB.URL != "postgres://notes:hunter2@db/notes" {
+		t.Errorf("Expected the URL from the flag's file, got %q", cfg.DB.URL)
+	}
+	if cfg.Tracing.OTLPHeaders["Authorization"] != "Bearer token" {
+		t.Errorf("Expected the headers from the config file's file, got %v", cfg.Tracing.OTLPHeaders)
+	}
+	if got, want := source(cfg, "auth.jwt_secret"), "env JWT_SECRET_FILE ("+jwtFile+")"; got != want {
+		t.Errorf("Expected source %q, got %q", want, got)
+	}
+
+	files := cfg.SecretFiles()
+	if len(files) != 3 {
+		t.Errorf("Expected the three files to be watched, got %v", files)
+	}
+}
+
+func TestLoadConfig_SecretValueOverridesEarlierFile(t *testing.T) {
+	configFile := writeFile(t, "notes.yaml", "auth:\n  jwt_secret_file: "+writeFile(t, "jwt", "from-file")+"\n")
+	setenv(t, map[string]string{"CONFIG_FILE": configFile, "DB_URL": "memory://", "JWT_SECRET": "from-env"})
+
+	cfg, err := LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Auth.JWTSecret != "from-env" || len(cfg.SecretFiles()) != 0 {
+		t.Errorf("Expected the environment to win and nothing to be watched, got %q, %v", cfg.Auth.JWTSecret, cfg.SecretFiles())
+	}
+}
+
+func TestLoadConfig_SecretFileErrors(t *testing.T) {
+	setenv(t, map[string]string{
+		"DB_URL":          "memory://",
+		"JWT_SECRET":      "secret",
+		"JWT_SECRET_FILE": writeFile(t, "jwt", "secret"),
+		"DB_URL_FILE":     filepath.Join(t.TempDir(), "missing"),
+	})
+
+	_, err := LoadConfig(nil)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for _, want := range []string{"set only one of DB_URL and DB_URL_FILE", "set only one of JWT_SECRET and JWT_SECRET_FILE"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to contain %q, got %v", want, err)
+		}
+	}
+
+	setenv(t, map[string]string{"JWT_SECRET": "secret", "DB_URL_FILE": filepath.Join(t.TempDir(), "missing")})
+	if _, err := LoadConfig(nil); err == nil || !strings.Contains(err.Error(), "reading db.url from env DB_URL_FILE") {
+		t.Errorf("Expected a read error, got %v", err)
+	}
+}
+
+func TestLoadConfig_FileVariantsOnlyForSecrets(t *testing.T) {
+	setenv(t, map[string]string{"CONFIG_FILE": writeFile(t, "notes.yaml", "server:\n  port_file: /tmp/port\n"), "DB_URL": "memory://", "JWT_SECRET": "s"})
+
+	if _, err := LoadConfig(nil); err == nil || !strings.Contains(err.Error(), "unknown setting server.port_file") {
+		t.Errorf("Expected port_file to be unknown, got %v", err)
+	}
+}
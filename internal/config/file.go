@@ -0,0 +1,106 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// readFile decodes a configuration file, picking the format by extension,
+// and flattens its sections into dotted keys such as "db.url". A map stops
+// being flattened where isSetting says it is a setting's value, as with
+// rate_limit.routes.
+func readFile(path string, isSetting func(key string) bool) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	var doc map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".json":
+		err = json.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("config file %s: unknown format, expected .yaml, .yml, .json or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	values := make(map[string]any)
+	flatten("", doc, isSetting, values)
+	return values, nil
+}
+
+func flatten(prefix string, doc map[string]any, isSetting func(string) bool, values map[string]any) {
+	for name, value := range doc {
+		key := prefix + name
+		if section, ok := value.(map[string]any); ok && !isSetting(key) {
+			flatten(key+".", section, isSetting, values)
+			continue
+		}
+		values[key] = value
+	}
+}
+
+// fileString renders a decoded file value in the syntax of the setting's
+// environment variable: lists are joined with commas and maps become
+// key=value pairs joined with pairSep.
+func fileString(value any, pairSep string) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := fileString(item, "")
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	case map[string]any:
+		if pairSep == "" {
+			return "", fmt.Errorf("expected a single value, got a map")
+		}
+		var pairs []string
+		for _, key := range sortedKeys(v) {
+			s, err := fileString(v[key], "")
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, key+"="+s)
+		}
+		return strings.Join(pairs, pairSep), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", value)
+}
+
+func sortedKeys(m map[string]any) []string {
+	return slices.Sorted(maps.Keys(m))
+}
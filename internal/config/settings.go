@@ -0,0 +1,317 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/ratelimit"
+)
+
+// setting is one configuration value. Its key names it in the config file
+// ("db.max_open_conns") and, with dashes, as a flag (-db.max-open-conns).
+type setting struct {
+	key   string
+	env   string
+	def   string
+	usage string
+	value flag.Value
+	// pairSep joins the entries of a map in the config file into the
+	// syntax the environment variable uses
+	pairSep string
+	// redact hides the value when the configuration is printed. Settings
+	// that have it are secrets, which may also be read from a file.
+	redact func(string) string
+}
+
+// fileSuffix turns the key of a secret into the key of the file to read it
+// from: auth.jwt_secret_file, like JWT_SECRET_FILE in the environment.
+const fileSuffix = "_file"
+
+func (s setting) secret() bool {
+	return s.redact != nil
+}
+
+func (s setting) flagName() string {
+	return strings.ReplaceAll(s.key, "_", "-")
+}
+
+// settings binds every setting to its field of c, in the order `api config
+// print` lists them.
+func (c *Config) settings() []setting {
+	return []setting{
+		{key: "server.port", env: "PORT", def: "8080", usage: "TCP port to listen on", value: (*portValue)(&c.Server.Port)},
+
+		{key: "auth.jwt_secret", env: "JWT_SECRET", usage: "secret that signs access tokens", value: (*stringValue)(&c.Auth.JWTSecret), redact: redactAll},
+		{key: "auth.jwt_drop_previous_secret", env: "JWT_DROP_PREVIOUS_SECRET", def: "false", usage: "reject tokens signed with the previous secret at once when it rotates, e.g. after a leak", value: (*boolValue)(&c.Auth.DropPreviousSecret)},
+		{key: "auth.device_verification_uri", env: "DEVICE_VERIFICATION_URI", usage: "where users enter device codes (default http://localhost:<port>/device)", value: (*stringValue)(&c.Auth.DeviceVerificationURI)},
+
+		{key: "db.url", env: "DB_URL", usage: "Postgres connection string, memory:// or sqlite://<path>", value: (*stringValue)(&c.DB.URL), redact: redactDSN},
+		{key: "db.max_open_conns", env: "DB_MAX_OPEN_CONNS", def: "25", usage: "maximum open connections per pool; 0 is unlimited", value: (*intValue)(&c.DB.Pool.MaxOpenConns)},
+		{key: "db.max_idle_conns", env: "DB_MAX_IDLE_CONNS", usage: "maximum idle connections per pool (default db.max_open_conns)", value: (*intValue)(&c.DB.Pool.MaxIdleConns)},
+		{key: "db.conn_max_idle_time", env: "DB_CONN_MAX_IDLE_TIME", usage: "close connections idle for longer; 0 keeps them", value: (*durationValue)(&c.DB.Pool.ConnMaxIdleTime)},
+		{key: "db.conn_max_lifetime", env: "DB_CONN_MAX_LIFETIME", def: "5m", usage: "close connections older than this; 0 keeps them", value: (*durationValue)(&c.DB.Pool.ConnMaxLifetime)},
+		{key: "db.statement_timeout", env: "DB_STATEMENT_TIMEOUT", usage: "server-side statement timeout; 0 disables it", value: (*durationValue)(&c.DB.Pool.StatementTimeout)},
+		{key: "db.connect_timeout", env: "DB_CONNECT_TIMEOUT", def: "30s", usage: "how long startup waits for the database", value: (*durationValue)(&c.DB.ConnectTimeout)},
+		{key: "db.query_timeout", env: "DB_QUERY_TIMEOUT", def: "5s", usage: "timeout of each store call; 0 disables it", value: (*durationValue)(&c.DB.QueryTimeout)},
+		{key: "db.replica_urls", env: "DB_REPLICA_URLS", usage: "comma-separated read replica connection strings", value: (*listValue)(&c.DB.ReplicaURLs), redact: redactDSNs},
+		{key: "db.replica_window", env: "DB_REPLICA_WINDOW", def: "5s", usage: "how long a user's reads stay on the primary after they write", value: (*durationValue)(&c.DB.ReplicaWindow)},
+		{key: "db.auto_migrate", env: "AUTO_MIGRATE", def: "false", usage: "apply pending migrations on startup", value: (*boolValue)(&c.DB.AutoMigrate)},
+
+		{key: "log.level", env: "LOG_LEVEL", def: "info", usage: "debug, info, warn or error", value: (*levelValue)(&c.Log.Level)},
+
+		{key: "tracing.exporter", env: "TRACING_EXPORTER", def: "none", usage: "none, stdout, file or otlp", value: &enumValue{p: &c.Tracing.Exporter, allowed: []string{"none", "stdout", "file", "otlp"}}},
+		{key: "tracing.file", env: "TRACING_FILE", def: "traces.jsonl", usage: "file the file exporter appends to", value: (*stringValue)(&c.Tracing.File)},
+		{key: "tracing.otlp_endpoint", env: "TRACING_OTLP_ENDPOINT", def: "http://localhost:4318/v1/traces", usage: "OTLP/HTTP traces endpoint", value: (*stringValue)(&c.Tracing.OTLPEndpoint)},
+		{key: "tracing.otlp_headers", env: "TRACING_OTLP_HEADERS", usage: "comma-separated key=value headers sent to the OTLP endpoint", value: (*headersValue)(&c.Tracing.OTLPHeaders), pairSep: ",", redact: redactHeaders},
+		{key: "tracing.sample_ratio", env: "TRACING_SAMPLE_RATIO", def: "1", usage: "fraction of traces to sample, between 0 and 1", value: (*ratioValue)(&c.Tracing.SampleRatio)},
+		{key: "tracing.service_name", env: "TRACING_SERVICE_NAME", def: "notes-api", usage: "service name reported in traces", value: (*stringValue)(&c.Tracing.ServiceName)},
+
+		{key: "shutdown.timeout", env: "SHUTDOWN_TIMEOUT", def: "30s", usage: "how long requests and workers may take to finish on shutdown", value: (*durationValue)(&c.Shutdown.Timeout)},
+		{key: "shutdown.drain_delay", env: "SHUTDOWN_DRAIN_DELAY", def: "5s", usage: "how long readiness fails before the listener closes", value: (*durationValue)(&c.Shutdown.DrainDelay)},
+
+		{key: "rate_limit.backend", env: "RATE_LIMIT_BACKEND", def: "memory", usage: "memory (per instance) or postgres (shared)", value: &enumValue{p: &c.RateLimit.Backend, allowed: []string{"memory", "postgres"}}},
+		{key: "rate_limit.default", env: "RATE_LIMIT_DEFAULT", def: "120/1m", usage: "limit of routes without their own, as <count>/<period>[:<burst>] or none", value: &limitValue{p: &c.RateLimit.Default, spec: &c.RateLimit.defaultSpec}},
+		{key: "rate_limit.routes", env: "RATE_LIMITS", def: defaultRouteLimits, usage: "per-route limits as <route>=<limit>;... or none", value: &routeLimitsValue{p: &c.RateLimit.Routes, spec: &c.RateLimit.routesSpec}, pairSep: ";"},
+		{key: "rate_limit.trust_proxy_headers", env: "TRUST_PROXY_HEADERS", def: "false", usage: "key anonymous clients by X-Forwarded-For", value: (*boolValue)(&c.RateLimit.TrustProxyHeaders)},
+		{key: "rate_limit.trusted_proxies", env: "TRUSTED_PROXIES", def: "1", usage: "how many proxies append to X-Forwarded-For; the client is the entry that many from the end", value: (*intValue)(&c.RateLimit.TrustedProxies)},
+
+		{key: "idempotency.backend", env: "IDEMPOTENCY_BACKEND", def: "memory", usage: "memory (per instance) or postgres (shared)", value: &enumValue{p: &c.Idempotency.Backend, allowed: []string{"memory", "postgres"}}},
+		{key: "idempotency.ttl", env: "IDEMPOTENCY_TTL", def: "24h", usage: "how long responses to requests with an Idempotency-Key are replayed", value: (*durationValue)(&c.Idempotency.TTL)},
+		{key: "idempotency.wait", env: "IDEMPOTENCY_WAIT", def: "5s", usage: "how long a retry of a request in progress waits before getting 409", value: (*durationValue)(&c.Idempotency.Wait)},
+
+		{key: "cors.allowed_origins", env: "CORS_ALLOWED_ORIGINS", usage: "comma-separated origins; CORS is off when empty", value: (*listValue)(&c.CORS.AllowedOrigins)},
+		{key: "cors.allowed_methods", env: "CORS_ALLOWED_METHODS", def: "GET,POST,PUT,PATCH,DELETE", usage: "comma-separated methods", value: (*listValue)(&c.CORS.AllowedMethods)},
+		{key: "cors.allowed_headers", env: "CORS_ALLOWED_HEADERS", def: "Authorization,Content-Type,X-Request-ID,Idempotency-Key", usage: "comma-separated request headers", value: (*listValue)(&c.CORS.AllowedHeaders)},
+		{key: "cors.allow_credentials", env: "CORS_ALLOW_CREDENTIALS", def: "false", usage: "allow cookies and Authorization on cross-origin requests", value: (*boolValue)(&c.CORS.AllowCredentials)},
+		{key: "cors.max_age", env: "CORS_MAX_AGE", def: "10m", usage: "how long browsers may cache preflight responses", value: (*durationValue)(&c.CORS.MaxAge)},
+
+		{key: "tls.cert_file", env: "TLS_CERT_FILE", usage: "PEM certificate chain; serves HTTPS and HTTP/2 when set", value: (*stringValue)(&c.TLS.CertFile)},
+		{key: "tls.key_file", env: "TLS_KEY_FILE", usage: "PEM private key of tls.cert_file", value: (*stringValue)(&c.TLS.KeyFile)},
+		{key: "tls.client_ca_file", env: "TLS_CLIENT_CA_FILE", usage: "PEM CA bundle that client certificates are verified against", value: (*stringValue)(&c.TLS.ClientCAFile)},
+		{key: "tls.client_auth", env: "TLS_CLIENT_AUTH", def: "none", usage: "none, optional (verify a certificate if sent) or require", value: &enumValue{p: &c.TLS.ClientAuth, allowed: []string{"none", "optional", "require"}}},
+		{key: "tls.client_identity", env: "TLS_CLIENT_IDENTITY", def: "false", usage: "map the subject common name of a verified client certificate to a service identity", value: (*boolValue)(&c.TLS.ClientIdentity)},
+	}
+}
+
+// The values below parse the syntax of the environment variables. File and
+// flag values go through the same parsers.
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error { *v = stringValue(s); return nil }
+func (v *stringValue) String() string     { return string(*v) }
+
+type portValue string
+
+func (v *portValue) Set(s string) error {
+	if n, err := strconv.Atoi(s); err != nil || n < 1 || n > 65535 {
+		return errors.New("must be a port number between 1 and 65535")
+	}
+	*v = portValue(s)
+	return nil
+}
+
+func (v *portValue) String() string { return string(*v) }
+
+type enumValue struct {
+	p       *string
+	allowed []string
+}
+
+func (v *enumValue) Set(s string) error {
+	if !slices.Contains(v.allowed, s) {
+		return fmt.Errorf("must be one of %s", strings.Join(v.allowed, ", "))
+	}
+	*v.p = s
+	return nil
+}
+
+func (v *enumValue) String() string { return *v.p }
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return errors.New("must be true or false")
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return errors.New("must be a non-negative integer")
+	}
+	*v = intValue(n)
+	return nil
+}
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return errors.New("must be a non-negative duration like 30s")
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+
+type ratioValue float64
+
+func (v *ratioValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 || f > 1 {
+		return errors.New("must be between 0 and 1")
+	}
+	*v = ratioValue(f)
+	return nil
+}
+
+func (v *ratioValue) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+
+type levelValue slog.Level
+
+func (v *levelValue) Set(s string) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return err
+	}
+	*v = levelValue(level)
+	return nil
+}
+
+func (v *levelValue) String() string { return strings.ToLower(slog.Level(*v).String()) }
+
+// listValue is a comma-separated list; blank entries are dropped.
+type listValue []string
+
+func (v *listValue) Set(s string) error {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	*v = list
+	return nil
+}
+
+func (v *listValue) String() string { return strings.Join(*v, ",") }
+
+// headersValue is a comma-separated list of key=value pairs.
+type headersValue map[string]string
+
+func (v *headersValue) Set(s string) error {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("entry %q: expected key=value", pair)
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	*v = headers
+	return nil
+}
+
+func (v *headersValue) String() string {
+	var pairs []string
+	for _, key := range slices.Sorted(maps.Keys(*v)) {
+		pairs = append(pairs, key+"="+(*v)[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// limitValue is a ratelimit.ParseLimit spec or "none" for no limit. It
+// keeps the spec for printing, which Limit.String does not reproduce.
+type limitValue struct {
+	p    *ratelimit.Limit
+	spec *string
+}
+
+func (v *limitValue) Set(s string) error {
+	limit := ratelimit.Limit{}
+	if s != "none" {
+		var err error
+		if limit, err = ratelimit.ParseLimit(s); err != nil {
+			return err
+		}
+	}
+	*v.p, *v.spec = limit, s
+	return nil
+}
+
+func (v *limitValue) String() string { return *v.spec }
+
+type routeLimitsValue struct {
+	p    *map[string]ratelimit.Limit
+	spec *string
+}
+
+func (v *routeLimitsValue) Set(s string) error {
+	limits, err := parseRouteLimits(s)
+	if err != nil {
+		return err
+	}
+	*v.p, *v.spec = limits, s
+	return nil
+}
+
+func (v *routeLimitsValue) String() string { return *v.spec }
+
+// redacted replaces secrets in printed values. It matches url.URL.Redacted.
+const redacted = "xxxxx"
+
+func redactAll(string) string { return redacted }
+
+var dsnPassword = regexp.MustCompile(`password=('[^']*'|[^\s&]+)`)
+
+// redactDSN hides the password of a URL or key=value connection string.
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.User != nil {
+		dsn = u.Redacted()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "password="+redacted)
+}
+
+func redactDSNs(list string) string {
+	dsns := strings.Split(list, ",")
+	for i, dsn := range dsns {
+		dsns[i] = redactDSN(dsn)
+	}
+	return strings.Join(dsns, ",")
+}
+
+// redactHeaders keeps header names, which help debugging, and hides values,
+// which are usually credentials.
+func redactHeaders(headers string) string {
+	pairs := strings.Split(headers, ",")
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		pairs[i] = key + "=" + redacted
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,57 @@
+// Package idempotency remembers the responses to requests sent with an
+// Idempotency-Key, so a client retrying a request whose response it never
+// received gets the original response instead of a second side effect. It
+// has in-memory and Postgres-backed storage.
+//
+// A key goes through two states: claimed by the request that runs, which
+// holds it for a short lease, then completed with that request's response,
+// which is kept for a longer TTL. A claim whose lease ran out, because the
+// instance handling it died, can be claimed again. Each claim carries a
+// token chosen by its request, so a request that outlived its lease cannot
+// overwrite the key once another request has claimed it.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrLeaseLost is returned by Complete when the key is no longer held by
+// the claim with the given token.
+var ErrLeaseLost = errors.New("idempotency key lease was lost")
+
+// Response is a stored response: what a retry is answered with.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Record is the state of a key claimed by an earlier request.
+type Record struct {
+	// Fingerprint identifies the request that claimed the key, so the key
+	// cannot be reused for a different request.
+	Fingerprint string
+	// Response is nil while that request is still running.
+	Response *Response
+}
+
+// Store keeps idempotency keys. Keys are opaque to it; callers scope them,
+// e.g. by user.
+type Store interface {
+	// Claim reserves key for the request identified by fingerprint for up
+	// to lease, under token, a unique value that identifies the claim. It
+	// returns nil when the caller got the key, and the record of the
+	// request holding it otherwise.
+	Claim(ctx context.Context, key, fingerprint, token string, lease time.Duration) (*Record, error)
+	// Complete stores the response to the request that claimed key with
+	// token and keeps it for ttl. It returns ErrLeaseLost when the key has
+	// been claimed again since.
+	Complete(ctx context.Context, key, token string, resp *Response, ttl time.Duration) error
+	// Release drops the claim with token without a response, so a retry
+	// runs the request again. Completed keys and later claims are left
+	// alone.
+	Release(ctx context.Context, key, token string) error
+}
@@ -0,0 +1,197 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestMemoryStore_ClaimCompleteReplay(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if record, _ := s.Claim(ctx, "k", "fp", "t1", time.Minute); record != nil {
+		t.Fatalf("Expected the first claim to get the key, got %+v", record)
+	}
+	record, _ := s.Claim(ctx, "k", "fp", "t1", time.Minute)
+	if record == nil || record.Fingerprint != "fp" || record.Response != nil {
+		t.Fatalf("Expected an in-progress record, got %+v", record)
+	}
+
+	resp := &Response{Status: http.StatusCreated, Header: http.Header{"Content-Type": {"application/json"}}, Body: []byte(`{}`)}
+	s.Complete(ctx, "k", "t1", resp, time.Hour)
+	now = now.Add(30 * time.Minute) // past the lease, within the TTL
+	record, _ = s.Claim(ctx, "k", "fp", "t1", time.Minute)
+	if record == nil || record.Response == nil || record.Response.Status != http.StatusCreated {
+		t.Fatalf("Expected the stored response, got %+v", record)
+	}
+
+	now = now.Add(time.Hour)
+	if record, _ := s.Claim(ctx, "k", "fp", "t1", time.Minute); record != nil {
+		t.Errorf("Expected the key to be claimable after the TTL, got %+v", record)
+	}
+}
+
+func TestMemoryStore_ExpiredLeaseCanBeClaimed(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	s.Claim(ctx, "k", "fp", "t1", time.Minute)
+	now = now.Add(time.Minute)
+	if record, _ := s.Claim(ctx, "k", "other", "t2", time.Minute); record != nil {
+		t.Errorf("Expected an abandoned claim to be taken over, got %+v", record)
+	}
+}
+
+func TestMemoryStore_StaleLeaseCannotOverwrite(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	s.Claim(ctx, "k", "fp", "slow", time.Minute)
+	now = now.Add(time.Minute) // the first request outlives its lease
+	s.Claim(ctx, "k", "fp", "retry", time.Minute)
+
+	if err := s.Complete(ctx, "k", "slow", &Response{Status: http.StatusCreated}, time.Hour); err != ErrLeaseLost {
+		t.Errorf("Expected ErrLeaseLost, got %v", err)
+	}
+	s.Release(ctx, "k", "slow")
+	record, _ := s.Claim(ctx, "k", "fp", "third", time.Minute)
+	if record == nil || record.Response != nil {
+		t.Fatalf("Expected the retry to still hold the key, got %+v", record)
+	}
+
+	if err := s.Complete(ctx, "k", "retry", &Response{Status: http.StatusOK}, time.Hour); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if record, _ := s.Claim(ctx, "k", "fp", "fourth", time.Minute); record == nil || record.Response.Status != http.StatusOK {
+		t.Errorf("Expected the retry's response, got %+v", record)
+	}
+}
+
+func TestMemoryStore_ReleaseOnlyDropsClaims(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+
+	s.Claim(ctx, "claimed", "fp", "t1", time.Minute)
+	s.Release(ctx, "claimed", "t1")
+	if record, _ := s.Claim(ctx, "claimed", "fp", "t1", time.Minute); record != nil {
+		t.Errorf("Expected a released key to be claimable, got %+v", record)
+	}
+
+	s.Claim(ctx, "done", "fp", "t1", time.Minute)
+	s.Complete(ctx, "done", "t1", &Response{Status: http.StatusOK}, time.Hour)
+	s.Release(ctx, "done", "t1")
+	if record, _ := s.Claim(ctx, "done", "fp", "t1", time.Minute); record == nil || record.Response == nil {
+		t.Errorf("Expected Release to keep completed keys, got %+v", record)
+	}
+}
+
+func TestMemoryStore_SweepsExpiredKeys(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	s.Claim(context.Background(), "k", "fp", "t1", time.Second)
+	now = now.Add(sweepInterval)
+	s.Claim(context.Background(), "other", "fp", "t2", time.Second)
+
+	if _, ok := s.entries["k"]; ok {
+		t.Error("Expired keys should be swept")
+	}
+}
+
+func newMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to open mock sql db: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+		db.Close()
+	})
+	return db, mock
+}
+
+func TestPostgresStore_Claimed(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO idempotency_keys (key, fingerprint, lease_token, expires_at)`)).
+		WithArgs("k", "fp", "t1", 60.0).
+		WillReturnRows(sqlmock.NewRows([]string{"key"}).AddRow("k"))
+
+	record, err := NewPostgresStore(db).Claim(context.Background(), "k", "fp", "t1", time.Minute)
+	if err != nil || record != nil {
+		t.Errorf("Expected the key to be claimed, got %+v, %v", record, err)
+	}
+}
+
+func TestPostgresStore_ClaimReturnsStoredResponse(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO idempotency_keys`)).WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT fingerprint, status, header, body FROM idempotency_keys WHERE key = $1`)).
+		WithArgs("k").
+		WillReturnRows(sqlmock.NewRows([]string{"fingerprint", "status", "header", "body"}).
+			AddRow("fp", 201, []byte(`{"Location":["/notes/1"]}`), []byte(`{"id":"1"}`)))
+
+	record, err := NewPostgresStore(db).Claim(context.Background(), "k", "fp", "t1", time.Minute)
+	if err != nil {
+		t.Fatalf("Claim failed: %v", err)
+	}
+	if record == nil || record.Response == nil || record.Response.Status != 201 ||
+		record.Response.Header.Get("Location") != "/notes/1" || string(record.Response.Body) != `{"id":"1"}` {
+		t.Errorf("Unexpected record: %+v", record)
+	}
+}
+
+func TestPostgresStore_ClaimInProgress(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO idempotency_keys`)).WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT fingerprint, status, header, body FROM idempotency_keys`)).
+		WillReturnRows(sqlmock.NewRows([]string{"fingerprint", "status", "header", "body"}).AddRow("fp", nil, nil, nil))
+
+	record, err := NewPostgresStore(db).Claim(context.Background(), "k", "fp", "t1", time.Minute)
+	if err != nil {
+		t.Fatalf("Claim failed: %v", err)
+	}
+	if record == nil || record.Fingerprint != "fp" || record.Response != nil {
+		t.Errorf("Expected an in-progress record, got %+v", record)
+	}
+}
+
+func TestPostgresStore_CompleteAndRelease(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE idempotency_keys SET status = $3, header = $4, body = $5, expires_at = now() + make_interval(secs => $6) WHERE key = $1 AND lease_token = $2`)).
+		WithArgs("k", "t1", 201, []byte(`{"Location":["/notes/1"]}`), []byte(`{}`), 3600.0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE idempotency_keys SET status`)).
+		WithArgs("k", "stale", 201, []byte(`{"Location":["/notes/1"]}`), []byte(`{}`), 3600.0).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM idempotency_keys WHERE key = $1 AND lease_token = $2 AND status IS NULL`)).
+		WithArgs("other", "t2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s := NewPostgresStore(db)
+	resp := &Response{Status: 201, Header: http.Header{"Location": {"/notes/1"}}, Body: []byte(`{}`)}
+	if err := s.Complete(context.Background(), "k", "t1", resp, time.Hour); err != nil {
+		t.Errorf("Complete failed: %v", err)
+	}
+	if err := s.Complete(context.Background(), "k", "stale", resp, time.Hour); err != ErrLeaseLost {
+		t.Errorf("Expected ErrLeaseLost for a stale lease, got %v", err)
+	}
+	if err := s.Release(context.Background(), "other", "t2"); err != nil {
+		t.Errorf("Release failed: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often MemoryStore drops expired keys.
+const sweepInterval = time.Minute
+
+// MemoryStore keeps keys in process memory. Retries are only recognized by
+// the instance that served the first request, so use PostgresStore when
+// running more than one replica.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryEntry struct {
+	record  Record
+	token   string
+	expires time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]*memoryEntry),
+		now:     time.Now,
+	}
+}
+
+func (m *MemoryStore) Claim(ctx context.Context, key, fingerprint, token string, lease time.Duration) (*Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		m.sweep(now)
+	}
+
+	if entry, ok := m.entries[key]; ok && entry.expires.After(now) {
+		record := entry.record
+		return &record, nil
+	}
+	m.entries[key] = &memoryEntry{record: Record{Fingerprint: fingerprint}, token: token, expires: now.Add(lease)}
+	return nil, nil
+}
+
+func (m *MemoryStore) Complete(ctx context.Context, key, token string, resp *Response, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok || entry.token != token {
+		return ErrLeaseLost
+	}
+	entry.record.Response = resp
+	entry.expires = m.now().Add(ttl)
+	return nil
+}
+
+func (m *MemoryStore) Release(ctx context.Context, key, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry, ok := m.entries[key]; ok && entry.token == token && entry.record.Response == nil {
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+// sweep drops expired keys, which are indistinguishable from missing ones.
+func (m *MemoryStore) sweep(now time.Time) {
+	for key, entry := range m.entries {
+		if !entry.expires.After(now) {
+			delete(m.entries, key)
+		}
+	}
+	m.lastSweep = now
+}
@@ -0,0 +1,141 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// PostgresStore shares keys between instances through the idempotency_keys
+// table:
+//
+//	CREATE TABLE idempotency_keys (
+//	    key TEXT PRIMARY KEY,
+//	    fingerprint TEXT NOT NULL,
+//	    lease_token TEXT NOT NULL,
+//	    status INTEGER,
+//	    header JSONB,
+//	    body BYTEA,
+//	    expires_at TIMESTAMPTZ NOT NULL
+//	);
+//
+// status is NULL while the key is claimed. Expiry is evaluated against the
+// database clock, so replicas with skewed clocks still agree.
+type PostgresStore struct {
+	db DB
+}
+
+// DB is what PostgresStore needs from a *sql.DB, so a store.Pool can stand
+// in for one.
+type DB interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func NewPostgresStore(db DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// The upsert only takes over an existing key once it has expired; otherwise
+// it matches no row and the key is held by someone else.
+const claimQuery = `INSERT INTO idempotency_keys (key, fingerprint, lease_token, expires_at) VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+ON CONFLICT (key) DO UPDATE SET fingerprint = EXCLUDED.fingerprint, lease_token = EXCLUDED.lease_token, status = NULL, header = NULL, body = NULL, expires_at = EXCLUDED.expires_at
+WHERE idempotency_keys.expires_at <= now()
+RETURNING key`
+
+// claimAttempts bounds the retries of a claim whose competing key vanished
+// between the upsert and the read.
+const claimAttempts = 3
+
+func (p *PostgresStore) Claim(ctx context.Context, key, fingerprint, token string, lease time.Duration) (*Record, error) {
+	for range claimAttempts {
+		err := p.db.QueryRowContext(ctx, claimQuery, key, fingerprint, token, lease.Seconds()).Scan(&key)
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		var record Record
+		var status sql.NullInt64
+		var header, body []byte
+		err = p.db.QueryRowContext(ctx, `SELECT fingerprint, status, header, body FROM idempotency_keys WHERE key = $1 AND expires_at > now()`, key).
+			Scan(&record.Fingerprint, &status, &header, &body)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if status.Valid {
+			record.Response = &Response{Status: int(status.Int64), Body: body}
+			if err := json.Unmarshal(header, &record.Response.Header); err != nil {
+				return nil, fmt.Errorf("invalid stored headers of idempotency key: %w", err)
+			}
+		}
+		return &record, nil
+	}
+	return nil, errors.New("idempotency key changed hands while being claimed")
+}
+
+func (p *PostgresStore) Complete(ctx context.Context, key, token string, resp *Response, ttl time.Duration) error {
+	header, err := json.Marshal(resp.Header)
+	if err != nil {
+		return err
+	}
+	res, err := p.db.ExecContext(ctx, `UPDATE idempotency_keys SET status = $3, header = $4, body = $5, expires_at = now() + make_interval(secs => $6) WHERE key = $1 AND lease_token = $2`,
+		key, token, resp.Status, header, resp.Body, ttl.Seconds())
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (p *PostgresStore) Release(ctx context.Context, key, token string) error {
+	_, err := p.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = $1 AND lease_token = $2 AND status IS NULL`, key, token)
+	return err
+}
+
+// DeleteExpired removes expired keys and returns how many were deleted.
+func (p *PostgresStore) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := p.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// PurgeExpired calls DeleteExpired every interval until ctx is cancelled.
+// Run it in its own goroutine.
+func (p *PostgresStore) PurgeExpired(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := p.DeleteExpired(ctx)
+			if err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Failed to purge idempotency keys", "error", err)
+				continue
+			}
+			if n > 0 {
+				slog.DebugContext(ctx, "Purged idempotency keys", "count", n)
+			}
+		}
+	}
+}
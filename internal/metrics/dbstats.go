@@ -0,0 +1,75 @@
+package metrics
+
+import "database/sql"
+
+// DBStatsCollector exposes the connection pool statistics of one or more
+// *sql.DB, each labelled with its "db" name. Every metric family is written
+// once with a sample per pool, as the exposition format requires.
+type DBStatsCollector struct {
+	dbs []namedDB
+}
+
+type namedDB struct {
+	name string
+	db   interface{ Stats() sql.DBStats }
+}
+
+func NewDBStatsCollector() *DBStatsCollector {
+	return &DBStatsCollector{}
+}
+
+// Add reports db, a *sql.DB or anything else that reports its statistics,
+// under name. Add every pool before registering the collector.
+func (c *DBStatsCollector) Add(name string, db interface{ Stats() sql.DBStats }) {
+	c.dbs = append(c.dbs, namedDB{name: name, db: db})
+}
+
+var dbStatsFamilies = []struct {
+	name, help, typ string
+	value           func(sql.DBStats) float64
+}{
+	{"notes_db_max_open_connections", "Maximum number of open connections to the database.", "gauge",
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+	{"notes_db_open_connections", "The number of established connections both in use and idle.", "gauge",
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+	{"notes_db_in_use_connections", "The number of connections currently in use.", "gauge",
+		func(s sql.DBStats) float64 { return float64(s.InUse) }},
+	{"notes_db_idle_connections", "The number of idle connections.", "gauge",
+		func(s sql.DBStats) float64 { return float64(s.Idle) }},
+	{"notes_db_wait_count_total", "The total number of connections waited for.", "counter",
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+	{"notes_db_wait_duration_seconds_total", "The total time blocked waiting for a new connection.", "counter",
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+	{"notes_db_max_idle_closed_total", "The total number of connections closed due to SetMaxIdleConns.", "counter",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+	{"notes_db_max_idle_time_closed_total", "The total number of connections closed due to SetConnMaxIdleTime.", "counter",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }},
+	{"notes_db_max_lifetime_closed_total", "The total number of connections closed due to SetConnMaxLifetime.", "counter",
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+}
+
+func (c *DBStatsCollector) familyNames() []string {
+	names := make([]string, len(dbStatsFamilies))
+	for i, f := range dbStatsFamilies {
+		names[i] = f.name
+	}
+	return names
+}
+
+func (c *DBStatsCollector) Collect(w *Writer) {
+	if len(c.dbs) == 0 {
+		return
+	}
+	// Read each pool once so the families of a scrape agree.
+	stats := make([]sql.DBStats, len(c.dbs))
+	for i, d := range c.dbs {
+		stats[i] = d.db.Stats()
+	}
+
+	for _, f := range dbStatsFamilies {
+		w.Header(f.name, f.help, f.typ)
+		for i, d := range c.dbs {
+			w.Sample(f.name, f.value(stats[i]), "db", d.name)
+		}
+	}
+}
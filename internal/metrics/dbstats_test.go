@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+type statsFunc func() sql.DBStats
+
+func (f statsFunc) Stats() sql.DBStats { return f() }
+
+func TestDBStatsCollector_SeveralPools(t *testing.T) {
+	reg := NewRegistry()
+	c := NewDBStatsCollector()
+	for name, open := range map[string]int{"primary": 5, "replica-0": 3, "replica-1": 2} {
+		c.Add(name, statsFunc(func() sql.DBStats { return sql.DBStats{OpenConnections: open} }))
+	}
+	reg.Register(c)
+
+	var sb strings.Builder
+	reg.WriteTo(&sb)
+	out := sb.String()
+
+	for _, family := range dbStatsFamilies {
+		if n := strings.Count(out, "# TYPE "+family.name+" "); n != 1 {
+			t.Errorf("Expected one TYPE line for %s, got %d", family.name, n)
+		}
+		if n := strings.Count(out, "# HELP "+family.name+" "); n != 1 {
+			t.Errorf("Expected one HELP line for %s, got %d", family.name, n)
+		}
+	}
+	for _, sample := range []string{
+		`notes_db_open_connections{db="primary"} 5`,
+		`notes_db_open_connections{db="replica-0"} 3`,
+		`notes_db_open_connections{db="replica-1"} 2`,
+	} {
+		if !strings.Contains(out, sample) {
+			t.Errorf("Expected %q in exposition:\n%s", sample, out)
+		}
+	}
+
+	// Samples of a family must follow its header, not another family's.
+	family := out[strings.Index(out, "# TYPE notes_db_open_connections"):]
+	family = family[:strings.Index(family, "# HELP notes_db_in_use_connections")]
+	if strings.Count(family, "notes_db_open_connections{") != 3 {
+		t.Errorf("Expected the three pools grouped under one family:\n%s", family)
+	}
+}
+
+func TestDBStatsCollector_ClaimsFamilyNames(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounterVec("notes_db_wait_count_total", "Help.")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic when a family is already registered")
+		}
+	}()
+	reg.Register(NewDBStatsCollector())
+}
@@ -0,0 +1,353 @@
+// Package metrics is a small, dependency-free implementation of the
+// Prometheus text exposition format (version 0.0.4).
+//
+// Metrics are usually declared as package-level variables with the New*
+// functions, which register them with Default:
+//
+//	var loginsTotal = metrics.NewCounterVec("notes_auth_logins_total", "Login attempts.", "result")
+//
+//	loginsTotal.WithLabelValues("success").Inc()
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefBuckets are the default histogram buckets, in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Collector writes one or more metric families in text format.
+type Collector interface {
+	Collect(w *Writer)
+}
+
+// Registry holds the collectors exposed by a metrics endpoint.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+	names      map[string]bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// Default is the registry used by the package-level constructors.
+var Default = NewRegistry()
+
+// namedCollector is a Collector that declares the metric families it
+// writes, so they are claimed like those of the vectors.
+type namedCollector interface {
+	Collector
+	familyNames() []string
+}
+
+// Register adds c to the registry.
+func (r *Registry) Register(c Collector) {
+	if named, ok := c.(namedCollector); ok {
+		for _, name := range named.familyNames() {
+			r.claim(name)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// Register adds c to the Default registry.
+func Register(c Collector) {
+	Default.Register(c)
+}
+
+func (r *Registry) claim(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[name] {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.names[name] = true
+}
+
+// WriteTo writes every registered metric in text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	mw := &Writer{w: bufio.NewWriter(cw)}
+	for _, c := range collectors {
+		c.Collect(mw)
+	}
+	err := mw.w.Flush()
+	return cw.n, err
+}
+
+// Handler serves the registry at a scrape endpoint.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteTo(w)
+	})
+}
+
+// Handler serves the Default registry.
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Writer formats samples for a Collector.
+type Writer struct {
+	w *bufio.Writer
+}
+
+// Header writes the HELP and TYPE lines of a metric family.
+func (w *Writer) Header(name, help, typ string) {
+	fmt.Fprintf(w.w, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, typ)
+}
+
+// Sample writes one sample. labels alternates names and values.
+func (w *Writer) Sample(name string, value float64, labels ...string) {
+	w.w.WriteString(name)
+	if len(labels) > 0 {
+		w.w.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				w.w.WriteByte(',')
+			}
+			fmt.Fprintf(w.w, "%s=\"%s\"", labels[i], escapeLabel(labels[i+1]))
+		}
+		w.w.WriteByte('}')
+	}
+	w.w.WriteByte(' ')
+	w.w.WriteString(formatFloat(value))
+	w.w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+// series holds the children of a vector keyed by their label values.
+type series[T any] struct {
+	mu       sync.Mutex
+	labels   []string
+	children map[string]*T
+	values   map[string][]string
+	newChild func() *T
+}
+
+func (s *series[T]) get(values []string) *T {
+	if len(values) != len(s.labels) {
+		panic(fmt.Sprintf("metrics: expected %d label values, got %d", len(s.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	child, ok := s.children[key]
+	if !ok {
+		child = s.newChild()
+		s.children[key] = child
+		s.values[key] = append([]string(nil), values...)
+	}
+	return child
+}
+
+// each visits children in a stable order.
+func (s *series[T]) each(fn func(labels []string, child *T)) {
+	s.mu.Lock()
+	keys := make([]string, 0, len(s.children))
+	for k := range s.children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	children := make([]*T, len(keys))
+	values := make([][]string, len(keys))
+	for i, k := range keys {
+		children[i] = s.children[k]
+		values[i] = s.values[k]
+	}
+	s.mu.Unlock()
+
+	for i := range keys {
+		pairs := make([]string, 0, 2*len(s.labels))
+		for j, name := range s.labels {
+			pairs = append(pairs, name, values[i][j])
+		}
+		fn(pairs, children[i])
+	}
+}
+
+func newSeries[T any](labels []string, newChild func() *T) series[T] {
+	return series[T]{
+		labels:   labels,
+		children: make(map[string]*T),
+		values:   make(map[string][]string),
+		newChild: newChild,
+	}
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	mu    sync.Mutex
+	value float64
+}
+
+func (c *Counter) Inc() { c.Add(1) }
+
+// Add increases the counter. Negative values are ignored.
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		return
+	}
+	c.mu.Lock()
+	c.value += v
+	c.mu.Unlock()
+}
+
+func (c *Counter) get() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.value
+}
+
+// CounterVec is a set of counters partitioned by labels.
+type CounterVec struct {
+	name, help string
+	series     series[Counter]
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	r.claim(name)
+	c := &CounterVec{name: name, help: help, series: newSeries(labels, func() *Counter { return &Counter{} })}
+	r.Register(c)
+	return c
+}
+
+// NewCounterVec creates a CounterVec registered with Default.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return Default.NewCounterVec(name, help, labels...)
+}
+
+func (c *CounterVec) WithLabelValues(values ...string) *Counter {
+	return c.series.get(values)
+}
+
+func (c *CounterVec) Collect(w *Writer) {
+	w.Header(c.name, c.help, "counter")
+	c.series.each(func(labels []string, child *Counter) {
+		w.Sample(c.name, child.get(), labels...)
+	})
+}
+
+// Histogram counts observations in cumulative buckets.
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// ObserveDuration records the time elapsed since start, in seconds.
+func (h *Histogram) ObserveDuration(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// HistogramVec is a set of histograms partitioned by labels.
+type HistogramVec struct {
+	name, help string
+	buckets    []float64
+	series     series[Histogram]
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	r.claim(name)
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &HistogramVec{name: name, help: help, buckets: buckets}
+	h.series = newSeries(labels, func() *Histogram {
+		return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+	})
+	r.Register(h)
+	return h
+}
+
+// NewHistogramVec creates a HistogramVec registered with Default.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return Default.NewHistogramVec(name, help, buckets, labels...)
+}
+
+func (h *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return h.series.get(values)
+}
+
+func (h *HistogramVec) Collect(w *Writer) {
+	w.Header(h.name, h.help, "histogram")
+	h.series.each(func(labels []string, child *Histogram) {
+		child.mu.Lock()
+		counts := append([]uint64(nil), child.counts...)
+		sum, count := child.sum, child.count
+		child.mu.Unlock()
+
+		for i, upper := range h.buckets {
+			w.Sample(h.name+"_bucket", float64(counts[i]), withLabel(labels, "le", formatFloat(upper))...)
+		}
+		w.Sample(h.name+"_bucket", float64(count), withLabel(labels, "le", "+Inf")...)
+		w.Sample(h.name+"_sum", sum, labels...)
+		w.Sample(h.name+"_count", float64(count), labels...)
+	})
+}
+
+func withLabel(labels []string, name, value string) []string {
+	return append(append(make([]string, 0, len(labels)+2), labels...), name, value)
+}
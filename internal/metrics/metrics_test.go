@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCounterVec_Exposition(t *testing.T) {
+	reg := NewRegistry()
+	c := reg.NewCounterVec("test_requests_total", "Total requests.", "route", "status")
+
+	c.WithLabelValues("GET /notes", "200").Inc()
+	c.WithLabelValues("GET /notes", "200").Add(2)
+	c.WithLabelValues("POST /notes", "500").Inc()
+
+	var sb strings.Builder
+	reg.WriteTo(&sb)
+
+	expected := `# HELP test_requests_total Total requests.
+# TYPE test_requests_total counter
+test_requests_total{route="GET /notes",status="200"} 3
+test_requests_total{route="POST /notes",status="500"} 1
+`
+	if sb.String() != expected {
+		t.Errorf("Unexpected exposition:\n%s", sb.String())
+	}
+}
+
+func TestHistogramVec_Buckets(t *testing.T) {
+	reg := NewRegistry()
+	h := reg.NewHistogramVec("test_duration_seconds", "Duration.", []float64{0.1, 1}, "op")
+
+	h.WithLabelValues("hash").Observe(0.05)
+	h.WithLabelValues("hash").Observe(0.5)
+	h.WithLabelValues("hash").Observe(2)
+
+	var sb strings.Builder
+	reg.WriteTo(&sb)
+
+	for _, line := range []string{
+		`test_duration_seconds_bucket{op="hash",le="0.1"} 1`,
+		`test_duration_seconds_bucket{op="hash",le="1"} 2`,
+		`test_duration_seconds_bucket{op="hash",le="+Inf"} 3`,
+		`test_duration_seconds_sum{op="hash"} 2.55`,
+		`test_duration_seconds_count{op="hash"} 3`,
+	} {
+		if !strings.Contains(sb.String(), line+"\n") {
+			t.Errorf("Missing line %q in:\n%s", line, sb.String())
+		}
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounterVec("test_total", "Help with \\ backslash.", "path").WithLabelValues("a\"b\nc").Inc()
+
+	var sb strings.Builder
+	reg.WriteTo(&sb)
+
+	if !strings.Contains(sb.String(), `test_total{path="a\"b\nc"} 1`) {
+		t.Errorf("Label not escaped:\n%s", sb.String())
+	}
+	if !strings.Contains(sb.String(), `# HELP test_total Help with \\ backslash.`) {
+		t.Errorf("Help not escaped:\n%s", sb.String())
+	}
+}
+
+func TestRegistry_DuplicatePanics(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounterVec("test_total", "Help.")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic on duplicate metric name")
+		}
+	}()
+	reg.NewCounterVec("test_total", "Help.")
+}
+
+func TestHandler_ContentType(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounterVec("test_total", "Help.").WithLabelValues().Inc()
+
+	w := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected Content-Type %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), "test_total 1\n") {
+		t.Errorf("Unexpected body:\n%s", w.Body.String())
+	}
+}
@@ -0,0 +1,181 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var testFS = fstest.MapFS{
+	"0001_create_things.up.sql":   {Data: []byte("CREATE TABLE things (id INT)")},
+	"0001_create_things.down.sql": {Data: []byte("DROP TABLE things")},
+	"0002_add_name.up.sql":        {Data: []byte("ALTER TABLE things ADD name TEXT")},
+	"0002_add_name.down.sql":      {Data: []byte("ALTER TABLE things DROP name")},
+}
+
+func newTestMigrator(t *testing.T) (*Migrator, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to open mock sql db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	m, err := NewFromFS(db, testFS)
+	if err != nil {
+		t.Fatalf("NewFromFS failed: %v", err)
+	}
+	return m, mock
+}
+
+func expectRecords(mock sqlmock.Sqlmock, migrations ...Migration) {
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migrations`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"version", "checksum", "applied_at"})
+	for _, m := range migrations {
+		rows.AddRow(m.Version, m.Checksum, time.Now())
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, checksum, applied_at FROM schema_migrations`)).WillReturnRows(rows)
+}
+
+func TestLoad_EmbeddedMigrations(t *testing.T) {
+	m, err := New(nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	migrations := m.Migrations()
+	if len(migrations) == 0 {
+		t.Fatal("Expected embedded migrations")
+	}
+	for i, migration := range migrations {
+		if migration.Version != int64(i+1) {
+			t.Errorf("Expected contiguous versions, got %d at position %d", migration.Version, i)
+		}
+		if migration.Down == "" {
+			t.Errorf("Migration %d_%s has no down script", migration.Version, migration.Name)
+		}
+	}
+}
+
+func TestLoad_RejectsBadFiles(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"bad name":   {"create_things.up.sql": {Data: []byte("x")}},
+		"missing up": {"0001_things.down.sql": {Data: []byte("x")}},
+		"two names":  {"0001_a.up.sql": {Data: []byte("x")}, "0001_b.down.sql": {Data: []byte("x")}},
+	}
+	for name, fsys := range cases {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestUp_AppliesPendingUnderLock(t *testing.T) {
+	m, mock := newTestMigrator(t)
+	first := m.Migrations()[0]
+
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)).WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectRecords(mock, first)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE things ADD name TEXT`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO schema_migrations`)).
+		WithArgs(int64(2), "add_name", m.Migrations()[1].Checksum).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_unlock($1)`)).WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := m.Up(context.Background())
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != 2 {
+		t.Errorf("Expected only version 2 to be applied, got %+v", applied)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUp_FailedMigrationRollsBack(t *testing.T) {
+	m, mock := newTestMigrator(t)
+
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectRecords(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE things`)).WillReturnError(errors.New("syntax error"))
+	mock.ExpectRollback()
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_unlock($1)`)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if _, err := m.Up(context.Background()); err == nil {
+		t.Fatal("Expected Up to fail")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUp_RefusesModifiedMigration(t *testing.T) {
+	m, mock := newTestMigrator(t)
+	edited := m.Migrations()[0]
+	edited.Checksum = "stale"
+
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectRecords(mock, edited)
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_unlock($1)`)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if _, err := m.Up(context.Background()); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+	}
+}
+
+func TestUp_RefusesUnknownVersion(t *testing.T) {
+	m, mock := newTestMigrator(t)
+
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectRecords(mock, Migration{Version: 99, Checksum: "x"})
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_unlock($1)`)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if _, err := m.Up(context.Background()); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("Expected ErrUnknownVersion, got %v", err)
+	}
+}
+
+func TestDown_RevertsNewestFirst(t *testing.T) {
+	m, mock := newTestMigrator(t)
+
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectRecords(mock, m.Migrations()...)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE things DROP name`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM schema_migrations WHERE version = $1`)).WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_unlock($1)`)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	reverted, err := m.Down(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if len(reverted) != 1 || reverted[0].Version != 2 {
+		t.Errorf("Expected version 2 to be reverted, got %+v", reverted)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	m, mock := newTestMigrator(t)
+	expectRecords(mock, m.Migrations()[0])
+
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(statuses) != 2 || !statuses[0].Applied || statuses[0].Modified || statuses[1].Applied {
+		t.Errorf("Unexpected statuses: %+v", statuses)
+	}
+}
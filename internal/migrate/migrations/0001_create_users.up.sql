@@ -0,0 +1,9 @@
+-- Tables are created without IF NOT EXISTS: a table created by hand before
+-- migrations existed may not match this schema, so migrating such a database
+-- fails loudly instead of recording the version as applied.
+CREATE TABLE users (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email      TEXT NOT NULL UNIQUE,
+    password   TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
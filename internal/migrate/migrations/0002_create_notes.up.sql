@@ -0,0 +1,10 @@
+CREATE TABLE notes (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id    UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    content    TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Serves keyset pagination: WHERE user_id = $1 AND (created_at, id) > (...)
+CREATE INDEX notes_user_id_created_at_id_idx ON notes (user_id, created_at, id);
@@ -0,0 +1,13 @@
+CREATE TABLE device_codes (
+    device_code      TEXT PRIMARY KEY,
+    user_code        TEXT NOT NULL UNIQUE,
+    client_id        TEXT NOT NULL,
+    status           TEXT NOT NULL DEFAULT 'pending',
+    user_id          UUID REFERENCES users (id) ON DELETE CASCADE,
+    interval_seconds INTEGER NOT NULL,
+    expires_at       TIMESTAMPTZ NOT NULL,
+    last_polled_at   TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX device_codes_expires_at_idx ON device_codes (expires_at);
@@ -0,0 +1,7 @@
+-- tat is the theoretical arrival time of the next request (GCRA).
+CREATE TABLE rate_limits (
+    key TEXT PRIMARY KEY,
+    tat TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX rate_limits_tat_idx ON rate_limits (tat);
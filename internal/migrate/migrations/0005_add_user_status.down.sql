@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP COLUMN token_generation,
+    DROP COLUMN disabled_at;
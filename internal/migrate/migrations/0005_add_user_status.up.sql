@@ -0,0 +1,5 @@
+ALTER TABLE users
+    ADD COLUMN disabled_at      TIMESTAMPTZ,
+    -- Tokens carry the generation they were issued in; revoking a user's
+    -- tokens bumps it.
+    ADD COLUMN token_generation BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,14 @@
+-- status, header and body are NULL while the first request is running.
+-- lease_token identifies that request, so one that outlived its lease cannot
+-- complete or release the key after another request re-claimed it.
+CREATE TABLE idempotency_keys (
+    key         TEXT PRIMARY KEY,
+    fingerprint TEXT NOT NULL,
+    lease_token TEXT NOT NULL,
+    status      INTEGER,
+    header      JSONB,
+    body        BYTEA,
+    expires_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often MemoryLimiter drops buckets that have refilled.
+const sweepInterval = time.Minute
+
+// MemoryLimiter keeps buckets in process memory. Limits are enforced per
+// instance, so use PostgresLimiter when running more than one replica.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	tats      map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		tats: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+func (m *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		m.sweep(now)
+	}
+
+	result, tat := decide(now, m.tats[key], limit)
+	if result.Allowed {
+		m.tats[key] = tat
+	}
+	return result, nil
+}
+
+// sweep drops full buckets, which are indistinguishable from missing ones.
+func (m *MemoryLimiter) sweep(now time.Time) {
+	for key, tat := range m.tats {
+		if !tat.After(now) {
+			delete(m.tats, key)
+		}
+	}
+	m.lastSweep = now
+}
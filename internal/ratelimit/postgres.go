@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// PostgresLimiter shares buckets between instances through the rate_limits
+// table:
+//
+//	CREATE TABLE rate_limits (
+//	    key TEXT PRIMARY KEY,
+//	    tat TIMESTAMPTZ NOT NULL
+//	);
+//
+// Each decision is a single atomic upsert evaluated against the database
+// clock, so replicas with skewed clocks still agree.
+type PostgresLimiter struct {
+	db DB
+}
+
+// DB is what PostgresLimiter needs from a *sql.DB, so a store.Pool can
+// stand in for one.
+type DB interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func NewPostgresLimiter(db DB) *PostgresLimiter {
+	return &PostgresLimiter{db: db}
+}
+
+// The upsert only advances the TAT when the event fits within the burst
+// tolerance; otherwise it matches no row and the event is denied.
+const allowQuery = `INSERT INTO rate_limits (key, tat) VALUES ($1, now() + make_interval(secs => $2))
+ON CONFLICT (key) DO UPDATE SET tat = GREATEST(rate_limits.tat, now()) + make_interval(secs => $2)
+WHERE GREATEST(rate_limits.tat, now()) + make_interval(secs => $2) - now() <= make_interval(secs => $3)
+RETURNING tat, now()`
+
+func (p *PostgresLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	interval, tolerance := limit.interval().Seconds(), limit.tolerance().Seconds()
+
+	var tat, now time.Time
+	err := p.db.QueryRowContext(ctx, allowQuery, key, interval, tolerance).Scan(&tat, &now)
+	if err == nil {
+		return allowed(now, tat, limit), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Result{}, err
+	}
+
+	err = p.db.QueryRowContext(ctx, `SELECT tat, now() FROM rate_limits WHERE key = $1`, key).Scan(&tat, &now)
+	if err != nil {
+		return Result{}, err
+	}
+	result, _ := decide(now, tat, limit)
+	return result, nil
+}
+
+// DeleteExpired removes buckets that have fully refilled and returns how many
+// were deleted.
+func (p *PostgresLimiter) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := p.db.ExecContext(ctx, `DELETE FROM rate_limits WHERE tat < now()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// PurgeExpired calls DeleteExpired every interval until ctx is cancelled.
+// Run it in its own goroutine.
+func (p *PostgresLimiter) PurgeExpired(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := p.DeleteExpired(ctx)
+			if err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Failed to purge rate limit buckets", "error", err)
+				continue
+			}
+			if n > 0 {
+				slog.DebugContext(ctx, "Purged rate limit buckets", "count", n)
+			}
+		}
+	}
+}
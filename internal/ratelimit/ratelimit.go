@@ -0,0 +1,122 @@
+// Package ratelimit implements token-bucket rate limiting with in-memory and
+// Postgres-backed storage.
+//
+// Both limiters use the generic cell rate algorithm (GCRA), which behaves
+// exactly like a token bucket refilled at Limit.Rate tokens per second with
+// capacity Limit.Burst, but only needs to store one timestamp per key: the
+// theoretical arrival time (TAT) at which the bucket will be full again.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Rate events per second with bursts of up to Burst events.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Per returns a Limit of n events per period, with a burst of n.
+func Per(n int, period time.Duration) Limit {
+	return Limit{Rate: float64(n) / period.Seconds(), Burst: n}
+}
+
+// ParseLimit parses "<count>/<period>[:<burst>]", e.g. "60/1m" or "10/1s:20".
+// The burst defaults to the count.
+func ParseLimit(s string) (Limit, error) {
+	spec, burstStr, hasBurst := strings.Cut(strings.TrimSpace(s), ":")
+	countStr, periodStr, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <count>/<period>[:<burst>]", s)
+	}
+
+	count, err := strconv.Atoi(countStr)
+	if err != nil || count <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: count must be a positive integer", s)
+	}
+	period, err := time.ParseDuration(periodStr)
+	if err != nil || period <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: period must be a positive duration", s)
+	}
+
+	limit := Per(count, period)
+	if hasBurst {
+		burst, err := strconv.Atoi(burstStr)
+		if err != nil || burst <= 0 {
+			return Limit{}, fmt.Errorf("invalid rate limit %q: burst must be a positive integer", s)
+		}
+		limit.Burst = burst
+	}
+	return limit, nil
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%g/s:%d", l.Rate, l.Burst)
+}
+
+// interval is the time it takes to refill one token.
+func (l Limit) interval() time.Duration {
+	return time.Duration(float64(time.Second) / l.Rate)
+}
+
+// tolerance is the time it takes to refill a whole bucket.
+func (l Limit) tolerance() time.Duration {
+	return time.Duration(l.Burst) * l.interval()
+}
+
+// Result describes the outcome of a call to Allow.
+type Result struct {
+	Allowed bool
+	// Limit is the bucket capacity.
+	Limit int
+	// Remaining is the number of events still allowed right now.
+	Remaining int
+	// ResetAfter is the time until the bucket is full again.
+	ResetAfter time.Duration
+	// RetryAfter is the time until the next event is allowed. Zero when Allowed.
+	RetryAfter time.Duration
+}
+
+// Limiter decides whether the event identified by key may proceed.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// decide applies GCRA to the stored theoretical arrival time. It returns the
+// new TAT to store when the event is allowed.
+func decide(now, tat time.Time, limit Limit) (Result, time.Time) {
+	if tat.Before(now) {
+		tat = now
+	}
+	interval, tolerance := limit.interval(), limit.tolerance()
+	newTAT := tat.Add(interval)
+
+	if newTAT.Sub(now) > tolerance {
+		return Result{
+			Allowed:    false,
+			Limit:      limit.Burst,
+			Remaining:  0,
+			ResetAfter: tat.Sub(now),
+			RetryAfter: newTAT.Add(-tolerance).Sub(now),
+		}, tat
+	}
+
+	return allowed(now, newTAT, limit), newTAT
+}
+
+// allowed describes an allowed event given the TAT after admitting it.
+func allowed(now, tat time.Time, limit Limit) Result {
+	remaining := int(math.Floor(float64(limit.tolerance()-tat.Sub(now)) / float64(limit.interval())))
+	return Result{
+		Allowed:    true,
+		Limit:      limit.Burst,
+		Remaining:  max(remaining, 0),
+		ResetAfter: tat.Sub(now),
+	}
+}
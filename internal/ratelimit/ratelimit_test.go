@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("60/1m")
+	if err != nil {
+		t.Fatalf("ParseLimit failed: %v", err)
+	}
+	if limit.Rate != 1 || limit.Burst != 60 {
+		t.Errorf("Expected 1/s with burst 60, got %s", limit)
+	}
+
+	limit, err = ParseLimit("10/1s:20")
+	if err != nil {
+		t.Fatalf("ParseLimit failed: %v", err)
+	}
+	if limit.Rate != 10 || limit.Burst != 20 {
+		t.Errorf("Expected 10/s with burst 20, got %s", limit)
+	}
+
+	for _, invalid := range []string{"", "10", "0/1m", "10/x", "10/1m:0", "10/-1s"} {
+		if _, err := ParseLimit(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestMemoryLimiter_BurstAndRefill(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+	limit := Per(3, 3*time.Second) // one token per second, burst of 3
+
+	for i := 2; i >= 0; i-- {
+		result, _ := limiter.Allow(context.Background(), "k", limit)
+		if !result.Allowed || result.Remaining != i {
+			t.Fatalf("Expected allowed with %d remaining, got %+v", i, result)
+		}
+	}
+
+	result, _ := limiter.Allow(context.Background(), "k", limit)
+	if result.Allowed {
+		t.Fatal("Expected the fourth request to be denied")
+	}
+	if result.RetryAfter != time.Second || result.ResetAfter != 3*time.Second {
+		t.Errorf("Unexpected retry/reset: %+v", result)
+	}
+
+	if result, _ := limiter.Allow(context.Background(), "other", limit); !result.Allowed {
+		t.Error("Keys should have independent buckets")
+	}
+
+	now = now.Add(time.Second)
+	if result, _ := limiter.Allow(context.Background(), "k", limit); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("Expected one refilled token after a second, got %+v", result)
+	}
+}
+
+func TestMemoryLimiter_SweepsFullBuckets(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow(context.Background(), "k", Per(1, time.Second))
+	now = now.Add(sweepInterval)
+	limiter.Allow(context.Background(), "other", Per(1, time.Second))
+
+	if _, ok := limiter.tats["k"]; ok {
+		t.Error("Refilled buckets should be swept")
+	}
+}
+
+func TestPostgresLimiter_Allowed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to open mock sql db: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO rate_limits (key, tat)`)).
+		WithArgs("k", 1.0, 10.0).
+		WillReturnRows(sqlmock.NewRows([]string{"tat", "now"}).AddRow(now.Add(time.Second), now))
+
+	result, err := NewPostgresLimiter(db).Allow(context.Background(), "k", Per(10, 10*time.Second))
+	if err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+	if !result.Allowed || result.Limit != 10 || result.Remaining != 9 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPostgresLimiter_Denied(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to open mock sql db: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO rate_limits (key, tat)`)).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT tat, now() FROM rate_limits WHERE key = $1`)).
+		WithArgs("k").
+		WillReturnRows(sqlmock.NewRows([]string{"tat", "now"}).AddRow(now.Add(10*time.Second), now))
+
+	result, err := NewPostgresLimiter(db).Allow(context.Background(), "k", Per(10, 10*time.Second))
+	if err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+	if result.Allowed || result.Remaining != 0 || result.RetryAfter != time.Second {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
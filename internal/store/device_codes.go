@@ -0,0 +1,169 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	DeviceCodePending  = "pending"
+	DeviceCodeApproved = "approved"
+	DeviceCodeDenied   = "denied"
+)
+
+// DeviceCode is a pending OAuth2 device authorization (RFC 8628).
+type DeviceCode struct {
+	DeviceCode   string    `json:"device_code"`
+	UserCode     string    `json:"user_code"`
+	ClientID     string    `json:"client_id"`
+	Status       string    `json:"status"`
+	UserID       string    `json:"user_id,omitempty"`
+	Interval     int       `json:"interval"` // minimum polling interval in seconds
+	ExpiresAt    time.Time `json:"expires_at"`
+	LastPolledAt time.Time `json:"last_polled_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Expired reports whether the code can no longer be approved or exchanged.
+func (d *DeviceCode) Expired(now time.Time) bool {
+	return !now.Before(d.ExpiresAt)
+}
+
+type DeviceCodeStorer interface {
+	CreateDeviceCode(ctx context.Context, code *DeviceCode) error
+	GetDeviceCode(ctx context.Context, deviceCode string) (*DeviceCode, error)
+	GetDeviceCodeByUserCode(ctx context.Context, userCode string) (*DeviceCode, error)
+	UpdateDeviceCodeStatus(ctx context.Context, userCode, status, userID string) error
+	TouchDeviceCode(ctx context.Context, deviceCode string, polledAt time.Time, interval int) error
+	DeleteDeviceCode(ctx context.Context, deviceCode string) error
+	DeleteExpiredDeviceCodes(ctx context.Context, before time.Time) (int64, error)
+}
+
+func (s *PostgresStore) CreateDeviceCode(ctx context.Context, code *DeviceCode) (err error) {
+	ctx, q := s.startQuery(ctx, "device_codes.create")
+	defer q.end(&err)
+
+	query := `INSERT INTO device_codes (device_code, user_code, client_id, status, interval_seconds, expires_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING created_at`
+
+	err = s.db.QueryRowContext(ctx, query, code.DeviceCode, code.UserCode, code.ClientID, code.Status, code.Interval, code.ExpiresAt).Scan(&code.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code == "23505" { // unique_violation
+				return ErrDuplicateCode
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) GetDeviceCode(ctx context.Context, deviceCode string) (_ *DeviceCode, err error) {
+	ctx, q := s.startQuery(ctx, "device_codes.get")
+	defer q.end(&err)
+
+	query := `SELECT device_code, user_code, client_id, status, COALESCE(user_id::text, ''), interval_seconds, expires_at, COALESCE(last_polled_at, 'epoch'), created_at FROM device_codes WHERE device_code = $1`
+
+	return s.getDeviceCode(ctx, query, deviceCode)
+}
+
+func (s *PostgresStore) GetDeviceCodeByUserCode(ctx context.Context, userCode string) (_ *DeviceCode, err error) {
+	ctx, q := s.startQuery(ctx, "device_codes.get_by_user_code")
+	defer q.end(&err)
+
+	query := `SELECT device_code, user_code, client_id, status, COALESCE(user_id::text, ''), interval_seconds, expires_at, COALESCE(last_polled_at, 'epoch'), created_at FROM device_codes WHERE user_code = $1`
+
+	return s.getDeviceCode(ctx, query, userCode)
+}
+
+// getDeviceCode reads device codes from the primary: polling clients must
+// see an approval as soon as it is made.
+func (s *PostgresStore) getDeviceCode(ctx context.Context, query, arg string) (*DeviceCode, error) {
+	var code DeviceCode
+	err := s.read(ctx, "", func(db dbtx) error {
+		return db.QueryRowContext(ctx, query, arg).Scan(&code.DeviceCode, &code.UserCode, &code.ClientID, &code.Status, &code.UserID, &code.Interval, &code.ExpiresAt, &code.LastPolledAt, &code.CreatedAt)
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &code, nil
+}
+
+// UpdateDeviceCodeStatus records the user's decision on a pending code.
+// Codes that are no longer pending are reported as ErrNotFound.
+func (s *PostgresStore) UpdateDeviceCodeStatus(ctx context.Context, userCode, status, userID string) (err error) {
+	ctx, q := s.startQuery(ctx, "device_codes.update_status")
+	defer q.end(&err)
+
+	query := `UPDATE device_codes SET status = $1, user_id = NULLIF($2, '')::uuid WHERE user_code = $3 AND status = 'pending'`
+
+	res, err := s.db.ExecContext(ctx, query, status, userID, userCode)
+	if err != nil {
+		return err
+	}
+
+	return expectOneRow(res)
+}
+
+func (s *PostgresStore) TouchDeviceCode(ctx context.Context, deviceCode string, polledAt time.Time, interval int) (err error) {
+	ctx, q := s.startQuery(ctx, "device_codes.touch")
+	defer q.end(&err)
+
+	query := `UPDATE device_codes SET last_polled_at = $1, interval_seconds = $2 WHERE device_code = $3`
+
+	res, err := s.db.ExecContext(ctx, query, polledAt, interval, deviceCode)
+	if err != nil {
+		return err
+	}
+
+	return expectOneRow(res)
+}
+
+func (s *PostgresStore) DeleteDeviceCode(ctx context.Context, deviceCode string) (err error) {
+	ctx, q := s.startQuery(ctx, "device_codes.delete")
+	defer q.end(&err)
+
+	query := `DELETE FROM device_codes WHERE device_code = $1`
+
+	res, err := s.db.ExecContext(ctx, query, deviceCode)
+	if err != nil {
+		return err
+	}
+
+	return expectOneRow(res)
+}
+
+// DeleteExpiredDeviceCodes removes codes that expired before the given time
+// and returns how many were deleted.
+func (s *PostgresStore) DeleteExpiredDeviceCodes(ctx context.Context, before time.Time) (_ int64, err error) {
+	ctx, q := s.startQuery(ctx, "device_codes.delete_expired")
+	defer q.end(&err)
+
+	query := `DELETE FROM device_codes WHERE expires_at < $1`
+
+	res, err := s.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// expectOneRow maps an UPDATE or DELETE that matched nothing to ErrNotFound.
+func expectOneRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
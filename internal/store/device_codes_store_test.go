@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestCreateDeviceCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to open mock sql db: %v", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+
+	expiresAt := time.Now().Add(10 * time.Minute)
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO device_codes (device_code, user_code, client_id, status, interval_seconds, expires_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING created_at`)).
+		WithArgs("device-123", "BCDF-GHJK", "cli", DeviceCodePending, 5, expiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+
+	code := &DeviceCode{
+		DeviceCode: "device-123",
+		UserCode:   "BCDF-GHJK",
+		ClientID:   "cli",
+		Status:     DeviceCodePending,
+		Interval:   5,
+		ExpiresAt:  expiresAt,
+	}
+
+	if err := store.CreateDeviceCode(context.Background(), code); err != nil {
+		t.Errorf("CreateDeviceCode failed: %v", err)
+	}
+
+	if code.CreatedAt.IsZero() {
+		t.Error("CreatedAt should be set")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCreateDeviceCode_Duplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to open mock sql db: %v", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO device_codes`)).
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	err = store.CreateDeviceCode(context.Background(), &DeviceCode{DeviceCode: "device-123", UserCode: "BCDF-GHJK"})
+	if err != ErrDuplicateCode {
+		t.Errorf("Expected ErrDuplicateCode, got %v", err)
+	}
+}
+
+func TestGetDeviceCode_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to open mock sql db: %v", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM device_codes WHERE device_code = $1`)).
+		WithArgs("ghost").
+		WillReturnRows(sqlmock.NewRows([]string{"device_code"}))
+
+	_, err = store.GetDeviceCode(context.Background(), "ghost")
+	if err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestUpdateDeviceCodeStatus_NotPending(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to open mock sql db: %v", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE device_codes SET status = $1, user_id = NULLIF($2, '')::uuid WHERE user_code = $3 AND status = 'pending'`)).
+		WithArgs(DeviceCodeApproved, "user-123", "BCDF-GHJK").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = store.UpdateDeviceCodeStatus(context.Background(), "BCDF-GHJK", DeviceCodeApproved, "user-123")
+	if err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/store"
+)
+
+func (s *Store) CreateDeviceCode(ctx context.Context, code *store.DeviceCode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deviceCodes[code.DeviceCode]; ok {
+		return store.ErrDuplicateCode
+	}
+	for _, existing := range s.deviceCodes {
+		if existing.UserCode == code.UserCode {
+			return store.ErrDuplicateCode
+		}
+	}
+	code.CreatedAt = s.timestamp()
+	stored := *code
+	stored.UserID = ""
+	stored.LastPolledAt = epoch
+	s.deviceCodes[code.DeviceCode] = &stored
+	return nil
+}
+
+func (s *Store) GetDeviceCode(ctx context.Context, deviceCode string) (*store.DeviceCode, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	code, ok := s.deviceCodes[deviceCode]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	copied := *code
+	return &copied, nil
+}
+
+func (s *Store) GetDeviceCodeByUserCode(ctx context.Context, userCode string) (*store.DeviceCode, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	code := s.byUserCode(userCode)
+	if code == nil {
+		return nil, store.ErrNotFound
+	}
+	copied := *code
+	return &copied, nil
+}
+
+// UpdateDeviceCodeStatus records the user's decision on a pending code.
+// Codes that are no longer pending are reported as ErrNotFound.
+func (s *Store) UpdateDeviceCodeStatus(ctx context.Context, userCode, status, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code := s.byUserCode(userCode)
+	if code == nil || code.Status != store.DeviceCodePending {
+		return store.ErrNotFound
+	}
+	code.Status, code.UserID = status, userID
+	return nil
+}
+
+func (s *Store) TouchDeviceCode(ctx context.Context, deviceCode string, polledAt time.Time, interval int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code, ok := s.deviceCodes[deviceCode]
+	if !ok {
+		return store.ErrNotFound
+	}
+	code.LastPolledAt, code.Interval = polledAt, interval
+	return nil
+}
+
+func (s *Store) DeleteDeviceCode(ctx context.Context, deviceCode string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deviceCodes[deviceCode]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.deviceCodes, deviceCode)
+	return nil
+}
+
+// DeleteExpiredDeviceCodes removes codes that expired before the given time
+// and returns how many were deleted.
+func (s *Store) DeleteExpiredDeviceCodes(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for key, code := range s.deviceCodes {
+		if code.ExpiresAt.Before(before) {
+			delete(s.deviceCodes, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// byUserCode finds a code by its user code. The caller holds the lock.
+func (s *Store) byUserCode(userCode string) *store.DeviceCode {
+	for _, code := range s.deviceCodes {
+		if code.UserCode == userCode {
+			return code
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// Package memory is an in-memory implementation of the store interfaces
+// with the same semantics as store.PostgresStore. It backs DB_URL=memory://
+// for demos and lets tests run the real handlers without a database. All
+// data is lost when the process exits.
+package memory
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/store"
+)
+
+// Store is safe for concurrent use. Methods return copies, so callers never
+// share memory with the store.
+type Store struct {
+	mu          sync.RWMutex
+	users       map[string]*userRecord // by ID
+	emails      map[string]string      // email to user ID
+	notes       map[string]*store.Note // by ID
+	deviceCodes map[string]*store.DeviceCode
+
+	// now is replaceable in tests.
+	now  func() time.Time
+	last time.Time
+
+	// inTx marks the copy that WithTx passes to its function.
+	inTx bool
+}
+
+type userRecord struct {
+	user store.User
+}
+
+var _ store.Store = (*Store)(nil)
+
+func New() *Store {
+	return &Store{
+		users:       make(map[string]*userRecord),
+		emails:      make(map[string]string),
+		notes:       make(map[string]*store.Note),
+		deviceCodes: make(map[string]*store.DeviceCode),
+		now:         time.Now,
+	}
+}
+
+// timestamp returns the current time at the microsecond precision of
+// Postgres timestamps. Timestamps strictly increase, so notes created in
+// the same microsecond still list in creation order. The caller holds the
+// write lock.
+func (s *Store) timestamp() time.Time {
+	t := s.now().UTC().Truncate(time.Microsecond)
+	if !t.After(s.last) {
+		t = s.last.Add(time.Microsecond)
+	}
+	s.last = t
+	return t
+}
+
+// epoch is what PostgresStore returns for timestamps that were never set.
+var epoch = time.Unix(0, 0).UTC()
+
+// newID returns a random (version 4) UUID, like gen_random_uuid().
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func (s *Store) Create(ctx context.Context, user *store.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.emails[user.Email]; ok {
+		return store.ErrDuplicateEmail
+	}
+	user.ID, user.CreatedAt = newID(), s.timestamp()
+	user.Disabled, user.TokenGeneration = false, 0
+	s.users[user.ID] = &userRecord{user: *user}
+	s.emails[user.Email] = user.ID
+	return nil
+}
+
+func (s *Store) GetByEmail(ctx context.Context, email string) (*store.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.users[s.emails[email]]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	user := rec.user
+	return &user, nil
+}
+
+func (s *Store) GetUserStatus(ctx context.Context, userID string) (*store.UserStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.users[userID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &store.UserStatus{Disabled: rec.user.Disabled, TokenGeneration: rec.user.TokenGeneration}, nil
+}
+
+func (s *Store) SetPassword(ctx context.Context, email, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.users[s.emails[email]]
+	if !ok {
+		return store.ErrNotFound
+	}
+	rec.user.Password = passwordHash
+	rec.user.TokenGeneration++
+	return nil
+}
+
+func (s *Store) DisableUser(ctx context.Context, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.users[s.emails[email]]
+	if !ok {
+		return store.ErrNotFound
+	}
+	rec.user.Disabled = true
+	rec.user.TokenGeneration++
+	return nil
+}
+
+func (s *Store) RevokeTokens(ctx context.Context, email string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if email == "" {
+		for _, rec := range s.users {
+			rec.user.TokenGeneration++
+		}
+		return int64(len(s.users)), nil
+	}
+
+	rec, ok := s.users[s.emails[email]]
+	if !ok {
+		return 0, store.ErrNotFound
+	}
+	rec.user.TokenGeneration++
+	return 1, nil
+}
+
+// WithTx runs fn against a copy of the data while holding the write lock,
+// and keeps the copy only when fn returns nil. Other callers wait until fn
+// returns, so every transaction is serializable whatever opts asks for.
+func (s *Store) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(tx store.Store) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := s.clone()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	s.users, s.emails, s.notes, s.deviceCodes, s.last = tx.users, tx.emails, tx.notes, tx.deviceCodes, tx.last
+	return nil
+}
+
+// clone deep-copies the data into a Store for WithTx. The caller holds the
+// lock.
+func (s *Store) clone() *Store {
+	tx := New()
+	tx.now, tx.last, tx.inTx = s.now, s.last, true
+	for id, rec := range s.users {
+		copied := *rec
+		tx.users[id] = &copied
+	}
+	for email, id := range s.emails {
+		tx.emails[email] = id
+	}
+	for id, note := range s.notes {
+		copied := *note
+		tx.notes[id] = &copied
+	}
+	for code, dc := range s.deviceCodes {
+		copied := *dc
+		tx.deviceCodes[code] = &copied
+	}
+	return tx
+}
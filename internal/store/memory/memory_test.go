@@ -0,0 +1,234 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/store"
+	"github.com/ivan-almanza/notes-api/internal/store/storetest"
+)
+
+func newUser(t *testing.T, s *Store, email string) *store.User {
+	t.Helper()
+	user := &store.User{Email: email, Password: "hash"}
+	if err := s.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	return user
+}
+
+func TestUsers(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+
+	user := newUser(t, s, "ada@example.com")
+	if len(user.ID) != 36 || user.CreatedAt.IsZero() {
+		t.Errorf("Expected a UUID and creation time, got %+v", user)
+	}
+	if err := s.Create(ctx, &store.User{Email: "ada@example.com"}); err != store.ErrDuplicateEmail {
+		t.Errorf("Expected ErrDuplicateEmail, got %v", err)
+	}
+
+	got, err := s.GetByEmail(ctx, "ada@example.com")
+	if err != nil || got.ID != user.ID || got.Password != "hash" {
+		t.Errorf("GetByEmail returned %+v, %v", got, err)
+	}
+	got.Email = "mutated"
+	if again, _ := s.GetByEmail(ctx, "ada@example.com"); again.Email != "ada@example.com" {
+		t.Error("GetByEmail must return a copy")
+	}
+	if _, err := s.GetByEmail(ctx, "ghost@example.com"); err != store.ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestUserStatus(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	user := newUser(t, s, "ada@example.com")
+
+	status, err := s.GetUserStatus(ctx, user.ID)
+	if err != nil || status.Disabled || status.TokenGeneration != 0 {
+		t.Errorf("Expected an active user never revoked, got %+v, %v", status, err)
+	}
+
+	if err := s.DisableUser(ctx, "ada@example.com"); err != nil {
+		t.Fatalf("DisableUser failed: %v", err)
+	}
+	status, _ = s.GetUserStatus(ctx, user.ID)
+	if !status.Disabled || status.TokenGeneration != 1 {
+		t.Errorf("Expected a disabled user with revoked tokens, got %+v", status)
+	}
+	if got, _ := s.GetByEmail(ctx, "ada@example.com"); !got.Disabled {
+		t.Error("Expected GetByEmail to report the user as disabled")
+	}
+
+	newUser(t, s, "bob@example.com")
+	if n, err := s.RevokeTokens(ctx, ""); err != nil || n != 2 {
+		t.Errorf("Expected 2 users revoked, got %d, %v", n, err)
+	}
+	if _, err := s.RevokeTokens(ctx, "ghost@example.com"); err != store.ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := s.SetPassword(ctx, "ghost@example.com", "x"); err != store.ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.GetUserStatus(ctx, "not-a-uuid"); err != store.ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestNotes_Ownership(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	ada := newUser(t, s, "ada@example.com")
+	bob := newUser(t, s, "bob@example.com")
+
+	note := &store.Note{UserID: ada.ID, Content: "secret"}
+	if err := s.CreateNote(ctx, note); err != nil {
+		t.Fatalf("CreateNote failed: %v", err)
+	}
+	if err := s.CreateNote(ctx, &store.Note{UserID: "ghost", Content: "x"}); err == nil {
+		t.Error("Expected an error for a note of an unknown user")
+	}
+
+	if _, err := s.GetNote(ctx, bob.ID, note.ID); err != store.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for another user's note, got %v", err)
+	}
+	if err := s.UpdateNote(ctx, &store.Note{ID: note.ID, UserID: bob.ID, Content: "mine"}); err != store.ErrNotFound {
+		t.Errorf("Expected ErrNotFound updating another user's note, got %v", err)
+	}
+	if err := s.DeleteNote(ctx, bob.ID, note.ID); err != store.ErrNotFound {
+		t.Errorf("Expected ErrNotFound deleting another user's note, got %v", err)
+	}
+
+	update := &store.Note{ID: note.ID, UserID: ada.ID, Content: "updated"}
+	if err := s.UpdateNote(ctx, update); err != nil {
+		t.Fatalf("UpdateNote failed: %v", err)
+	}
+	if !update.UpdatedAt.After(update.CreatedAt) {
+		t.Errorf("Expected updated_at after created_at, got %+v", update)
+	}
+	if got, _ := s.GetNote(ctx, ada.ID, note.ID); got.Content != "updated" {
+		t.Errorf("Expected updated content, got %q", got.Content)
+	}
+
+	if err := s.DeleteNote(ctx, ada.ID, note.ID); err != nil {
+		t.Fatalf("DeleteNote failed: %v", err)
+	}
+	if _, err := s.GetNote(ctx, ada.ID, note.ID); err != store.ErrNotFound {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestNotes_PaginationAndSearch(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	// A frozen clock: timestamps must still increase.
+	frozen := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return frozen }
+	user := newUser(t, s, "ada@example.com")
+
+	for i := 0; i < 5; i++ {
+		s.CreateNote(ctx, &store.Note{UserID: user.ID, Content: fmt.Sprintf("Note %d", i)})
+	}
+
+	var contents []string
+	var after *store.NoteCursor
+	for {
+		page, err := s.ListNotesPage(ctx, user.ID, after, 2)
+		if err != nil {
+			t.Fatalf("ListNotesPage failed: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, n := range page {
+			contents = append(contents, n.Content)
+		}
+		last := page[len(page)-1]
+		after = &store.NoteCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+	if fmt.Sprint(contents) != "[Note 0 Note 1 Note 2 Note 3 Note 4]" {
+		t.Errorf("Expected every note once in creation order, got %v", contents)
+	}
+
+	found, err := s.SearchNotes(ctx, user.ID, "note", 2)
+	if err != nil || len(found) != 2 || found[0].Content != "Note 4" || found[1].Content != "Note 3" {
+		t.Errorf("Expected the two newest notes, got %v, %v", found, err)
+	}
+	if found, _ := s.SearchNotes(ctx, user.ID, "%", 10); len(found) != 0 {
+		t.Errorf("Expected wildcards to match literally, got %v", found)
+	}
+}
+
+func TestDeviceCodes(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	user := newUser(t, s, "ada@example.com")
+	now := time.Now()
+
+	code := &store.DeviceCode{DeviceCode: "dev", UserCode: "ABCD-EFGH", ClientID: "cli", Status: store.DeviceCodePending, Interval: 5, ExpiresAt: now.Add(time.Minute)}
+	if err := s.CreateDeviceCode(ctx, code); err != nil {
+		t.Fatalf("CreateDeviceCode failed: %v", err)
+	}
+	if err := s.CreateDeviceCode(ctx, &store.DeviceCode{DeviceCode: "other", UserCode: "ABCD-EFGH"}); err != store.ErrDuplicateCode {
+		t.Errorf("Expected ErrDuplicateCode, got %v", err)
+	}
+
+	got, err := s.GetDeviceCode(ctx, "dev")
+	if err != nil || !got.LastPolledAt.Equal(epoch) {
+		t.Errorf("Expected a never-polled code, got %+v, %v", got, err)
+	}
+
+	if err := s.UpdateDeviceCodeStatus(ctx, "ABCD-EFGH", store.DeviceCodeApproved, user.ID); err != nil {
+		t.Fatalf("UpdateDeviceCodeStatus failed: %v", err)
+	}
+	if err := s.UpdateDeviceCodeStatus(ctx, "ABCD-EFGH", store.DeviceCodeDenied, user.ID); err != store.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for a decided code, got %v", err)
+	}
+	if got, _ := s.GetDeviceCodeByUserCode(ctx, "ABCD-EFGH"); got.Status != store.DeviceCodeApproved || got.UserID != user.ID {
+		t.Errorf("Expected an approved code, got %+v", got)
+	}
+
+	s.CreateDeviceCode(ctx, &store.DeviceCode{DeviceCode: "old", UserCode: "OLD", ExpiresAt: now.Add(-time.Minute)})
+	if n, _ := s.DeleteExpiredDeviceCodes(ctx, now); n != 1 {
+		t.Errorf("Expected 1 expired code deleted, got %d", n)
+	}
+	if err := s.DeleteDeviceCode(ctx, "dev"); err != nil {
+		t.Errorf("DeleteDeviceCode failed: %v", err)
+	}
+	if err := s.TouchDeviceCode(ctx, "dev", now, 10); err != store.ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	user := newUser(t, s, "ada@example.com")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			note := &store.Note{UserID: user.ID, Content: "x"}
+			s.CreateNote(ctx, note)
+			s.ListNotes(ctx, user.ID)
+			s.UpdateNote(ctx, note)
+		}()
+	}
+	wg.Wait()
+
+	if notes, _ := s.ListNotes(ctx, user.ID); len(notes) != 20 {
+		t.Errorf("Expected 20 notes, got %d", len(notes))
+	}
+}
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store { return New() })
+}
@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ivan-almanza/notes-api/internal/store"
+)
+
+func (s *Store) CreateNote(ctx context.Context, note *store.Note) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Mirrors the foreign key on notes.user_id.
+	if _, ok := s.users[note.UserID]; !ok {
+		return fmt.Errorf("memory: user %q does not exist", note.UserID)
+	}
+	note.ID = newID()
+	note.CreatedAt = s.timestamp()
+	note.UpdatedAt = note.CreatedAt
+	stored := *note
+	s.notes[note.ID] = &stored
+	return nil
+}
+
+func (s *Store) ListNotes(ctx context.Context, userID string) ([]*store.Note, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.userNotes(userID, func(*store.Note) bool { return true }), nil
+}
+
+func (s *Store) ListNotesPage(ctx context.Context, userID string, after *store.NoteCursor, limit int) ([]*store.Note, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	notes := s.userNotes(userID, func(n *store.Note) bool {
+		return after == nil || afterCursor(n, after)
+	})
+	return notes[:min(limit, len(notes))], nil
+}
+
+func (s *Store) GetNote(ctx context.Context, userID, id string) (*store.Note, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	note, ok := s.notes[id]
+	if !ok || note.UserID != userID {
+		return nil, store.ErrNotFound
+	}
+	copied := *note
+	return &copied, nil
+}
+
+func (s *Store) UpdateNote(ctx context.Context, note *store.Note) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.notes[note.ID]
+	if !ok || stored.UserID != note.UserID {
+		return store.ErrNotFound
+	}
+	stored.Content = note.Content
+	stored.UpdatedAt = s.timestamp()
+	note.CreatedAt, note.UpdatedAt = stored.CreatedAt, stored.UpdatedAt
+	return nil
+}
+
+func (s *Store) DeleteNote(ctx context.Context, userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	note, ok := s.notes[id]
+	if !ok || note.UserID != userID {
+		return store.ErrNotFound
+	}
+	delete(s.notes, id)
+	return nil
+}
+
+func (s *Store) SearchNotes(ctx context.Context, userID, query string, limit int) ([]*store.Note, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query = strings.ToLower(query)
+	notes := s.userNotes(userID, func(n *store.Note) bool {
+		return strings.Contains(strings.ToLower(n.Content), query)
+	})
+	// Newest first, like ORDER BY created_at DESC, id DESC.
+	for i, j := 0, len(notes)-1; i < j; i, j = i+1, j-1 {
+		notes[i], notes[j] = notes[j], notes[i]
+	}
+	return notes[:min(limit, len(notes))], nil
+}
+
+// userNotes returns copies of the user's notes matching keep, ordered by
+// creation time and ID. The caller holds the lock.
+func (s *Store) userNotes(userID string, keep func(*store.Note) bool) []*store.Note {
+	var notes []*store.Note
+	for _, n := range s.notes {
+		if n.UserID == userID && keep(n) {
+			copied := *n
+			notes = append(notes, &copied)
+		}
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		return afterCursor(notes[j], &store.NoteCursor{CreatedAt: notes[i].CreatedAt, ID: notes[i].ID})
+	})
+	return notes
+}
+
+// afterCursor reports whether (created_at, id) > (cursor.CreatedAt, cursor.ID).
+func afterCursor(n *store.Note, cursor *store.NoteCursor) bool {
+	if !n.CreatedAt.Equal(cursor.CreatedAt) {
+		return n.CreatedAt.After(cursor.CreatedAt)
+	}
+	return n.ID > cursor.ID
+}
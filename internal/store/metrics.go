@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/metrics"
+	"github.com/ivan-almanza/notes-api/internal/tracing"
+)
+
+var (
+	queryDuration = metrics.NewHistogramVec(
+		"notes_store_query_duration_seconds",
+		"Latency of PostgresStore queries, by query name.",
+		metrics.DefBuckets,
+		"query",
+	)
+	queryErrors = metrics.NewCounterVec(
+		"notes_store_query_errors_total",
+		"PostgresStore queries that failed, by query name. Expected outcomes like ErrNotFound are not counted.",
+		"query",
+	)
+	txRetries = metrics.NewCounterVec(
+		"notes_store_tx_retries_total",
+		"PostgresStore transactions run again after a serialization failure or deadlock, by SQLSTATE.",
+		"code",
+	)
+)
+
+// query observes a single named statement: its latency and error count as
+// metrics, and a client span for tracing.
+type query struct {
+	name   string
+	start  time.Time
+	span   *tracing.Span
+	cancel context.CancelFunc
+}
+
+// startQuery begins observing a statement. The returned context carries the
+// span and must be passed to the database call. End it with a pointer to
+// the method's named error result:
+//
+//	ctx, q := s.startQuery(ctx, "users.create")
+//	defer q.end(&err)
+func startQuery(ctx context.Context, name string) (context.Context, *query) {
+	ctx, span := tracing.Start(ctx, name,
+		tracing.WithSpanKind(tracing.SpanKindClient),
+		tracing.WithAttributes(
+			tracing.String("db.system", "postgresql"),
+			tracing.String("db.statement.name", name),
+		),
+	)
+	return ctx, &query{name: name, start: time.Now(), span: span}
+}
+
+// startQuery is startQuery bounded by the store's query timeout, which
+// covers the statement and any retries of it.
+func (s *PostgresStore) startQuery(ctx context.Context, name string) (context.Context, *query) {
+	ctx, q := startQuery(ctx, name)
+	if s.queryTimeout > 0 {
+		ctx, q.cancel = context.WithTimeout(ctx, s.queryTimeout)
+	}
+	return ctx, q
+}
+
+func (q *query) end(errp *error) {
+	if q.cancel != nil {
+		q.cancel()
+	}
+	queryDuration.WithLabelValues(q.name).ObserveDuration(q.start)
+
+	switch err := *errp; err {
+	case nil, ErrNotFound, ErrDuplicateEmail, ErrDuplicateCode:
+	default:
+		queryErrors.WithLabelValues(q.name).Inc()
+		q.span.SetError(err)
+	}
+	q.span.End()
+}
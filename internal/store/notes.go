@@ -2,40 +2,175 @@ package store
 
 import (
 	"context"
+	"database/sql"
+	"strings"
 	"time"
+
+	"github.com/ivan-almanza/notes-api/pkg/apitypes"
+	"github.com/lib/pq"
 )
 
-type Note struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"user_id"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+// Note is served by the API as is, so it is defined with the other response
+// bodies in apitypes.
+type Note = apitypes.Note
+
+// NoteCursor is the position of the last note of a page. Pages are ordered
+// by creation time, with the ID breaking ties.
+type NoteCursor struct {
+	CreatedAt time.Time
+	ID        string
 }
 
 type NoteStorer interface {
 	CreateNote(ctx context.Context, note *Note) error
 	ListNotes(ctx context.Context, userID string) ([]*Note, error)
+	// ListNotesPage returns up to limit notes after the cursor, oldest first.
+	// A nil cursor starts from the beginning.
+	ListNotesPage(ctx context.Context, userID string, after *NoteCursor, limit int) ([]*Note, error)
+	// GetNote, UpdateNote and DeleteNote return ErrNotFound when the note
+	// does not exist or belongs to another user.
+	GetNote(ctx context.Context, userID, id string) (*Note, error)
+	UpdateNote(ctx context.Context, note *Note) error
+	DeleteNote(ctx context.Context, userID, id string) error
+	// SearchNotes returns up to limit notes containing query, ignoring
+	// case, newest first.
+	SearchNotes(ctx context.Context, userID, query string, limit int) ([]*Note, error)
 }
 
-func (s *PostgresStore) CreateNote(ctx context.Context, note *Note) error {
+func (s *PostgresStore) CreateNote(ctx context.Context, note *Note) (err error) {
+	ctx, q := s.startQuery(ctx, "notes.create")
+	defer q.end(&err)
+
 	query := `INSERT INTO notes (user_id, content) VALUES ($1, $2) RETURNING id, created_at, updated_at`
 
-	err := s.db.QueryRowContext(ctx, query, note.UserID, note.Content).Scan(&note.ID, &note.CreatedAt, &note.UpdatedAt)
+	err = s.db.QueryRowContext(ctx, query, note.UserID, note.Content).Scan(&note.ID, &note.CreatedAt, &note.UpdatedAt)
 	if err != nil {
 		return err
 	}
 
+	s.wrote(userKey(note.UserID))
 	return nil
 }
 
-func (s *PostgresStore) ListNotes(ctx context.Context, userID string) ([]*Note, error) {
+func (s *PostgresStore) ListNotes(ctx context.Context, userID string) (_ []*Note, err error) {
+	ctx, q := s.startQuery(ctx, "notes.list")
+	defer q.end(&err)
+
 	query := `SELECT id, user_id, content, created_at, updated_at FROM notes WHERE user_id = $1`
 
-	rows, err := s.db.QueryContext(ctx, query, userID)
+	var notes []*Note
+	err = s.read(ctx, userKey(userID), func(db dbtx) error {
+		rows, err := db.QueryContext(ctx, query, userID)
+		if err != nil {
+			return err
+		}
+		notes, err = scanNotes(rows)
+		return err
+	})
+	return notes, err
+}
+
+func (s *PostgresStore) ListNotesPage(ctx context.Context, userID string, after *NoteCursor, limit int) (_ []*Note, err error) {
+	ctx, q := s.startQuery(ctx, "notes.list_page")
+	defer q.end(&err)
+
+	var notes []*Note
+	err = s.read(ctx, userKey(userID), func(db dbtx) error {
+		var rows *sql.Rows
+		var err error
+		if after == nil {
+			query := `SELECT id, user_id, content, created_at, updated_at FROM notes WHERE user_id = $1 ORDER BY created_at, id LIMIT $2`
+			rows, err = db.QueryContext(ctx, query, userID, limit)
+		} else {
+			query := `SELECT id, user_id, content, created_at, updated_at FROM notes WHERE user_id = $1 AND (created_at, id) > ($2, $3) ORDER BY created_at, id LIMIT $4`
+			rows, err = db.QueryContext(ctx, query, userID, after.CreatedAt, after.ID, limit)
+		}
+		if err != nil {
+			return err
+		}
+		notes, err = scanNotes(rows)
+		return err
+	})
+	return notes, err
+}
+
+func (s *PostgresStore) GetNote(ctx context.Context, userID, id string) (_ *Note, err error) {
+	ctx, q := s.startQuery(ctx, "notes.get")
+	defer q.end(&err)
+
+	query := `SELECT id, user_id, content, created_at, updated_at FROM notes WHERE id = $1 AND user_id = $2`
+
+	note := &Note{}
+	err = s.read(ctx, userKey(userID), func(db dbtx) error {
+		return db.QueryRowContext(ctx, query, id, userID).Scan(&note.ID, &note.UserID, &note.Content, &note.CreatedAt, &note.UpdatedAt)
+	})
 	if err != nil {
-		return nil, err
+		return nil, lookupError(err)
+	}
+	return note, nil
+}
+
+func (s *PostgresStore) UpdateNote(ctx context.Context, note *Note) (err error) {
+	ctx, q := s.startQuery(ctx, "notes.update")
+	defer q.end(&err)
+
+	query := `UPDATE notes SET content = $1, updated_at = now() WHERE id = $2 AND user_id = $3 RETURNING created_at, updated_at`
+
+	err = s.db.QueryRowContext(ctx, query, note.Content, note.ID, note.UserID).Scan(&note.CreatedAt, &note.UpdatedAt)
+	if err != nil {
+		return lookupError(err)
+	}
+	s.wrote(userKey(note.UserID))
+	return nil
+}
+
+func (s *PostgresStore) DeleteNote(ctx context.Context, userID, id string) (err error) {
+	ctx, q := s.startQuery(ctx, "notes.delete")
+	defer q.end(&err)
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM notes WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return lookupError(err)
 	}
+	s.wrote(userKey(userID))
+	return expectOneRow(res)
+}
+
+func (s *PostgresStore) SearchNotes(ctx context.Context, userID, search string, limit int) (_ []*Note, err error) {
+	ctx, q := s.startQuery(ctx, "notes.search")
+	defer q.end(&err)
+
+	query := `SELECT id, user_id, content, created_at, updated_at FROM notes WHERE user_id = $1 AND content ILIKE '%' || $2 || '%' ORDER BY created_at DESC, id DESC LIMIT $3`
+
+	var notes []*Note
+	err = s.read(ctx, userKey(userID), func(db dbtx) error {
+		rows, err := db.QueryContext(ctx, query, userID, escapeLike(search), limit)
+		if err != nil {
+			return err
+		}
+		notes, err = scanNotes(rows)
+		return err
+	})
+	return notes, err
+}
+
+// escapeLike makes LIKE wildcards in s match literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// lookupError maps missing rows and malformed IDs to ErrNotFound.
+func lookupError(err error) error {
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "22P02" { // invalid_text_representation
+		return ErrNotFound
+	}
+	return err
+}
+
+func scanNotes(rows *sql.Rows) ([]*Note, error) {
 	defer rows.Close()
 
 	var notes []*Note
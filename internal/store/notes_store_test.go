@@ -2,11 +2,13 @@ package store
 
 import (
 	"context"
+	"database/sql"
 	"regexp"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 )
 
 func TestCreateNote(t *testing.T) {
@@ -84,3 +86,103 @@ func TestListNotes_Isolation(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestListNotesPage_AfterCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to open mock sql db: %v", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+	cursor := &NoteCursor{CreatedAt: time.Now(), ID: "note-1"}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, user_id, content, created_at, updated_at FROM notes WHERE user_id = $1 AND (created_at, id) > ($2, $3) ORDER BY created_at, id LIMIT $4`)).
+		WithArgs("user-A", cursor.CreatedAt, cursor.ID, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "content", "created_at", "updated_at"}).
+			AddRow("note-2", "user-A", "Second", time.Now(), time.Now()))
+
+	notes, err := store.ListNotesPage(context.Background(), "user-A", cursor, 10)
+	if err != nil {
+		t.Fatalf("ListNotesPage failed: %v", err)
+	}
+	if len(notes) != 1 || notes[0].ID != "note-2" {
+		t.Errorf("Unexpected notes: %+v", notes)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetNote_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to open mock sql db: %v", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, user_id, content, created_at, updated_at FROM notes WHERE id = $1 AND user_id = $2`)).
+		WithArgs("note-1", "user-B").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, user_id, content, created_at, updated_at FROM notes WHERE id = $1 AND user_id = $2`)).
+		WithArgs("not-a-uuid", "user-B").
+		WillReturnError(&pq.Error{Code: "22P02"})
+
+	if _, err := store.GetNote(context.Background(), "user-B", "note-1"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if _, err := store.GetNote(context.Background(), "user-B", "not-a-uuid"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for a malformed ID, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDeleteNote_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to open mock sql db: %v", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM notes WHERE id = $1 AND user_id = $2`)).
+		WithArgs("note-1", "user-B").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := store.DeleteNote(context.Background(), "user-B", "note-1"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSearchNotes_EscapesWildcards(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to open mock sql db: %v", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, user_id, content, created_at, updated_at FROM notes WHERE user_id = $1 AND content ILIKE '%' || $2 || '%' ORDER BY created_at DESC, id DESC LIMIT $3`)).
+		WithArgs("user-A", `100\%`, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "content", "created_at", "updated_at"}))
+
+	if _, err := store.SearchNotes(context.Background(), "user-A", "100%", 20); err != nil {
+		t.Errorf("SearchNotes failed: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+)
+
+// DB is a connection pool: a *sql.DB, or a *Pool that can be replaced while
+// in use.
+type DB interface {
+	dbtx
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	PingContext(ctx context.Context) error
+}
+
+var (
+	_ DB = (*sql.DB)(nil)
+	_ DB = (*Pool)(nil)
+)
+
+// poolRetireDelay is how long a replaced pool stays open, for callers that
+// picked it just before the swap.
+var poolRetireDelay = 5 * time.Second
+
+// Pool wraps a *sql.DB that Replace can swap for another, so rotated
+// database credentials take effect without a restart.
+type Pool struct {
+	db atomic.Pointer[sql.DB]
+}
+
+func NewPool(db *sql.DB) *Pool {
+	p := &Pool{}
+	p.db.Store(db)
+	return p
+}
+
+// DB returns the current *sql.DB.
+func (p *Pool) DB() *sql.DB {
+	return p.db.Load()
+}
+
+// Replace sends new queries to db and closes the previous *sql.DB after
+// poolRetireDelay. Queries and transactions already running on it are not
+// interrupted: Close waits for them to finish.
+func (p *Pool) Replace(db *sql.DB) {
+	old := p.db.Swap(db)
+	if old != nil && old != db {
+		time.AfterFunc(poolRetireDelay, func() { old.Close() })
+	}
+}
+
+// Close closes the current *sql.DB.
+func (p *Pool) Close() error {
+	return p.db.Load().Close()
+}
+
+func (p *Pool) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return p.db.Load().ExecContext(ctx, query, args...)
+}
+
+func (p *Pool) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return p.db.Load().QueryContext(ctx, query, args...)
+}
+
+func (p *Pool) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return p.db.Load().QueryRowContext(ctx, query, args...)
+}
+
+func (p *Pool) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return p.db.Load().BeginTx(ctx, opts)
+}
+
+func (p *Pool) PingContext(ctx context.Context) error {
+	return p.db.Load().PingContext(ctx)
+}
+
+func (p *Pool) Stats() sql.DBStats {
+	return p.db.Load().Stats()
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func setPoolRetireDelay(t *testing.T, d time.Duration) {
+	delay := poolRetireDelay
+	poolRetireDelay = d
+	t.Cleanup(func() { poolRetireDelay = delay })
+}
+
+// waitClosed waits for the pool Replace retired to be closed.
+func waitClosed(t *testing.T, db *sql.DB) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if err := db.Ping(); err != nil && err.Error() == "sql: database is closed" {
+			return
+		}
+	}
+	t.Fatal("Expected the replaced pool to be closed")
+}
+
+func TestPool_ReplaceSendsNewQueriesToNewPool(t *testing.T) {
+	setPoolRetireDelay(t, 0)
+	oldDB, oldMock := newMockDB(t)
+	newDB, newMock := newMockDB(t)
+	pool := NewPool(oldDB)
+	s := NewPostgresStore(pool)
+	ctx := context.Background()
+
+	statusRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"disabled", "token_generation"}).AddRow(false, 0)
+	}
+	oldMock.ExpectQuery("SELECT disabled_at").WillReturnRows(statusRows())
+	newMock.ExpectQuery("SELECT disabled_at").WillReturnRows(statusRows())
+
+	if _, err := s.GetUserStatus(ctx, "user-1"); err != nil {
+		t.Fatalf("GetUserStatus on the old pool failed: %v", err)
+	}
+	pool.Replace(newDB)
+	if _, err := s.GetUserStatus(ctx, "user-1"); err != nil {
+		t.Fatalf("GetUserStatus on the new pool failed: %v", err)
+	}
+	if pool.DB() != newDB {
+		t.Error("Expected DB to return the new pool")
+	}
+	waitClosed(t, oldDB)
+}
+
+func TestPool_ReplaceLetsTransactionsFinish(t *testing.T) {
+	setPoolRetireDelay(t, 0)
+	oldDB, oldMock := newMockDB(t)
+	newDB, _ := newMockDB(t)
+	pool := NewPool(oldDB)
+	s := NewPostgresStore(pool)
+
+	oldMock.ExpectBegin()
+	oldMock.ExpectExec("DELETE FROM notes").WillReturnResult(sqlmock.NewResult(0, 1))
+	oldMock.ExpectCommit()
+
+	err := s.WithTx(context.Background(), nil, func(tx Store) error {
+		pool.Replace(newDB)
+		waitClosed(t, oldDB)
+		return tx.DeleteNote(context.Background(), "user-1", "note-1")
+	})
+	if err != nil {
+		t.Fatalf("Expected the transaction to finish on the replaced pool, got %v", err)
+	}
+}
+
+func TestPool_ReplaceWithSamePoolKeepsItOpen(t *testing.T) {
+	setPoolRetireDelay(t, 0)
+	db, mock := newMockDB(t)
+	pool := NewPool(db)
+
+	pool.Replace(db)
+	time.Sleep(10 * time.Millisecond)
+	mock.ExpectPing()
+	if err := pool.PingContext(context.Background()); err != nil {
+		t.Errorf("Expected the pool to stay open, got %v", err)
+	}
+}
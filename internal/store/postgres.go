@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PostgresOption configures a PostgresStore.
+type PostgresOption func(*PostgresStore)
+
+// WithQueryTimeout cancels a store call's statements when it takes longer
+// than d. Zero means no timeout.
+func WithQueryTimeout(d time.Duration) PostgresOption {
+	return func(s *PostgresStore) { s.queryTimeout = d }
+}
+
+// PoolConfig sizes a Postgres connection pool. Zero values keep the
+// database/sql defaults.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxIdleTime time.Duration
+	ConnMaxLifetime time.Duration
+	// StatementTimeout makes the server cancel statements that run longer,
+	// even when the client has gone away.
+	StatementTimeout time.Duration
+}
+
+// OpenPostgres returns a pool for dsn configured by cfg. Like sql.Open it
+// does not connect; see WaitForPostgres.
+func OpenPostgres(dsn string, cfg PoolConfig) (*sql.DB, error) {
+	if cfg.StatementTimeout > 0 {
+		var err error
+		dsn, err = withRuntimeParam(dsn, "statement_timeout", fmt.Sprint(cfg.StatementTimeout.Milliseconds()))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	return db, nil
+}
+
+// withRuntimeParam adds a server setting to a URL or key=value dsn. lib/pq
+// sends settings it does not know itself to the server at connect time.
+func withRuntimeParam(dsn, name, value string) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", fmt.Errorf("invalid database URL: %w", err)
+		}
+		query := u.Query()
+		query.Set(name, value)
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	}
+	return strings.TrimSpace(dsn + " " + name + "=" + value), nil
+}
+
+// Backoff between WaitForPostgres attempts.
+var (
+	waitInitialDelay = 100 * time.Millisecond
+	waitMaxDelay     = 5 * time.Second
+)
+
+// WaitForPostgres pings db until it answers, doubling the delay between
+// attempts, and gives up with the last error when ctx is done. It lets the
+// API start before the database, as happens under docker compose.
+func WaitForPostgres(ctx context.Context, db *sql.DB) error {
+	delay := waitInitialDelay
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+
+		slog.WarnContext(ctx, "Database is not reachable yet", "attempt", attempt, "retry_in", delay.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, waitMaxDelay)
+	}
+}
@@ -0,0 +1,45 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/ivan-almanza/notes-api/internal/migrate"
+	"github.com/ivan-almanza/notes-api/internal/store"
+	"github.com/ivan-almanza/notes-api/internal/store/storetest"
+)
+
+// TestPostgresConformance runs the conformance suite against a real
+// database. It is skipped unless TEST_DB_URL is set, and it empties every
+// table it touches, so never point it at a database you care about:
+//
+//	TEST_DB_URL=postgres://localhost/notes_test?sslmode=disable go test ./internal/store
+func TestPostgresConformance(t *testing.T) {
+	dbURL := os.Getenv("TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	m, err := migrate.New(db)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	storetest.Run(t, func(t *testing.T) store.Store {
+		if _, err := db.Exec(`TRUNCATE users, notes, device_codes CASCADE`); err != nil {
+			t.Fatalf("Failed to empty tables: %v", err)
+		}
+		return store.NewPostgresStore(db)
+	})
+}
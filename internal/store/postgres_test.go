@@ -0,0 +1,236 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestWithRuntimeParam(t *testing.T) {
+	cases := map[string]string{
+		"postgres://u:p@db/notes?sslmode=disable": "postgres://u:p@db/notes?sslmode=disable&statement_timeout=5000",
+		"host=db dbname=notes":                    "host=db dbname=notes statement_timeout=5000",
+		"":                                        "statement_timeout=5000",
+	}
+	for dsn, want := range cases {
+		got, err := withRuntimeParam(dsn, "statement_timeout", "5000")
+		if err != nil || got != want {
+			t.Errorf("withRuntimeParam(%q) = %q, %v; want %q", dsn, got, err, want)
+		}
+	}
+}
+
+func setWaitDelays(t *testing.T) {
+	initial, max := waitInitialDelay, waitMaxDelay
+	waitInitialDelay, waitMaxDelay = time.Millisecond, 2*time.Millisecond
+	t.Cleanup(func() { waitInitialDelay, waitMaxDelay = initial, max })
+}
+
+func TestWaitForPostgres_RetriesUntilReachable(t *testing.T) {
+	setWaitDelays(t)
+	db, mock := newMockDB(t)
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	mock.ExpectPing()
+
+	if err := WaitForPostgres(context.Background(), db); err != nil {
+		t.Fatalf("Expected the third ping to succeed, got %v", err)
+	}
+}
+
+func TestWaitForPostgres_GivesUp(t *testing.T) {
+	setWaitDelays(t)
+	db, _ := newMockDB(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := WaitForPostgres(ctx, db); err == nil {
+		t.Fatal("Expected an error once the context is done")
+	}
+}
+
+func setReadRetryDelay(t *testing.T) {
+	delay := readRetryDelay
+	readRetryDelay = 0
+	t.Cleanup(func() { readRetryDelay = delay })
+}
+
+func TestRead_RetriesTransientErrors(t *testing.T) {
+	setReadRetryDelay(t)
+	db, mock := newMockDB(t)
+	s := NewPostgresStore(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(listNotesQuery)).WillReturnError(&pq.Error{Code: "57P01"})
+	mock.ExpectQuery(regexp.QuoteMeta(listNotesQuery)).WillReturnError(&pq.Error{Code: "08006"})
+	mock.ExpectQuery(regexp.QuoteMeta(listNotesQuery)).WillReturnRows(noteRows().AddRow("note-1", "user-1", "hi", time.Now(), time.Now()))
+
+	notes, err := s.ListNotes(context.Background(), "user-1")
+	if err != nil || len(notes) != 1 {
+		t.Fatalf("Expected the third attempt to succeed, got %v, %v", notes, err)
+	}
+}
+
+func TestRead_DoesNotRetryOtherErrors(t *testing.T) {
+	setReadRetryDelay(t)
+	db, mock := newMockDB(t)
+	s := NewPostgresStore(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(listNotesQuery)).WillReturnError(&pq.Error{Code: "42P01"}) // undefined_table
+
+	if _, err := s.ListNotes(context.Background(), "user-1"); err == nil {
+		t.Fatal("Expected the error to be returned")
+	}
+}
+
+func TestRead_GivesUpAfterMaxAttempts(t *testing.T) {
+	setReadRetryDelay(t)
+	db, mock := newMockDB(t)
+	s := NewPostgresStore(db)
+
+	for i := 0; i < maxReadAttempts; i++ {
+		mock.ExpectQuery(regexp.QuoteMeta(listNotesQuery)).WillReturnError(&pq.Error{Code: "53300"})
+	}
+
+	if _, err := s.ListNotes(context.Background(), "user-1"); transientCode(err) != "53300" {
+		t.Fatalf("Expected the last transient error, got %v", err)
+	}
+}
+
+func TestTransientCode(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"connection failure", &pq.Error{Code: "08006"}, "08006"},
+		{"admin shutdown", &pq.Error{Code: "57P01"}, "57P01"},
+		{"too many connections", &pq.Error{Code: "53300"}, "53300"},
+		{"undefined table", &pq.Error{Code: "42P01"}, ""},
+		{"bad connection", driver.ErrBadConn, "bad_conn"},
+		{"wrapped bad connection", fmt.Errorf("list notes: %w", driver.ErrBadConn), "bad_conn"},
+		{"unexpected EOF", io.ErrUnexpectedEOF, "unexpected_eof"},
+		{"connection reset", &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}, "net"},
+		{"deadline exceeded", context.DeadlineExceeded, ""},
+		{"canceled", context.Canceled, ""},
+		{"no rows", errors.New("sql: no rows in result set"), ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := transientCode(tc.err); got != tc.want {
+				t.Errorf("Expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRead_RetriesBrokenConnections(t *testing.T) {
+	setReadRetryDelay(t)
+	db, mock := newMockDB(t)
+	s := NewPostgresStore(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(listNotesQuery)).WillReturnError(io.ErrUnexpectedEOF)
+	mock.ExpectQuery(regexp.QuoteMeta(listNotesQuery)).WillReturnRows(noteRows().AddRow("note-1", "user-1", "hi", time.Now(), time.Now()))
+
+	notes, err := s.ListNotes(context.Background(), "user-1")
+	if err != nil || len(notes) != 1 {
+		t.Fatalf("Expected the second attempt to succeed, got %v, %v", notes, err)
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := NewPostgresStore(db, WithQueryTimeout(10*time.Millisecond))
+
+	mock.ExpectQuery(regexp.QuoteMeta(listNotesQuery)).WillDelayFor(time.Second).WillReturnRows(noteRows())
+
+	start := time.Now()
+	if _, err := s.ListNotes(context.Background(), "user-1"); err == nil {
+		t.Fatal("Expected the query to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the timeout to cancel the query, took %v", elapsed)
+	}
+}
+
+// flakyDB fails queries with the errors in fail, one per query, before
+// passing them on to DB.
+type flakyDB struct {
+	DB
+	fail  []error
+	calls int
+}
+
+func (f *flakyDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	f.calls++
+	if len(f.fail) > 0 {
+		err := f.fail[0]
+		f.fail = f.fail[1:]
+		return nil, err
+	}
+	return f.DB.QueryContext(ctx, query, args...)
+}
+
+func TestRead_RetriesBadConnFromReplica(t *testing.T) {
+	setReadRetryDelay(t)
+	primaryDB, primaryMock := newMockDB(t)
+	replicaDB, _ := newMockDB(t)
+	primary := &flakyDB{DB: primaryDB, fail: []error{driver.ErrBadConn}}
+	replica := &flakyDB{DB: replicaDB, fail: []error{driver.ErrBadConn}}
+	s := NewPostgresStore(primary, WithReplicas([]DB{replica}, time.Minute))
+
+	primaryMock.ExpectQuery(regexp.QuoteMeta(listNotesQuery)).WillReturnRows(noteRows().AddRow("note-1", "user-1", "hi", time.Now(), time.Now()))
+
+	// The replica's bad connection sends the first attempt to the primary,
+	// whose connection is bad too; the retry then reads from the primary.
+	notes, err := s.ListNotes(context.Background(), "user-1")
+	if err != nil || len(notes) != 1 {
+		t.Fatalf("Expected the retry to succeed, got %v, %v", notes, err)
+	}
+	if replica.calls != 1 || primary.calls != 2 {
+		t.Errorf("Expected 1 replica and 2 primary queries, got %d and %d", replica.calls, primary.calls)
+	}
+}
+
+func TestRead_DoesNotRetryDoneContexts(t *testing.T) {
+	setReadRetryDelay(t)
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cases := []struct {
+		name string
+		ctx  context.Context
+		err  error
+	}{
+		{"expired", expired, driver.ErrBadConn},
+		{"canceled", canceled, driver.ErrBadConn},
+		// A deadline inside the driver is a net.Error too, but not a
+		// broken connection.
+		{"deadline error", context.Background(), fmt.Errorf("read: %w", context.DeadlineExceeded)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, _ := newMockDB(t)
+			primary := &flakyDB{DB: db, fail: []error{tc.err, tc.err}}
+			s := NewPostgresStore(primary)
+
+			if _, err := s.ListNotes(tc.ctx, "user-1"); err == nil {
+				t.Error("Expected the error to be returned")
+			}
+			if primary.calls != 1 {
+				t.Errorf("Expected a single query, got %d", primary.calls)
+			}
+		})
+	}
+}
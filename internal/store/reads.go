@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/metrics"
+	"github.com/lib/pq"
+)
+
+var readRetries = metrics.NewCounterVec(
+	"notes_store_read_retries_total",
+	"PostgresStore reads run again after a transient connection error, by SQLSTATE or error kind.",
+	"code",
+)
+
+// maxReadAttempts bounds how often read runs a query that failed with a
+// transient error.
+const maxReadAttempts = 3
+
+// readRetryDelay is multiplied by the attempt number between retries.
+var readRetryDelay = 50 * time.Millisecond
+
+// read runs fn, which must only read, on the database chosen by route for
+// key. Reads outside a transaction that fail with a transient error are run
+// again, since repeating them has no effect.
+func (s *PostgresStore) read(ctx context.Context, key string, fn func(db dbtx) error) error {
+	if _, inTx := s.db.(*sql.Tx); inTx {
+		return fn(s.db)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := s.route(ctx, key, fn)
+		code := transientCode(err)
+		if code == "" || attempt == maxReadAttempts || ctx.Err() != nil {
+			return err
+		}
+		readRetries.WithLabelValues(code).Inc()
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * readRetryDelay):
+		}
+	}
+}
+
+// transientCode returns the SQLSTATE of err when the server lost or refused
+// the connection, so the same query may succeed on a new one, a short name
+// for the kind of error when the connection broke below the protocol, and ""
+// otherwise.
+func transientCode(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code.Class() == "08", // connection_exception
+			pqErr.Code == "57P01", // admin_shutdown
+			pqErr.Code == "57P02", // crash_shutdown
+			pqErr.Code == "57P03", // cannot_connect_now
+			pqErr.Code == "53300": // too_many_connections
+			return string(pqErr.Code)
+		}
+		return ""
+	}
+
+	// context.DeadlineExceeded is also a net.Error, but a read that ran out
+	// of time must not be run again.
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ""
+	}
+	var netErr net.Error
+	switch {
+	case errors.Is(err, driver.ErrBadConn):
+		return "bad_conn"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return "unexpected_eof"
+	case errors.As(err, &netErr):
+		return "net"
+	}
+	return ""
+}
@@ -0,0 +1,184 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/metrics"
+	"github.com/lib/pq"
+)
+
+var replicaReads = metrics.NewCounterVec(
+	"notes_store_replica_reads_total",
+	"PostgresStore read queries by where they ran: replica, primary (no healthy replica or a recent write) or fallback (the replica failed).",
+	"target",
+)
+
+// WithReplicas routes read queries to replicas, round robin among the
+// healthy ones, and to the primary when none is healthy. For window after a
+// user writes, that user's reads stay on the primary so they see their own
+// writes despite replication lag. Writes are only tracked within this
+// process: a read served by another instance may still miss them. Reads
+// that authentication depends on always use the primary.
+//
+// Replicas start out healthy; run MonitorReplicas to keep their health up
+// to date. A replica that fails a query is marked unhealthy and the query
+// is run again on the primary.
+func WithReplicas(replicas []DB, window time.Duration) PostgresOption {
+	return func(s *PostgresStore) {
+		if len(replicas) == 0 {
+			return
+		}
+		rs := &replicaSet{window: window, recentWrites: make(map[string]time.Time)}
+		for _, db := range replicas {
+			r := &replica{db: db}
+			r.healthy.Store(true)
+			rs.replicas = append(rs.replicas, r)
+		}
+		s.replicas = rs
+	}
+}
+
+type replicaSet struct {
+	replicas []*replica
+	next     atomic.Uint64
+	window   time.Duration
+
+	mu           sync.Mutex
+	recentWrites map[string]time.Time // routing key to end of its window
+}
+
+type replica struct {
+	db      DB
+	healthy atomic.Bool
+}
+
+// Routing keys name whose writes a read must see.
+func userKey(userID string) string { return "user:" + userID }
+
+// pick returns a healthy replica, or nil when the read must use the primary.
+func (rs *replicaSet) pick(key string) *replica {
+	rs.mu.Lock()
+	until, ok := rs.recentWrites[key]
+	if ok && !time.Now().Before(until) {
+		delete(rs.recentWrites, key)
+		ok = false
+	}
+	rs.mu.Unlock()
+	if ok {
+		return nil
+	}
+
+	start := rs.next.Add(1)
+	for i := range rs.replicas {
+		r := rs.replicas[(start+uint64(i))%uint64(len(rs.replicas))]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// wrote pins reads for keys to the primary for the window.
+func (rs *replicaSet) wrote(keys ...string) {
+	until := time.Now().Add(rs.window)
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for _, key := range keys {
+		rs.recentWrites[key] = until
+	}
+}
+
+// sweep forgets windows that have ended.
+func (rs *replicaSet) sweep(now time.Time) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for key, until := range rs.recentWrites {
+		if !now.Before(until) {
+			delete(rs.recentWrites, key)
+		}
+	}
+}
+
+// route runs fn on a replica when one may serve key, and on the primary
+// otherwise; an empty key always uses the primary. Errors reported by the
+// replica's server, such as a missing row, are returned as they are. Any
+// other error, including a transient one, is taken as the replica being
+// unavailable: it is marked unhealthy and fn runs again on the primary.
+func (s *PostgresStore) route(ctx context.Context, key string, fn func(db dbtx) error) error {
+	if s.replicas == nil || key == "" {
+		return fn(s.db)
+	}
+
+	r := s.replicas.pick(key)
+	if r == nil {
+		replicaReads.WithLabelValues("primary").Inc()
+		return fn(s.db)
+	}
+
+	err := fn(r.db)
+	var pqErr *pq.Error
+	if err == nil || err == sql.ErrNoRows || ctx.Err() != nil || errors.As(err, &pqErr) && transientCode(err) == "" {
+		replicaReads.WithLabelValues("replica").Inc()
+		return err
+	}
+
+	slog.WarnContext(ctx, "Replica query failed; using the primary", "error", err)
+	r.healthy.Store(false)
+	replicaReads.WithLabelValues("fallback").Inc()
+	return fn(s.db)
+}
+
+// wrote records a write for read-your-writes routing.
+func (s *PostgresStore) wrote(keys ...string) {
+	if s.replicas != nil {
+		s.replicas.wrote(keys...)
+	}
+}
+
+// MonitorReplicas pings every replica each interval, marking it healthy or
+// not, until ctx is done. It does nothing without replicas.
+func (s *PostgresStore) MonitorReplicas(ctx context.Context, interval time.Duration) {
+	if s.replicas == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.replicas.check(ctx, interval)
+
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.replicas.sweep(now)
+		}
+	}
+}
+
+func (rs *replicaSet) check(ctx context.Context, timeout time.Duration) {
+	for i, r := range rs.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := r.db.PingContext(pingCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		healthy := err == nil
+		if r.healthy.Swap(healthy) != healthy {
+			if healthy {
+				slog.InfoContext(ctx, "Replica is healthy again", "replica", i)
+			} else {
+				slog.WarnContext(ctx, "Replica is unhealthy", "replica", i, "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+const listNotesQuery = `SELECT id, user_id, content, created_at, updated_at FROM notes WHERE user_id = $1`
+
+func newMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("Failed to open mock sql db: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+	return db, mock
+}
+
+func newReplicatedStore(t *testing.T, window time.Duration) (*PostgresStore, sqlmock.Sqlmock, sqlmock.Sqlmock) {
+	t.Helper()
+	primary, primaryMock := newMockDB(t)
+	replica, replicaMock := newMockDB(t)
+	return NewPostgresStore(primary, WithReplicas([]DB{replica}, window)), primaryMock, replicaMock
+}
+
+func noteRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "user_id", "content", "created_at", "updated_at"})
+}
+
+func TestReplicas_ReadsGoToReplica(t *testing.T) {
+	s, _, replicaMock := newReplicatedStore(t, time.Minute)
+	replicaMock.ExpectQuery(regexp.QuoteMeta(listNotesQuery)).WithArgs("user-1").WillReturnRows(noteRows())
+
+	if _, err := s.ListNotes(context.Background(), "user-1"); err != nil {
+		t.Fatalf("ListNotes failed: %v", err)
+	}
+}
+
+func TestReplicas_ReadYourWrites(t *testing.T) {
+	ctx := context.Background()
+	s, primaryMock, replicaMock := newReplicatedStore(t, time.Minute)
+
+	primaryMock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO notes`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow("note-1", time.Now(), time.Now()))
+	primaryMock.ExpectQuery(regexp.QuoteMeta(listNotesQuery)).WithArgs("user-1").WillReturnRows(noteRows())
+	replicaMock.ExpectQuery(regexp.QuoteMeta(listNotesQuery)).WithArgs("user-2").WillReturnRows(noteRows())
+
+	if err := s.CreateNote(ctx, &Note{UserID: "user-1", Content: "hello"}); err != nil {
+		t.Fatalf("CreateNote failed: %v", err)
+	}
+	// The writer reads from the primary; other users are unaffected.
+	s.ListNotes(ctx, "user-1")
+	s.ListNotes(ctx, "user-2")
+}
+
+func TestReplicas_WindowEnds(t *testing.T) {
+	ctx := context.Background()
+	s, primaryMock, replicaMock := newReplicatedStore(t, 0)
+
+	primaryMock.ExpectExec(regexp.QuoteMeta(`DELETE FROM notes`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	replicaMock.ExpectQuery(regexp.QuoteMeta(listNotesQuery)).WithArgs("user-1").WillReturnRows(noteRows())
+
+	s.DeleteNote(ctx, "user-1", "note-1")
+	s.ListNotes(ctx, "user-1")
+}
+
+func TestReplicas_FallBackToPrimary(t *testing.T) {
+	ctx := context.Background()
+	s, primaryMock, replicaMock := newReplicatedStore(t, time.Minute)
+
+	replicaMock.ExpectQuery(regexp.QuoteMeta(listNotesQuery)).WillReturnError(errors.New("connection refused"))
+	primaryMock.ExpectQuery(regexp.QuoteMeta(listNotesQuery)).WillReturnRows(noteRows().AddRow("note-1", "user-1", "hi", time.Now(), time.Now()))
+	primaryMock.ExpectQuery(regexp.QuoteMeta(listNotesQuery)).WillReturnRows(noteRows())
+
+	notes, err := s.ListNotes(ctx, "user-1")
+	if err != nil || len(notes) != 1 {
+		t.Fatalf("Expected the primary's result, got %v, %v", notes, err)
+	}
+	// The failed replica is skipped until a health check passes.
+	s.ListNotes(ctx, "user-1")
+}
+
+func TestReplicas_ServerErrorsAreReturned(t *testing.T) {
+	ctx := context.Background()
+	s, _, replicaMock := newReplicatedStore(t, time.Minute)
+
+	replicaMock.ExpectQuery(regexp.QuoteMeta(`SELECT id, user_id`)).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	replicaMock.ExpectQuery(regexp.QuoteMeta(`SELECT id, user_id`)).WillReturnError(&pq.Error{Code: "22P02"})
+
+	if _, err := s.GetNote(ctx, "user-1", "11111111-1111-1111-1111-111111111111"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound from the replica, got %v", err)
+	}
+	if _, err := s.GetNote(ctx, "user-1", "not-a-uuid"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound from the replica, got %v", err)
+	}
+}
+
+func TestReplicas_AuthReadsUsePrimary(t *testing.T) {
+	s, primaryMock, _ := newReplicatedStore(t, time.Minute)
+	primaryMock.ExpectQuery(regexp.QuoteMeta(`SELECT disabled_at IS NOT NULL`)).
+		WillReturnRows(sqlmock.NewRows([]string{"disabled", "token_generation"}).AddRow(false, 0))
+	primaryMock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "disabled", "created_at", "token_generation"}).
+			AddRow("user-1", "ada@example.com", "hash", false, time.Now(), 0))
+
+	if _, err := s.GetUserStatus(context.Background(), "user-1"); err != nil {
+		t.Fatalf("GetUserStatus failed: %v", err)
+	}
+	if _, err := s.GetByEmail(context.Background(), "ada@example.com"); err != nil {
+		t.Fatalf("GetByEmail failed: %v", err)
+	}
+}
+
+func TestReplicas_HealthCheck(t *testing.T) {
+	ctx := context.Background()
+	s, primaryMock, replicaMock := newReplicatedStore(t, time.Minute)
+
+	replicaMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	s.replicas.check(ctx, time.Second)
+	primaryMock.ExpectQuery(regexp.QuoteMeta(listNotesQuery)).WillReturnRows(noteRows())
+	s.ListNotes(ctx, "user-1")
+
+	replicaMock.ExpectPing()
+	s.replicas.check(ctx, time.Second)
+	replicaMock.ExpectQuery(regexp.QuoteMeta(listNotesQuery)).WillReturnRows(noteRows())
+	s.ListNotes(ctx, "user-1")
+}
@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/store"
+)
+
+const deviceCodeColumns = `device_code, user_code, client_id, status, COALESCE(user_id, ''), interval_seconds, expires_at, last_polled_at, created_at`
+
+func (s *Store) CreateDeviceCode(ctx context.Context, code *store.DeviceCode) error {
+	createdAt := s.timestamp()
+
+	query := `INSERT INTO device_codes (device_code, user_code, client_id, status, interval_seconds, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := s.db.ExecContext(ctx, query, code.DeviceCode, code.UserCode, code.ClientID, code.Status, code.Interval, code.ExpiresAt.UnixMicro(), createdAt.UnixMicro())
+	if err != nil {
+		if isUniqueViolation(err) {
+			return store.ErrDuplicateCode
+		}
+		return err
+	}
+
+	code.CreatedAt = createdAt
+	return nil
+}
+
+func (s *Store) GetDeviceCode(ctx context.Context, deviceCode string) (*store.DeviceCode, error) {
+	query := `SELECT ` + deviceCodeColumns + ` FROM device_codes WHERE device_code = ?`
+
+	return scanDeviceCode(s.db.QueryRowContext(ctx, query, deviceCode))
+}
+
+func (s *Store) GetDeviceCodeByUserCode(ctx context.Context, userCode string) (*store.DeviceCode, error) {
+	query := `SELECT ` + deviceCodeColumns + ` FROM device_codes WHERE user_code = ?`
+
+	return scanDeviceCode(s.db.QueryRowContext(ctx, query, userCode))
+}
+
+func scanDeviceCode(row *sql.Row) (*store.DeviceCode, error) {
+	var code store.DeviceCode
+	err := row.Scan(&code.DeviceCode, &code.UserCode, &code.ClientID, &code.Status, &code.UserID, &code.Interval,
+		unixMicros{&code.ExpiresAt}, unixMicros{&code.LastPolledAt}, unixMicros{&code.CreatedAt})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &code, nil
+}
+
+// UpdateDeviceCodeStatus records the user's decision on a pending code.
+// Codes that are no longer pending are reported as store.ErrNotFound.
+func (s *Store) UpdateDeviceCodeStatus(ctx context.Context, userCode, status, userID string) error {
+	query := `UPDATE device_codes SET status = ?, user_id = NULLIF(?, '') WHERE user_code = ? AND status = 'pending'`
+
+	res, err := s.db.ExecContext(ctx, query, status, userID, userCode)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+func (s *Store) TouchDeviceCode(ctx context.Context, deviceCode string, polledAt time.Time, interval int) error {
+	query := `UPDATE device_codes SET last_polled_at = ?, interval_seconds = ? WHERE device_code = ?`
+
+	res, err := s.db.ExecContext(ctx, query, polledAt.UnixMicro(), interval, deviceCode)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+func (s *Store) DeleteDeviceCode(ctx context.Context, deviceCode string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM device_codes WHERE device_code = ?`, deviceCode)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// DeleteExpiredDeviceCodes removes codes that expired before the given time
+// and returns how many were deleted.
+func (s *Store) DeleteExpiredDeviceCodes(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM device_codes WHERE expires_at < ?`, before.UnixMicro())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
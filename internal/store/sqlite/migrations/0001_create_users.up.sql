@@ -0,0 +1,9 @@
+-- Timestamps are Unix microseconds, the precision of Postgres timestamps.
+CREATE TABLE users (
+    id                TEXT PRIMARY KEY,
+    email             TEXT NOT NULL UNIQUE,
+    password          TEXT NOT NULL,
+    disabled_at       INTEGER,
+    token_generation  INTEGER NOT NULL DEFAULT 0,
+    created_at        INTEGER NOT NULL
+);
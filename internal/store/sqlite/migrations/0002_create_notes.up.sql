@@ -0,0 +1,12 @@
+-- seq is the stable rowid that notes_fts refers to; notes are addressed by id.
+CREATE TABLE notes (
+    seq        INTEGER PRIMARY KEY,
+    id         TEXT NOT NULL UNIQUE,
+    user_id    TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    content    TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+-- Serves keyset pagination: WHERE user_id = ? AND (created_at, id) > (?, ?)
+CREATE INDEX notes_user_id_created_at_id_idx ON notes (user_id, created_at, id);
@@ -0,0 +1,13 @@
+CREATE TABLE device_codes (
+    device_code      TEXT PRIMARY KEY,
+    user_code        TEXT NOT NULL UNIQUE,
+    client_id        TEXT NOT NULL,
+    status           TEXT NOT NULL DEFAULT 'pending',
+    user_id          TEXT REFERENCES users (id) ON DELETE CASCADE,
+    interval_seconds INTEGER NOT NULL,
+    expires_at       INTEGER NOT NULL,
+    last_polled_at   INTEGER,
+    created_at       INTEGER NOT NULL
+);
+
+CREATE INDEX device_codes_expires_at_idx ON device_codes (expires_at);
@@ -0,0 +1,21 @@
+-- The trigram tokenizer matches any substring of three or more characters,
+-- ignoring case, like the ILIKE search of the Postgres store.
+CREATE VIRTUAL TABLE notes_fts USING fts5 (
+    content,
+    content = 'notes',
+    content_rowid = 'seq',
+    tokenize = 'trigram'
+);
+
+CREATE TRIGGER notes_fts_insert AFTER INSERT ON notes BEGIN
+    INSERT INTO notes_fts (rowid, content) VALUES (new.seq, new.content);
+END;
+
+CREATE TRIGGER notes_fts_delete AFTER DELETE ON notes BEGIN
+    INSERT INTO notes_fts (notes_fts, rowid, content) VALUES ('delete', old.seq, old.content);
+END;
+
+CREATE TRIGGER notes_fts_update AFTER UPDATE OF content ON notes BEGIN
+    INSERT INTO notes_fts (notes_fts, rowid, content) VALUES ('delete', old.seq, old.content);
+    INSERT INTO notes_fts (rowid, content) VALUES (new.seq, new.content);
+END;
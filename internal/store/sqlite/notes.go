@@ -0,0 +1,147 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"unicode/utf8"
+
+	"modernc.org/sqlite"
+
+	"github.com/ivan-almanza/notes-api/internal/store"
+)
+
+const noteColumns = `id, user_id, content, created_at, updated_at`
+
+// SQLite's lower() only folds ASCII, so short searches use unicode_lower,
+// which lowercases like the other stores do.
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("unicode_lower", 1, unicodeLower)
+}
+
+func unicodeLower(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	switch v := args[0].(type) {
+	case string:
+		return strings.ToLower(v), nil
+	case []byte:
+		return strings.ToLower(string(v)), nil
+	default:
+		return v, nil
+	}
+}
+
+func (s *Store) CreateNote(ctx context.Context, note *store.Note) error {
+	id, now := newID(), s.timestamp()
+
+	query := `INSERT INTO notes (id, user_id, content, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`
+
+	if _, err := s.db.ExecContext(ctx, query, id, note.UserID, note.Content, now.UnixMicro(), now.UnixMicro()); err != nil {
+		return err
+	}
+
+	note.ID, note.CreatedAt, note.UpdatedAt = id, now, now
+	return nil
+}
+
+func (s *Store) ListNotes(ctx context.Context, userID string) ([]*store.Note, error) {
+	query := `SELECT ` + noteColumns + ` FROM notes WHERE user_id = ?`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanNotes(rows)
+}
+
+func (s *Store) ListNotesPage(ctx context.Context, userID string, after *store.NoteCursor, limit int) ([]*store.Note, error) {
+	var rows *sql.Rows
+	var err error
+	if after == nil {
+		query := `SELECT ` + noteColumns + ` FROM notes WHERE user_id = ? ORDER BY created_at, id LIMIT ?`
+		rows, err = s.db.QueryContext(ctx, query, userID, limit)
+	} else {
+		query := `SELECT ` + noteColumns + ` FROM notes WHERE user_id = ? AND (created_at, id) > (?, ?) ORDER BY created_at, id LIMIT ?`
+		rows, err = s.db.QueryContext(ctx, query, userID, after.CreatedAt.UnixMicro(), after.ID, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return scanNotes(rows)
+}
+
+func (s *Store) GetNote(ctx context.Context, userID, id string) (*store.Note, error) {
+	query := `SELECT ` + noteColumns + ` FROM notes WHERE id = ? AND user_id = ?`
+
+	note := &store.Note{}
+	err := s.db.QueryRowContext(ctx, query, id, userID).Scan(&note.ID, &note.UserID, &note.Content, unixMicros{&note.CreatedAt}, unixMicros{&note.UpdatedAt})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrNotFound
+		}
+		return nil, err
+	}
+	return note, nil
+}
+
+func (s *Store) UpdateNote(ctx context.Context, note *store.Note) error {
+	query := `UPDATE notes SET content = ?, updated_at = ? WHERE id = ? AND user_id = ? RETURNING created_at, updated_at`
+
+	err := s.db.QueryRowContext(ctx, query, note.Content, s.timestamp().UnixMicro(), note.ID, note.UserID).Scan(unixMicros{&note.CreatedAt}, unixMicros{&note.UpdatedAt})
+	if err == sql.ErrNoRows {
+		return store.ErrNotFound
+	}
+	return err
+}
+
+func (s *Store) DeleteNote(ctx context.Context, userID, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM notes WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// SearchNotes looks queries up in the notes_fts trigram index. The index
+// cannot match fewer than three characters, so shorter queries scan the
+// user's notes instead.
+func (s *Store) SearchNotes(ctx context.Context, userID, search string, limit int) ([]*store.Note, error) {
+	var rows *sql.Rows
+	var err error
+	if utf8.RuneCountInString(search) >= 3 {
+		query := `SELECT ` + noteColumns + ` FROM notes WHERE user_id = ? AND seq IN (SELECT rowid FROM notes_fts WHERE notes_fts MATCH ?) ORDER BY created_at DESC, id DESC LIMIT ?`
+		rows, err = s.db.QueryContext(ctx, query, userID, ftsPhrase(search), limit)
+	} else {
+		query := `SELECT ` + noteColumns + ` FROM notes WHERE user_id = ? AND instr(unicode_lower(content), ?) > 0 ORDER BY created_at DESC, id DESC LIMIT ?`
+		rows, err = s.db.QueryContext(ctx, query, userID, strings.ToLower(search), limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return scanNotes(rows)
+}
+
+// ftsPhrase quotes s as an FTS5 string, so operators and punctuation in
+// it match literally.
+func ftsPhrase(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func scanNotes(rows *sql.Rows) ([]*store.Note, error) {
+	defer rows.Close()
+
+	var notes []*store.Note
+	for rows.Next() {
+		note := &store.Note{}
+		if err := rows.Scan(&note.ID, &note.UserID, &note.Content, unixMicros{&note.CreatedAt}, unixMicros{&note.UpdatedAt}); err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notes, nil
+}
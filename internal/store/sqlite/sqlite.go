@@ -0,0 +1,234 @@
+// Package sqlite implements the store interfaces on a single SQLite file,
+// using a pure-Go driver so the binary still builds without cgo. It backs
+// DB_URL=sqlite://<path> for small deployments and local development, with
+// the same semantics as store.PostgresStore.
+//
+// The schema is applied when the database is opened: SQLite has no
+// concurrent instances to coordinate, so there is no separate migrate step.
+package sqlite
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"sync"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"github.com/ivan-almanza/notes-api/internal/migrate"
+	"github.com/ivan-almanza/notes-api/internal/store"
+)
+
+//go:embed migrations/*.sql
+var embedded embed.FS
+
+// Store is safe for concurrent use.
+type Store struct {
+	pool *sql.DB
+	// db is the pool, or the transaction of a store passed to WithTx.
+	db    dbtx
+	clock *clock
+}
+
+// dbtx is what the Store methods need from *sql.DB and *sql.Tx.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+var _ store.Store = (*Store)(nil)
+
+// Open opens or creates the database at path and applies pending
+// migrations.
+func Open(ctx context.Context, path string) (*Store, error) {
+	// Foreign keys are off by default in SQLite. Immediate transactions take
+	// the write lock up front, so concurrent writers wait on busy_timeout
+	// instead of failing to upgrade a read lock.
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{pool: db, db: db, clock: &clock{}}
+	if err := s.migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) Close() error {
+	return s.pool.Close()
+}
+
+// Ping checks that the database file is still readable.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.pool.PingContext(ctx)
+}
+
+// WithTx runs fn in a transaction. SQLite transactions are always
+// serializable and writers queue for the single write lock, so fn runs
+// once and opts.Isolation is ignored.
+func (s *Store) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(tx store.Store) error) (err error) {
+	if _, ok := s.db.(*sql.Tx); ok {
+		return fn(s) // already in a transaction
+	}
+
+	tx, err := s.pool.BeginTx(ctx, &sql.TxOptions{ReadOnly: opts != nil && opts.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := fn(&Store{pool: s.pool, db: tx, clock: s.clock}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// migrate applies the embedded migrations newer than PRAGMA user_version,
+// each in its own transaction together with the version bump.
+func (s *Store) migrate(ctx context.Context) error {
+	sub, err := fs.Sub(embedded, "migrations")
+	if err != nil {
+		return err
+	}
+	migrations, err := migrate.Load(sub)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		applied, err := s.applyMigration(ctx, m)
+		if err != nil {
+			return fmt.Errorf("applying %d_%s: %w", m.Version, m.Name, err)
+		}
+		if applied {
+			slog.InfoContext(ctx, "Applied migration", "version", m.Version, "name", m.Name)
+		}
+	}
+	return nil
+}
+
+func (s *Store) applyMigration(ctx context.Context, m migrate.Migration) (bool, error) {
+	tx, err := s.pool.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Read the version under the write lock, in case another process
+	// opened the same file at the same time.
+	var version int64
+	if err := tx.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version); err != nil {
+		return false, err
+	}
+	if version >= m.Version {
+		return false, nil
+	}
+	if _, err := tx.ExecContext(ctx, m.Up); err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d`, m.Version)); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// clock hands out timestamps. It is shared with the stores passed to
+// WithTx.
+type clock struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+// timestamp returns the current time at microsecond precision. Timestamps
+// strictly increase, so notes created in the same microsecond still list in
+// creation order.
+func (s *Store) timestamp() time.Time {
+	c := s.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := time.Now().UTC().Truncate(time.Microsecond)
+	if !t.After(c.last) {
+		t = c.last.Add(time.Microsecond)
+	}
+	c.last = t
+	return t
+}
+
+// unixMicros scans an INTEGER column of Unix microseconds, the storage
+// format of every timestamp. NULL scans as the Unix epoch, like the
+// COALESCE(..., 'epoch') of PostgresStore.
+type unixMicros struct{ t *time.Time }
+
+func (u unixMicros) Scan(value any) error {
+	switch v := value.(type) {
+	case int64:
+		*u.t = time.UnixMicro(v).UTC()
+	case nil:
+		*u.t = time.Unix(0, 0).UTC()
+	default:
+		return fmt.Errorf("sqlite: cannot scan %T into a timestamp", value)
+	}
+	return nil
+}
+
+// newID returns a random (version 4) UUID, like gen_random_uuid().
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// isUniqueViolation reports whether err is a UNIQUE or PRIMARY KEY
+// constraint failure, the SQLite counterpart of Postgres error 23505.
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return true
+	}
+	return false
+}
+
+// expectOneRow maps an UPDATE or DELETE that matched nothing to
+// store.ErrNotFound.
+func expectOneRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ivan-almanza/notes-api/internal/store"
+	"github.com/ivan-almanza/notes-api/internal/store/storetest"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(context.Background(), filepath.Join(t.TempDir(), "notes.db"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store { return openTestStore(t) })
+}
+
+func TestOpen_MigratesOnce(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "notes.db")
+
+	s, err := Open(ctx, path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	user := &store.User{Email: "ada@example.com", Password: "hash"}
+	if err := s.Create(ctx, user); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	s.Close()
+
+	s, err = Open(ctx, path)
+	if err != nil {
+		t.Fatalf("Reopening failed: %v", err)
+	}
+	defer s.Close()
+
+	var version int64
+	s.pool.QueryRow(`PRAGMA user_version`).Scan(&version)
+	if version != 4 {
+		t.Errorf("Expected schema version 4, got %d", version)
+	}
+	if got, err := s.GetByEmail(ctx, "ada@example.com"); err != nil || got.ID != user.ID {
+		t.Errorf("Expected the user to survive reopening, got %+v, %v", got, err)
+	}
+}
+
+func TestSearchNotes_IndexFollowsUpdatesAndDeletes(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t)
+	user := &store.User{Email: "ada@example.com", Password: "hash"}
+	s.Create(ctx, user)
+
+	note := &store.Note{UserID: user.ID, Content: "buy milk"}
+	s.CreateNote(ctx, note)
+	s.UpdateNote(ctx, &store.Note{ID: note.ID, UserID: user.ID, Content: "buy bread"})
+
+	if found, _ := s.SearchNotes(ctx, user.ID, "milk", 10); len(found) != 0 {
+		t.Errorf("Expected the old content to be unindexed, got %v", found)
+	}
+	if found, _ := s.SearchNotes(ctx, user.ID, "BREAD", 10); len(found) != 1 {
+		t.Errorf("Expected the new content to be indexed, got %v", found)
+	}
+
+	s.DeleteNote(ctx, user.ID, note.ID)
+	if found, _ := s.SearchNotes(ctx, user.ID, "bread", 10); len(found) != 0 {
+		t.Errorf("Expected deleted notes to be unindexed, got %v", found)
+	}
+}
+
+func TestCreateNote_RequiresUser(t *testing.T) {
+	s := openTestStore(t)
+	err := s.CreateNote(context.Background(), &store.Note{UserID: "missing", Content: "orphan"})
+	if err == nil {
+		t.Error("Expected the foreign key to reject a note without a user")
+	}
+}
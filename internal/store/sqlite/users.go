@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ivan-almanza/notes-api/internal/store"
+)
+
+func (s *Store) Create(ctx context.Context, user *store.User) error {
+	id, createdAt := newID(), s.timestamp()
+
+	query := `INSERT INTO users (id, email, password, created_at) VALUES (?, ?, ?, ?)`
+
+	_, err := s.db.ExecContext(ctx, query, id, user.Email, user.Password, createdAt.UnixMicro())
+	if err != nil {
+		if isUniqueViolation(err) {
+			return store.ErrDuplicateEmail
+		}
+		return err
+	}
+
+	user.ID, user.CreatedAt = id, createdAt
+	return nil
+}
+
+func (s *Store) GetByEmail(ctx context.Context, email string) (*store.User, error) {
+	query := `SELECT id, email, password, disabled_at IS NOT NULL, created_at, token_generation FROM users WHERE email = ?`
+
+	user := &store.User{}
+	err := s.db.QueryRowContext(ctx, query, email).Scan(&user.ID, &user.Email, &user.Password, &user.Disabled, unixMicros{&user.CreatedAt}, &user.TokenGeneration)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *Store) GetUserStatus(ctx context.Context, userID string) (*store.UserStatus, error) {
+	query := `SELECT disabled_at IS NOT NULL, token_generation FROM users WHERE id = ?`
+
+	status := &store.UserStatus{}
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&status.Disabled, &status.TokenGeneration)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrNotFound
+		}
+		return nil, err
+	}
+	return status, nil
+}
+
+func (s *Store) SetPassword(ctx context.Context, email, passwordHash string) error {
+	query := `UPDATE users SET password = ?, token_generation = token_generation + 1 WHERE email = ?`
+
+	res, err := s.db.ExecContext(ctx, query, passwordHash, email)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+func (s *Store) DisableUser(ctx context.Context, email string) error {
+	query := `UPDATE users SET disabled_at = COALESCE(disabled_at, ?), token_generation = token_generation + 1 WHERE email = ?`
+
+	res, err := s.db.ExecContext(ctx, query, s.timestamp().UnixMicro(), email)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+func (s *Store) RevokeTokens(ctx context.Context, email string) (int64, error) {
+	query := `UPDATE users SET token_generation = token_generation + 1 WHERE ? = '' OR email = ?`
+
+	res, err := s.db.ExecContext(ctx, query, email, email)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 && email != "" {
+		return 0, store.ErrNotFound
+	}
+	return n, nil
+}
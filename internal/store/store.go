@@ -0,0 +1,14 @@
+package store
+
+// Store is every store interface the API needs. PostgresStore,
+// memory.Store and sqlite.Store implement it.
+type Store interface {
+	UserStorer
+	UserStatusStorer
+	UserAdminStorer
+	NoteStorer
+	DeviceCodeStorer
+	Transactor
+}
+
+var _ Store = (*PostgresStore)(nil)
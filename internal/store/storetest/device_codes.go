@@ -0,0 +1,138 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/store"
+)
+
+// RunDeviceCodeStorerTests checks the store.DeviceCodeStorer contract.
+func RunDeviceCodeStorerTests(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+
+	newCode := func(t *testing.T, s store.Store, expiresAt time.Time) *store.DeviceCode {
+		t.Helper()
+		code := &store.DeviceCode{
+			DeviceCode: "device-" + randomHex(8),
+			UserCode:   "USER-" + randomHex(4),
+			ClientID:   "cli",
+			Status:     store.DeviceCodePending,
+			Interval:   5,
+			ExpiresAt:  expiresAt,
+		}
+		if err := s.CreateDeviceCode(ctx, code); err != nil {
+			t.Fatalf("CreateDeviceCode failed: %v", err)
+		}
+		return code
+	}
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		s := newStore(t)
+		code := newCode(t, s, time.Now().Add(time.Hour))
+		if code.CreatedAt.IsZero() {
+			t.Error("Expected a creation time")
+		}
+
+		for name, get := range map[string]func() (*store.DeviceCode, error){
+			"GetDeviceCode":           func() (*store.DeviceCode, error) { return s.GetDeviceCode(ctx, code.DeviceCode) },
+			"GetDeviceCodeByUserCode": func() (*store.DeviceCode, error) { return s.GetDeviceCodeByUserCode(ctx, code.UserCode) },
+		} {
+			got, err := get()
+			if err != nil {
+				t.Fatalf("%s failed: %v", name, err)
+			}
+			if got.DeviceCode != code.DeviceCode || got.UserCode != code.UserCode || got.ClientID != "cli" ||
+				got.Status != store.DeviceCodePending || got.UserID != "" || got.Interval != 5 ||
+				!sameInstant(got.ExpiresAt, code.ExpiresAt) || got.LastPolledAt.After(code.CreatedAt) {
+				t.Errorf("%s: expected %+v, got %+v", name, code, got)
+			}
+		}
+	})
+
+	t.Run("Duplicate", func(t *testing.T) {
+		s := newStore(t)
+		code := newCode(t, s, time.Now().Add(time.Hour))
+
+		sameDevice := *code
+		sameDevice.UserCode = "OTHER-" + randomHex(4)
+		if err := s.CreateDeviceCode(ctx, &sameDevice); err != store.ErrDuplicateCode {
+			t.Errorf("Duplicate device code: expected ErrDuplicateCode, got %v", err)
+		}
+		sameUser := *code
+		sameUser.DeviceCode = "other-" + randomHex(8)
+		if err := s.CreateDeviceCode(ctx, &sameUser); err != store.ErrDuplicateCode {
+			t.Errorf("Duplicate user code: expected ErrDuplicateCode, got %v", err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		s := newStore(t)
+		if _, err := s.GetDeviceCode(ctx, "missing"); err != store.ErrNotFound {
+			t.Errorf("GetDeviceCode: expected ErrNotFound, got %v", err)
+		}
+		if _, err := s.GetDeviceCodeByUserCode(ctx, "MISSING"); err != store.ErrNotFound {
+			t.Errorf("GetDeviceCodeByUserCode: expected ErrNotFound, got %v", err)
+		}
+		if err := s.UpdateDeviceCodeStatus(ctx, "MISSING", store.DeviceCodeDenied, ""); err != store.ErrNotFound {
+			t.Errorf("UpdateDeviceCodeStatus: expected ErrNotFound, got %v", err)
+		}
+		if err := s.TouchDeviceCode(ctx, "missing", time.Now(), 5); err != store.ErrNotFound {
+			t.Errorf("TouchDeviceCode: expected ErrNotFound, got %v", err)
+		}
+		if err := s.DeleteDeviceCode(ctx, "missing"); err != store.ErrNotFound {
+			t.Errorf("DeleteDeviceCode: expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("ApproveOnlyPending", func(t *testing.T) {
+		s := newStore(t)
+		user := createUser(t, s, "ada")
+		code := newCode(t, s, time.Now().Add(time.Hour))
+
+		if err := s.UpdateDeviceCodeStatus(ctx, code.UserCode, store.DeviceCodeApproved, user.ID); err != nil {
+			t.Fatalf("UpdateDeviceCodeStatus failed: %v", err)
+		}
+		got, _ := s.GetDeviceCode(ctx, code.DeviceCode)
+		if got.Status != store.DeviceCodeApproved || got.UserID != user.ID {
+			t.Errorf("Expected the code approved by %s, got %+v", user.ID, got)
+		}
+
+		if err := s.UpdateDeviceCodeStatus(ctx, code.UserCode, store.DeviceCodeDenied, ""); err != store.ErrNotFound {
+			t.Errorf("Expected ErrNotFound once the code is decided, got %v", err)
+		}
+	})
+
+	t.Run("Touch", func(t *testing.T) {
+		s := newStore(t)
+		code := newCode(t, s, time.Now().Add(time.Hour))
+		polledAt := time.Now().Add(time.Minute)
+
+		if err := s.TouchDeviceCode(ctx, code.DeviceCode, polledAt, 10); err != nil {
+			t.Fatalf("TouchDeviceCode failed: %v", err)
+		}
+		got, _ := s.GetDeviceCode(ctx, code.DeviceCode)
+		if !sameInstant(got.LastPolledAt, polledAt) || got.Interval != 10 {
+			t.Errorf("Expected the poll recorded, got %+v", got)
+		}
+	})
+
+	t.Run("DeleteExpired", func(t *testing.T) {
+		s := newStore(t)
+		now := time.Now()
+		expired := newCode(t, s, now.Add(-time.Minute))
+		live := newCode(t, s, now.Add(time.Hour))
+
+		n, err := s.DeleteExpiredDeviceCodes(ctx, now)
+		if err != nil || n != 1 {
+			t.Fatalf("Expected one expired code deleted, got %d, %v", n, err)
+		}
+		if _, err := s.GetDeviceCode(ctx, expired.DeviceCode); err != store.ErrNotFound {
+			t.Errorf("Expected the expired code gone, got %v", err)
+		}
+		if err := s.DeleteDeviceCode(ctx, live.DeviceCode); err != nil {
+			t.Errorf("DeleteDeviceCode failed: %v", err)
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ivan-almanza/notes-api/internal/store"
+)
+
+// RunNoteStorerTests checks the store.NoteStorer contract.
+func RunNoteStorerTests(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		s := newStore(t)
+		user := createUser(t, s, "ada")
+		note := &store.Note{UserID: user.ID, Content: "hello"}
+		if err := s.CreateNote(ctx, note); err != nil {
+			t.Fatalf("CreateNote failed: %v", err)
+		}
+		if note.ID == "" || note.CreatedAt.IsZero() || !sameInstant(note.CreatedAt, note.UpdatedAt) {
+			t.Fatalf("Expected an ID and equal timestamps, got %+v", note)
+		}
+
+		got, err := s.GetNote(ctx, user.ID, note.ID)
+		if err != nil {
+			t.Fatalf("GetNote failed: %v", err)
+		}
+		if got.ID != note.ID || got.UserID != user.ID || got.Content != "hello" || !sameInstant(got.CreatedAt, note.CreatedAt) {
+			t.Errorf("Expected %+v, got %+v", note, got)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		s := newStore(t)
+		user := createUser(t, s, "ada")
+		for _, id := range []string{unknownID, "not-a-uuid"} {
+			if _, err := s.GetNote(ctx, user.ID, id); err != store.ErrNotFound {
+				t.Errorf("GetNote(%q): expected ErrNotFound, got %v", id, err)
+			}
+			if err := s.UpdateNote(ctx, &store.Note{ID: id, UserID: user.ID, Content: "x"}); err != store.ErrNotFound {
+				t.Errorf("UpdateNote(%q): expected ErrNotFound, got %v", id, err)
+			}
+			if err := s.DeleteNote(ctx, user.ID, id); err != store.ErrNotFound {
+				t.Errorf("DeleteNote(%q): expected ErrNotFound, got %v", id, err)
+			}
+		}
+	})
+
+	t.Run("OwnershipIsolation", func(t *testing.T) {
+		s := newStore(t)
+		owner := createUser(t, s, "owner")
+		other := createUser(t, s, "other")
+		note := &store.Note{UserID: owner.ID, Content: "secret"}
+		if err := s.CreateNote(ctx, note); err != nil {
+			t.Fatalf("CreateNote failed: %v", err)
+		}
+
+		if _, err := s.GetNote(ctx, other.ID, note.ID); err != store.ErrNotFound {
+			t.Errorf("GetNote: expected ErrNotFound for another user, got %v", err)
+		}
+		if err := s.UpdateNote(ctx, &store.Note{ID: note.ID, UserID: other.ID, Content: "hijacked"}); err != store.ErrNotFound {
+			t.Errorf("UpdateNote: expected ErrNotFound for another user, got %v", err)
+		}
+		if err := s.DeleteNote(ctx, other.ID, note.ID); err != store.ErrNotFound {
+			t.Errorf("DeleteNote: expected ErrNotFound for another user, got %v", err)
+		}
+		if notes, err := s.ListNotes(ctx, other.ID); err != nil || len(notes) != 0 {
+			t.Errorf("ListNotes: expected no notes for another user, got %v, %v", notes, err)
+		}
+		if notes, err := s.ListNotesPage(ctx, other.ID, nil, 10); err != nil || len(notes) != 0 {
+			t.Errorf("ListNotesPage: expected no notes for another user, got %v, %v", notes, err)
+		}
+		if notes, err := s.SearchNotes(ctx, other.ID, "secret", 10); err != nil || len(notes) != 0 {
+			t.Errorf("SearchNotes: expected no notes for another user, got %v, %v", notes, err)
+		}
+
+		got, err := s.GetNote(ctx, owner.ID, note.ID)
+		if err != nil || got.Content != "secret" {
+			t.Errorf("The owner's note must be untouched, got %+v, %v", got, err)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		s := newStore(t)
+		user := createUser(t, s, "ada")
+		note := &store.Note{UserID: user.ID, Content: "draft"}
+		s.CreateNote(ctx, note)
+		created := note.CreatedAt
+
+		update := &store.Note{ID: note.ID, UserID: user.ID, Content: "final"}
+		if err := s.UpdateNote(ctx, update); err != nil {
+			t.Fatalf("UpdateNote failed: %v", err)
+		}
+		if !sameInstant(update.CreatedAt, created) || update.UpdatedAt.Before(created) {
+			t.Errorf("Expected the creation time kept and a later update time, got %+v", update)
+		}
+		if got, _ := s.GetNote(ctx, user.ID, note.ID); got.Content != "final" {
+			t.Errorf("Expected the new content, got %q", got.Content)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		s := newStore(t)
+		user := createUser(t, s, "ada")
+		note := &store.Note{UserID: user.ID, Content: "gone"}
+		s.CreateNote(ctx, note)
+
+		if err := s.DeleteNote(ctx, user.ID, note.ID); err != nil {
+			t.Fatalf("DeleteNote failed: %v", err)
+		}
+		if _, err := s.GetNote(ctx, user.ID, note.ID); err != store.ErrNotFound {
+			t.Errorf("Expected ErrNotFound after delete, got %v", err)
+		}
+		if err := s.DeleteNote(ctx, user.ID, note.ID); err != store.ErrNotFound {
+			t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+		}
+	})
+
+	t.Run("PagesInCreationOrder", func(t *testing.T) {
+		s := newStore(t)
+		user := createUser(t, s, "ada")
+		var want []string
+		for i := 0; i < 5; i++ {
+			note := &store.Note{UserID: user.ID, Content: fmt.Sprintf("note %d", i)}
+			if err := s.CreateNote(ctx, note); err != nil {
+				t.Fatalf("CreateNote failed: %v", err)
+			}
+			want = append(want, note.ID)
+		}
+
+		var got []string
+		var after *store.NoteCursor
+		for pages := 0; ; pages++ {
+			if pages > len(want) {
+				t.Fatal("Pagination did not terminate")
+			}
+			notes, err := s.ListNotesPage(ctx, user.ID, after, 2)
+			if err != nil {
+				t.Fatalf("ListNotesPage failed: %v", err)
+			}
+			if len(notes) > 2 {
+				t.Fatalf("Expected at most 2 notes per page, got %d", len(notes))
+			}
+			if len(notes) == 0 {
+				break
+			}
+			for _, note := range notes {
+				got = append(got, note.ID)
+			}
+			last := notes[len(notes)-1]
+			after = &store.NoteCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+
+		all, err := s.ListNotes(ctx, user.ID)
+		if err != nil || len(all) != len(want) {
+			t.Errorf("ListNotes: expected %d notes, got %d, %v", len(want), len(all), err)
+		}
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		s := newStore(t)
+		user := createUser(t, s, "ada")
+		for _, content := range []string{"Buy MILK", "call mum", "buy oat milk", "100% done", "snake_case", "ÉCOLE", "une école"} {
+			if err := s.CreateNote(ctx, &store.Note{UserID: user.ID, Content: content}); err != nil {
+				t.Fatalf("CreateNote failed: %v", err)
+			}
+		}
+
+		cases := []struct {
+			query string
+			limit int
+			want  []string
+		}{
+			{"milk", 10, []string{"buy oat milk", "Buy MILK"}},
+			{"milk", 1, []string{"buy oat milk"}},
+			{"%", 10, []string{"100% done"}},
+			{"e_c", 10, []string{"snake_case"}},
+			{"nothing", 10, nil},
+			{"é", 10, []string{"une école", "ÉCOLE"}},
+			{"ÉC", 10, []string{"une école", "ÉCOLE"}},
+		}
+		for _, tc := range cases {
+			notes, err := s.SearchNotes(ctx, user.ID, tc.query, tc.limit)
+			if err != nil {
+				t.Fatalf("SearchNotes(%q) failed: %v", tc.query, err)
+			}
+			var got []string
+			for _, note := range notes {
+				got = append(got, note.Content)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("SearchNotes(%q, %d): expected %q, got %q", tc.query, tc.limit, tc.want, got)
+			}
+		}
+	})
+
+	t.Run("ConcurrentCreate", func(t *testing.T) {
+		s := newStore(t)
+		user := createUser(t, s, "ada")
+		const n = 20
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := s.CreateNote(ctx, &store.Note{UserID: user.ID, Content: fmt.Sprintf("note %d", i)}); err != nil {
+					t.Errorf("CreateNote failed: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		notes, err := s.ListNotes(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("ListNotes failed: %v", err)
+		}
+		ids := make(map[string]bool)
+		for _, note := range notes {
+			ids[note.ID] = true
+		}
+		if len(ids) != n {
+			t.Errorf("Expected %d distinct notes, got %d", n, len(ids))
+		}
+	})
+}
@@ -0,0 +1,57 @@
+// Package storetest is a conformance suite for store backends. It checks
+// the behaviour every implementation of the store interfaces must share,
+// such as error values, ownership isolation and ordering, rather than how
+// a backend implements them:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) store.Store { return memory.New() })
+//	}
+package storetest
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/store"
+)
+
+// Factory returns an empty store. It is called once per test, so backends
+// sharing a database must clear it. Tests never run in parallel with each
+// other.
+type Factory func(t *testing.T) store.Store
+
+// Run runs every conformance test.
+func Run(t *testing.T, newStore Factory) {
+	t.Run("UserStorer", func(t *testing.T) { RunUserStorerTests(t, newStore) })
+	t.Run("UserAdminStorer", func(t *testing.T) { RunUserAdminStorerTests(t, newStore) })
+	t.Run("NoteStorer", func(t *testing.T) { RunNoteStorerTests(t, newStore) })
+	t.Run("DeviceCodeStorer", func(t *testing.T) { RunDeviceCodeStorerTests(t, newStore) })
+	t.Run("Transactor", func(t *testing.T) { RunTransactorTests(t, newStore) })
+}
+
+// createUser creates a user with a unique email.
+func createUser(t *testing.T, s store.UserStorer, name string) *store.User {
+	t.Helper()
+	user := &store.User{Email: fmt.Sprintf("%s-%s@example.com", name, randomHex(4)), Password: "hash-" + name}
+	if err := s.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create(%s) failed: %v", user.Email, err)
+	}
+	return user
+}
+
+// unknownID is a well-formed UUID that no backend will have generated.
+const unknownID = "00000000-0000-4000-8000-000000000000"
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return fmt.Sprintf("%x", b)
+}
+
+// sameInstant compares timestamps at the microsecond precision of Postgres.
+func sameInstant(a, b time.Time) bool {
+	return a.Truncate(time.Microsecond).Equal(b.Truncate(time.Microsecond))
+}
@@ -0,0 +1,114 @@
+package storetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/ivan-almanza/notes-api/internal/store"
+)
+
+// RunTransactorTests checks the store.Transactor contract.
+func RunTransactorTests(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+
+	t.Run("Commit", func(t *testing.T) {
+		s := newStore(t)
+		var user *store.User
+		var note *store.Note
+		err := s.WithTx(ctx, nil, func(tx store.Store) error {
+			user = createUser(t, tx, "ada")
+			note = &store.Note{UserID: user.ID, Content: "atomic"}
+			return tx.CreateNote(ctx, note)
+		})
+		if err != nil {
+			t.Fatalf("WithTx failed: %v", err)
+		}
+		if _, err := s.GetNote(ctx, user.ID, note.ID); err != nil {
+			t.Errorf("Expected the note to be committed, got %v", err)
+		}
+	})
+
+	t.Run("RollbackOnError", func(t *testing.T) {
+		s := newStore(t)
+		user := createUser(t, s, "ada")
+		failure := errors.New("boom")
+
+		err := s.WithTx(ctx, nil, func(tx store.Store) error {
+			if err := tx.CreateNote(ctx, &store.Note{UserID: user.ID, Content: "discarded"}); err != nil {
+				return err
+			}
+			if err := tx.DisableUser(ctx, user.Email); err != nil {
+				return err
+			}
+			return failure
+		})
+		if err != failure {
+			t.Fatalf("Expected fn's error, got %v", err)
+		}
+		if notes, _ := s.ListNotes(ctx, user.ID); len(notes) != 0 {
+			t.Errorf("Expected no notes after rollback, got %d", len(notes))
+		}
+		if got, _ := s.GetByEmail(ctx, user.Email); got.Disabled {
+			t.Error("Expected the user to stay enabled after rollback")
+		}
+	})
+
+	t.Run("RollbackOnPanic", func(t *testing.T) {
+		s := newStore(t)
+		user := createUser(t, s, "ada")
+
+		func() {
+			defer func() {
+				if p := recover(); p != "boom" {
+					t.Errorf("Expected the panic to propagate, got %v", p)
+				}
+			}()
+			s.WithTx(ctx, nil, func(tx store.Store) error {
+				tx.CreateNote(ctx, &store.Note{UserID: user.ID, Content: "discarded"})
+				panic("boom")
+			})
+		}()
+
+		if notes, _ := s.ListNotes(ctx, user.ID); len(notes) != 0 {
+			t.Errorf("Expected no notes after a panic, got %d", len(notes))
+		}
+		// The store must still be usable, with no lock or connection held.
+		createUser(t, s, "after-panic")
+	})
+
+	t.Run("NestedJoinsOuter", func(t *testing.T) {
+		s := newStore(t)
+		user := createUser(t, s, "ada")
+
+		s.WithTx(ctx, nil, func(tx store.Store) error {
+			err := tx.WithTx(ctx, nil, func(inner store.Store) error {
+				return inner.CreateNote(ctx, &store.Note{UserID: user.ID, Content: "inner"})
+			})
+			if err != nil {
+				t.Errorf("Nested WithTx failed: %v", err)
+			}
+			return errors.New("abort the outer transaction")
+		})
+
+		if notes, _ := s.ListNotes(ctx, user.ID); len(notes) != 0 {
+			t.Errorf("Expected the inner write to roll back with the outer one, got %d notes", len(notes))
+		}
+	})
+
+	t.Run("Serializable", func(t *testing.T) {
+		s := newStore(t)
+		user := createUser(t, s, "ada")
+
+		err := s.WithTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable}, func(tx store.Store) error {
+			return tx.CreateNote(ctx, &store.Note{UserID: user.ID, Content: "serializable"})
+		})
+		if err != nil {
+			t.Fatalf("WithTx failed: %v", err)
+		}
+		if notes, _ := s.ListNotes(ctx, user.ID); len(notes) != 1 {
+			t.Errorf("Expected one note, got %d", len(notes))
+		}
+	})
+}
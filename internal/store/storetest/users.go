@@ -0,0 +1,169 @@
+package storetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/ivan-almanza/notes-api/internal/store"
+)
+
+// RunUserStorerTests checks the store.UserStorer and store.UserStatusStorer
+// contract.
+func RunUserStorerTests(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+
+	t.Run("CreateAssignsIDAndTimestamp", func(t *testing.T) {
+		s := newStore(t)
+		user := &store.User{Email: "ada@example.com", Password: "hash"}
+		if err := s.Create(ctx, user); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if user.ID == "" || user.CreatedAt.IsZero() {
+			t.Fatalf("Expected an ID and creation time, got %+v", user)
+		}
+
+		got, err := s.GetByEmail(ctx, "ada@example.com")
+		if err != nil {
+			t.Fatalf("GetByEmail failed: %v", err)
+		}
+		if got.ID != user.ID || got.Password != "hash" || got.Disabled || !sameInstant(got.CreatedAt, user.CreatedAt) {
+			t.Errorf("Expected %+v, got %+v", user, got)
+		}
+	})
+
+	t.Run("DuplicateEmail", func(t *testing.T) {
+		s := newStore(t)
+		user := createUser(t, s, "dup")
+		err := s.Create(ctx, &store.User{Email: user.Email, Password: "other"})
+		if err != store.ErrDuplicateEmail {
+			t.Errorf("Expected ErrDuplicateEmail, got %v", err)
+		}
+	})
+
+	t.Run("GetByEmailNotFound", func(t *testing.T) {
+		s := newStore(t)
+		if _, err := s.GetByEmail(ctx, "ghost@example.com"); err != store.ErrNotFound {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("ConcurrentCreateSameEmail", func(t *testing.T) {
+		s := newStore(t)
+		const n = 10
+		errs := make(chan error, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- s.Create(ctx, &store.User{Email: "race@example.com", Password: "hash"})
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		created := 0
+		for err := range errs {
+			switch err {
+			case nil:
+				created++
+			case store.ErrDuplicateEmail:
+			default:
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}
+		if created != 1 {
+			t.Errorf("Expected exactly one user created, got %d", created)
+		}
+	})
+
+	t.Run("StatusOfNewUser", func(t *testing.T) {
+		s := newStore(t)
+		user := createUser(t, s, "status")
+		status, err := s.GetUserStatus(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("GetUserStatus failed: %v", err)
+		}
+		if status.Disabled || status.TokenGeneration != 0 {
+			t.Errorf("Expected an active user with no revocation, got %+v", status)
+		}
+	})
+
+	t.Run("StatusNotFound", func(t *testing.T) {
+		s := newStore(t)
+		for _, id := range []string{unknownID, "not-a-uuid"} {
+			if _, err := s.GetUserStatus(ctx, id); err != store.ErrNotFound {
+				t.Errorf("GetUserStatus(%q): expected ErrNotFound, got %v", id, err)
+			}
+		}
+	})
+}
+
+// RunUserAdminStorerTests checks the store.UserAdminStorer contract.
+func RunUserAdminStorerTests(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+
+	t.Run("SetPasswordRevokesTokens", func(t *testing.T) {
+		s := newStore(t)
+		user := createUser(t, s, "reset")
+		if err := s.SetPassword(ctx, user.Email, "new-hash"); err != nil {
+			t.Fatalf("SetPassword failed: %v", err)
+		}
+		got, _ := s.GetByEmail(ctx, user.Email)
+		if got.Password != "new-hash" {
+			t.Errorf("Expected the new hash, got %q", got.Password)
+		}
+		status, _ := s.GetUserStatus(ctx, user.ID)
+		if status.TokenGeneration != 1 || got.TokenGeneration != 1 {
+			t.Errorf("Expected tokens to be revoked, got %+v and %d", status, got.TokenGeneration)
+		}
+	})
+
+	t.Run("DisableUser", func(t *testing.T) {
+		s := newStore(t)
+		user := createUser(t, s, "disable")
+		other := createUser(t, s, "bystander")
+		if err := s.DisableUser(ctx, user.Email); err != nil {
+			t.Fatalf("DisableUser failed: %v", err)
+		}
+		if got, _ := s.GetByEmail(ctx, user.Email); !got.Disabled {
+			t.Error("Expected GetByEmail to report the user as disabled")
+		}
+		if status, _ := s.GetUserStatus(ctx, user.ID); !status.Disabled {
+			t.Error("Expected GetUserStatus to report the user as disabled")
+		}
+		if status, _ := s.GetUserStatus(ctx, other.ID); status.Disabled {
+			t.Error("Disabling one user must not affect another")
+		}
+	})
+
+	t.Run("RevokeTokens", func(t *testing.T) {
+		s := newStore(t)
+		ada := createUser(t, s, "ada")
+		createUser(t, s, "bob")
+
+		if n, err := s.RevokeTokens(ctx, ada.Email); err != nil || n != 1 {
+			t.Errorf("Expected one user revoked, got %d, %v", n, err)
+		}
+		if n, err := s.RevokeTokens(ctx, ""); err != nil || n != 2 {
+			t.Errorf("Expected every user revoked, got %d, %v", n, err)
+		}
+		if status, _ := s.GetUserStatus(ctx, ada.ID); status.TokenGeneration != 2 {
+			t.Errorf("Expected each revocation to start a new generation, got %d", status.TokenGeneration)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		s := newStore(t)
+		if err := s.SetPassword(ctx, "ghost@example.com", "x"); err != store.ErrNotFound {
+			t.Errorf("SetPassword: expected ErrNotFound, got %v", err)
+		}
+		if err := s.DisableUser(ctx, "ghost@example.com"); err != store.ErrNotFound {
+			t.Errorf("DisableUser: expected ErrNotFound, got %v", err)
+		}
+		if _, err := s.RevokeTokens(ctx, "ghost@example.com"); err != store.ErrNotFound {
+			t.Errorf("RevokeTokens: expected ErrNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Transactor runs several store calls as one unit of work.
+type Transactor interface {
+	// WithTx calls fn with a Store whose methods all run in one
+	// transaction. The transaction commits when fn returns nil and rolls
+	// back when it returns an error or panics; the panic is then re-raised.
+	// A nil opts uses the backend's default isolation level.
+	//
+	// Backends may call fn again when the transaction loses a conflict
+	// with a concurrent one, so fn must not have effects outside the
+	// store. Calling WithTx on the Store passed to fn runs the inner
+	// function in the same transaction.
+	WithTx(ctx context.Context, opts *sql.TxOptions, fn func(tx Store) error) error
+}
+
+// dbtx is what the PostgresStore methods need from *sql.DB and *sql.Tx.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// maxTxAttempts bounds how often WithTx runs fn when Postgres aborts the
+// transaction with a serialization failure or a deadlock.
+const maxTxAttempts = 3
+
+// txRetryDelay is multiplied by the attempt number between retries.
+var txRetryDelay = 10 * time.Millisecond
+
+func (s *PostgresStore) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(tx Store) error) error {
+	db, ok := s.db.(DB)
+	if !ok {
+		return fn(s) // already in a transaction
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := s.runTx(ctx, db, opts, fn)
+		code := retryCode(err)
+		if code == "" || attempt == maxTxAttempts {
+			return err
+		}
+		txRetries.WithLabelValues(code).Inc()
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * txRetryDelay):
+		}
+	}
+}
+
+func (s *PostgresStore) runTx(ctx context.Context, db DB, opts *sql.TxOptions, fn func(tx Store) error) (err error) {
+	ctx, q := startQuery(ctx, "tx")
+	defer q.end(&err)
+
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := fn(&PostgresStore{db: tx, replicas: s.replicas, queryTimeout: s.queryTimeout}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// retryCode returns the SQLSTATE of err when it aborted a transaction that
+// may succeed when run again, and "" otherwise.
+func retryCode(err error) string {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return ""
+	}
+	switch pqErr.Code {
+	case "40001", // serialization_failure
+		"40P01": // deadlock_detected
+		return string(pqErr.Code)
+	}
+	return ""
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func newTxTestStore(t *testing.T) (*PostgresStore, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to open mock sql db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	delay := txRetryDelay
+	txRetryDelay = 0
+	t.Cleanup(func() { txRetryDelay = delay })
+
+	return NewPostgresStore(db), mock
+}
+
+func expectTxDelete(mock sqlmock.Sqlmock) *sqlmock.ExpectedExec {
+	return mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM notes WHERE id = $1 AND user_id = $2`)).WithArgs("note-1", "user-1")
+}
+
+func TestWithTx_CommitsOnSuccess(t *testing.T) {
+	s, mock := newTxTestStore(t)
+	mock.ExpectBegin()
+	expectTxDelete(mock).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := s.WithTx(context.Background(), nil, func(tx Store) error {
+		return tx.DeleteNote(context.Background(), "user-1", "note-1")
+	})
+	if err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestWithTx_RollsBackOnError(t *testing.T) {
+	s, mock := newTxTestStore(t)
+	mock.ExpectBegin()
+	expectTxDelete(mock).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := s.WithTx(context.Background(), nil, func(tx Store) error {
+		return tx.DeleteNote(context.Background(), "user-1", "note-1")
+	})
+	if err != ErrNotFound {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestWithTx_RollsBackOnPanic(t *testing.T) {
+	s, mock := newTxTestStore(t)
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("Expected the panic to propagate, got %v", p)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	}()
+	s.WithTx(context.Background(), nil, func(tx Store) error { panic("boom") })
+}
+
+func TestWithTx_RetriesSerializationFailures(t *testing.T) {
+	s, mock := newTxTestStore(t)
+	mock.ExpectBegin()
+	expectTxDelete(mock).WillReturnError(&pq.Error{Code: "40001"})
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	expectTxDelete(mock).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit().WillReturnError(&pq.Error{Code: "40P01"})
+	mock.ExpectBegin()
+	expectTxDelete(mock).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	calls := 0
+	err := s.WithTx(context.Background(), nil, func(tx Store) error {
+		calls++
+		return tx.DeleteNote(context.Background(), "user-1", "note-1")
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("Expected success on the third attempt, got %v after %d calls", err, calls)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestWithTx_GivesUpAfterMaxAttempts(t *testing.T) {
+	s, mock := newTxTestStore(t)
+	for i := 0; i < maxTxAttempts; i++ {
+		mock.ExpectBegin()
+		expectTxDelete(mock).WillReturnError(&pq.Error{Code: "40001"})
+		mock.ExpectRollback()
+	}
+
+	err := s.WithTx(context.Background(), nil, func(tx Store) error {
+		return tx.DeleteNote(context.Background(), "user-1", "note-1")
+	})
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "40001" {
+		t.Fatalf("Expected the serialization failure, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestWithTx_DoesNotRetryOtherErrors(t *testing.T) {
+	s, mock := newTxTestStore(t)
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	calls := 0
+	failure := errors.New("validation failed")
+	err := s.WithTx(context.Background(), nil, func(tx Store) error {
+		calls++
+		return failure
+	})
+	if err != failure || calls != 1 {
+		t.Errorf("Expected one call and fn's error, got %v after %d calls", err, calls)
+	}
+}
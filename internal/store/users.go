@@ -13,29 +13,41 @@ import (
 var (
 	ErrInvalidEmail     = errors.New("invalid email format")
 	ErrPasswordTooShort = errors.New("password must be at least 6 characters")
+	ErrPasswordTooLong  = errors.New("password must be at most 72 bytes")
 	ErrDuplicateEmail   = errors.New("email already exists")
 	ErrNotFound         = errors.New("resource not found")
+	ErrDuplicateCode    = errors.New("code already exists")
 )
 
 type User struct {
 	ID        string    `json:"id"`
 	Email     string    `json:"email"`
 	Password  string    `json:"password,omitempty"` // plaintext for input, not stored
+	Disabled  bool      `json:"disabled"`
 	CreatedAt time.Time `json:"created_at"`
+	// TokenGeneration is the generation that tokens issued now carry; see
+	// UserStatus.
+	TokenGeneration int64 `json:"-"`
 }
 
+// Validate returns every problem with u, joined.
 func (u *User) Validate() error {
+	var errs []error
 	if len(u.Password) < 6 {
-		return ErrPasswordTooShort
+		errs = append(errs, ErrPasswordTooShort)
+	}
+	// bcrypt rejects longer passwords.
+	if len(u.Password) > 72 {
+		errs = append(errs, ErrPasswordTooLong)
 	}
 
 	// Simple regex for email validation
 	emailRegex := `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
 	if match, _ := regexp.MatchString(emailRegex, u.Email); !match {
-		return ErrInvalidEmail
+		errs = append(errs, ErrInvalidEmail)
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 type UserStorer interface {
@@ -43,18 +55,53 @@ type UserStorer interface {
 	GetByEmail(ctx context.Context, email string) (*User, error)
 }
 
+// UserStatus is what WithActiveUser needs to decide whether a token for the
+// user is still honoured.
+type UserStatus struct {
+	Disabled bool
+	// TokenGeneration is bumped every time the user's tokens are revoked.
+	// Only tokens issued in the current generation are honoured.
+	TokenGeneration int64
+}
+
+type UserStatusStorer interface {
+	GetUserStatus(ctx context.Context, userID string) (*UserStatus, error)
+}
+
+// UserAdminStorer holds the operator actions behind `api user` and
+// `api tokens`. Users are addressed by email; each action that locks a user
+// out also revokes their tokens.
+type UserAdminStorer interface {
+	SetPassword(ctx context.Context, email, passwordHash string) error
+	DisableUser(ctx context.Context, email string) error
+	// RevokeTokens revokes the tokens of the user with email, or of every
+	// user when email is empty, and returns the number of users affected.
+	RevokeTokens(ctx context.Context, email string) (int64, error)
+}
+
 type PostgresStore struct {
-	db *sql.DB
+	// db is the primary pool, or the transaction of a store passed to
+	// WithTx.
+	db           dbtx
+	replicas     *replicaSet
+	queryTimeout time.Duration
 }
 
-func NewPostgresStore(db *sql.DB) *PostgresStore {
-	return &PostgresStore{db: db}
+func NewPostgresStore(db DB, opts ...PostgresOption) *PostgresStore {
+	s := &PostgresStore{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-func (s *PostgresStore) Create(ctx context.Context, user *User) error {
+func (s *PostgresStore) Create(ctx context.Context, user *User) (err error) {
+	ctx, q := s.startQuery(ctx, "users.create")
+	defer q.end(&err)
+
 	query := `INSERT INTO users (email, password) VALUES ($1, $2) RETURNING id, created_at`
 
-	err := s.db.QueryRowContext(ctx, query, user.Email, user.Password).Scan(&user.ID, &user.CreatedAt)
+	err = s.db.QueryRowContext(ctx, query, user.Email, user.Password).Scan(&user.ID, &user.CreatedAt)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
 			if pqErr.Code == "23505" { // unique_violation
@@ -64,14 +111,23 @@ func (s *PostgresStore) Create(ctx context.Context, user *User) error {
 		return err
 	}
 
+	s.wrote(userKey(user.ID))
 	return nil
 }
 
-func (s *PostgresStore) GetByEmail(ctx context.Context, email string) (*User, error) {
-	query := `SELECT id, email, password, created_at FROM users WHERE email = $1`
+// GetByEmail always reads the primary: login checks the password hash,
+// disabled flag and token generation, which must not lag behind a password
+// reset or a disable.
+func (s *PostgresStore) GetByEmail(ctx context.Context, email string) (_ *User, err error) {
+	ctx, q := s.startQuery(ctx, "users.get_by_email")
+	defer q.end(&err)
+
+	query := `SELECT id, email, password, disabled_at IS NOT NULL, created_at, token_generation FROM users WHERE email = $1`
 
 	var user User
-	err := s.db.QueryRowContext(ctx, query, email).Scan(&user.ID, &user.Email, &user.Password, &user.CreatedAt)
+	err = s.read(ctx, "", func(db dbtx) error {
+		return db.QueryRowContext(ctx, query, email).Scan(&user.ID, &user.Email, &user.Password, &user.Disabled, &user.CreatedAt, &user.TokenGeneration)
+	})
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
@@ -81,3 +137,67 @@ func (s *PostgresStore) GetByEmail(ctx context.Context, email string) (*User, er
 
 	return &user, nil
 }
+
+// GetUserStatus always reads the primary, so disabling a user or revoking
+// their tokens takes effect without waiting for replication.
+func (s *PostgresStore) GetUserStatus(ctx context.Context, userID string) (_ *UserStatus, err error) {
+	ctx, q := s.startQuery(ctx, "users.get_status")
+	defer q.end(&err)
+
+	query := `SELECT disabled_at IS NOT NULL, token_generation FROM users WHERE id = $1`
+
+	var status UserStatus
+	err = s.read(ctx, "", func(db dbtx) error {
+		return db.QueryRowContext(ctx, query, userID).Scan(&status.Disabled, &status.TokenGeneration)
+	})
+	if err != nil {
+		return nil, lookupError(err)
+	}
+	return &status, nil
+}
+
+func (s *PostgresStore) SetPassword(ctx context.Context, email, passwordHash string) (err error) {
+	ctx, q := s.startQuery(ctx, "users.set_password")
+	defer q.end(&err)
+
+	query := `UPDATE users SET password = $1, token_generation = token_generation + 1 WHERE email = $2`
+
+	res, err := s.db.ExecContext(ctx, query, passwordHash, email)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+func (s *PostgresStore) DisableUser(ctx context.Context, email string) (err error) {
+	ctx, q := s.startQuery(ctx, "users.disable")
+	defer q.end(&err)
+
+	query := `UPDATE users SET disabled_at = COALESCE(disabled_at, now()), token_generation = token_generation + 1 WHERE email = $1`
+
+	res, err := s.db.ExecContext(ctx, query, email)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+func (s *PostgresStore) RevokeTokens(ctx context.Context, email string) (_ int64, err error) {
+	ctx, q := s.startQuery(ctx, "users.revoke_tokens")
+	defer q.end(&err)
+
+	query := `UPDATE users SET token_generation = token_generation + 1 WHERE $1 = '' OR email = $1`
+
+	res, err := s.db.ExecContext(ctx, query, email)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if email != "" && n == 0 {
+		return 0, ErrNotFound
+	}
+	return n, nil
+}
@@ -1,6 +1,10 @@
 package store
 
-import "testing"
+import (
+	"errors"
+	"strings"
+	"testing"
+)
 
 func TestUser_Validate_Success(t *testing.T) {
 	u := &User{
@@ -34,3 +38,27 @@ func TestUser_Validate_ShortPassword(t *testing.T) {
 		t.Error("Expected error for short password, got nil")
 	}
 }
+
+func TestUser_Validate_LongPassword(t *testing.T) {
+	u := &User{
+		Email: "test@example.com",
+		// 37 characters, 73 bytes
+		Password: strings.Repeat("é", 36) + "x",
+	}
+
+	if err := u.Validate(); !errors.Is(err, ErrPasswordTooLong) {
+		t.Errorf("Expected ErrPasswordTooLong for a password over 72 bytes, got %v", err)
+	}
+}
+
+func TestUser_Validate_ReportsEveryError(t *testing.T) {
+	u := &User{
+		Email:    "invalid-email",
+		Password: "123",
+	}
+
+	err := u.Validate()
+	if !errors.Is(err, ErrInvalidEmail) || !errors.Is(err, ErrPasswordTooShort) {
+		t.Errorf("Expected both the email and the password errors, got %v", err)
+	}
+}
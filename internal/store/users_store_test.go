@@ -98,10 +98,10 @@ func TestGetByEmail_Found(t *testing.T) {
 	expectedID := "550e8400-e29b-41d4-a716-446655440000"
 	expectedTime := time.Now()
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, password, created_at FROM users WHERE email = $1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, password, disabled_at IS NOT NULL, created_at, token_generation FROM users WHERE email = $1`)).
 		WithArgs("found@example.com").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "created_at"}).
-			AddRow(expectedID, "found@example.com", "hashedpassword", expectedTime))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "disabled", "created_at", "token_generation"}).
+			AddRow(expectedID, "found@example.com", "hashedpassword", true, expectedTime, 3))
 
 	user, err := store.GetByEmail(context.Background(), "found@example.com")
 	if err != nil {
@@ -111,6 +111,12 @@ func TestGetByEmail_Found(t *testing.T) {
 	if user.ID != expectedID {
 		t.Errorf("Expected ID %v, got %v", expectedID, user.ID)
 	}
+	if !user.Disabled {
+		t.Error("Expected the user to be disabled")
+	}
+	if user.TokenGeneration != 3 {
+		t.Errorf("Expected token generation 3, got %d", user.TokenGeneration)
+	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
@@ -126,9 +132,9 @@ func TestGetByEmail_NotFound(t *testing.T) {
 
 	store := &PostgresStore{db: db}
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, password, created_at FROM users WHERE email = $1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, password, disabled_at IS NOT NULL, created_at, token_generation FROM users WHERE email = $1`)).
 		WithArgs("ghost@user.com").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "created_at"})) // Empty result
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "disabled", "created_at"})) // Empty result
 
 	_, err = store.GetByEmail(context.Background(), "ghost@user.com")
 	if err != ErrNotFound {
@@ -139,3 +145,68 @@ func TestGetByEmail_NotFound(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestGetUserStatus_MalformedIDIsNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to open mock sql db: %v", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT disabled_at IS NOT NULL, token_generation FROM users WHERE id = $1`)).
+		WithArgs("not-a-uuid").
+		WillReturnError(&pq.Error{Code: "22P02"})
+
+	if _, err := store.GetUserStatus(context.Background(), "not-a-uuid"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestDisableUser_RevokesTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to open mock sql db: %v", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET disabled_at = COALESCE(disabled_at, now()), token_generation = token_generation + 1 WHERE email = $1`)).
+		WithArgs("ada@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET disabled_at`)).
+		WithArgs("ghost@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := store.DisableUser(context.Background(), "ada@example.com"); err != nil {
+		t.Errorf("DisableUser failed: %v", err)
+	}
+	if err := store.DisableUser(context.Background(), "ghost@example.com"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRevokeTokens_AllUsers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to open mock sql db: %v", err)
+	}
+	defer db.Close()
+
+	store := &PostgresStore{db: db}
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET token_generation = token_generation + 1 WHERE $1 = '' OR email = $1`)).
+		WithArgs("").
+		WillReturnResult(sqlmock.NewResult(0, 7))
+
+	n, err := store.RevokeTokens(context.Background(), "")
+	if err != nil || n != 7 {
+		t.Errorf("Expected 7 users, got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,117 @@
+// Package tlsconfig builds the server's TLS configuration from PEM files:
+// the certificate, its key and, for mutual TLS, the CA bundle client
+// certificates are verified against. Reload reads the files again, so a
+// renewed certificate is served without a restart.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// Options names the files and how client certificates are handled.
+type Options struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is required unless ClientAuth is tls.NoClientCert
+	ClientCAFile string
+	ClientAuth   tls.ClientAuthType
+}
+
+// Reloader holds the TLS configuration loaded from the files of Options.
+type Reloader struct {
+	opts   Options
+	config atomic.Pointer[tls.Config]
+}
+
+// New loads the files of opts.
+func New(opts Options) (*Reloader, error) {
+	r := &Reloader{opts: opts}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the files again. On error the current configuration stays in
+// effect, so a certificate caught half-written is not served.
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		// GetConfigForClient replaces the configuration net/http would
+		// have added h2 to
+		NextProtos: []string{"h2", "http/1.1"},
+		ClientAuth: r.opts.ClientAuth,
+	}
+	if r.opts.ClientAuth != tls.NoClientCert {
+		pool, err := loadCAs(r.opts.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		config.ClientCAs = pool
+	}
+	r.config.Store(config)
+	return nil
+}
+
+func loadCAs(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("failed to read client CA bundle: no PEM certificates in " + file)
+	}
+	return pool, nil
+}
+
+// Files returns the files Reload reads, so they can be watched for changes.
+func (r *Reloader) Files() []string {
+	files := []string{r.opts.CertFile, r.opts.KeyFile}
+	if r.opts.ClientAuth != tls.NoClientCert {
+		files = append(files, r.opts.ClientCAFile)
+	}
+	return files
+}
+
+// Config returns the configuration for http.Server.TLSConfig. Every
+// handshake uses whatever Reload loaded last.
+func (r *Reloader) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.config.Load(), nil
+		},
+		// Not reached while GetConfigForClient is set; it tells
+		// http.Server.ServeTLS that no certificate files are needed.
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &r.config.Load().Certificates[0], nil
+		},
+	}
+}
+
+// ParseClientAuth maps the tls.client_auth setting to a tls.ClientAuthType:
+// "optional" verifies a certificate if the client sends one and "require"
+// rejects clients without one. Anything else disables client certificates.
+func ParseClientAuth(mode string) tls.ClientAuthType {
+	switch mode {
+	case "optional":
+		return tls.VerifyClientCertIfGiven
+	case "require":
+		return tls.RequireAndVerifyClientCert
+	default:
+		return tls.NoClientCert
+	}
+}
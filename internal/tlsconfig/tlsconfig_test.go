@@ -0,0 +1,266 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert is a certificate and its key, signed by parent or self-signed.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newCert(t *testing.T, cn string, serial int64, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// write stores c as PEM in dir and returns the certificate and key files.
+func (c *testCert) write(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writePEM(t, certFile, "CERTIFICATE", c.der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, file, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// handshake connects a client with config to a server with r.Config() and
+// returns the certificate the server presented and the client certificate
+// chain it verified.
+func handshake(t *testing.T, r *Reloader, config *tls.Config) (server *x509.Certificate, client []*x509.Certificate, err error) {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", r.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	type result struct {
+		state tls.ConnectionState
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer conn.Close()
+		srv := conn.(*tls.Conn)
+		err = srv.Handshake()
+		done <- result{srv.ConnectionState(), err}
+	}()
+
+	cli, err := tls.Dial("tcp", ln.Addr().String(), config)
+	if err != nil {
+		<-done
+		return nil, nil, err
+	}
+	defer cli.Close()
+	// TLS 1.3 clients finish before the server checks their certificate
+	res := <-done
+	if res.err != nil {
+		return nil, nil, res.err
+	}
+	if len(res.state.VerifiedChains) > 0 {
+		client = res.state.VerifiedChains[0]
+	}
+	state := cli.ConnectionState()
+	if state.NegotiatedProtocol != "h2" {
+		t.Errorf("Expected HTTP/2 to be negotiated, got %q", state.NegotiatedProtocol)
+	}
+	return state.PeerCertificates[0], client, nil
+}
+
+func clientConfig(ca *testCert, cert *testCert) *tls.Config {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	config := &tls.Config{ServerName: "localhost", RootCAs: roots, NextProtos: []string{"h2"}}
+	if cert != nil {
+		// Sent even when the server asks for another CA's certificates
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &tls.Certificate{Certificate: [][]byte{cert.der}, PrivateKey: cert.key}, nil
+		}
+	}
+	return config
+}
+
+func TestReloader_ReloadServesNewCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCert(t, "test-ca", 1, nil)
+	certFile, keyFile := newCert(t, "localhost", 2, ca).write(t, dir)
+
+	r, err := New(Options{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	server, _, err := handshake(t, r, clientConfig(ca, nil))
+	if err != nil {
+		t.Fatalf("Handshake failed: %v", err)
+	}
+	if server.SerialNumber.Int64() != 2 {
+		t.Errorf("Expected certificate 2, got %v", server.SerialNumber)
+	}
+
+	newCert(t, "localhost", 3, ca).write(t, dir)
+	if err := r.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	server, _, err = handshake(t, r, clientConfig(ca, nil))
+	if err != nil {
+		t.Fatalf("Handshake failed: %v", err)
+	}
+	if server.SerialNumber.Int64() != 3 {
+		t.Errorf("Expected the reloaded certificate 3, got %v", server.SerialNumber)
+	}
+}
+
+func TestReloader_FailedReloadKeepsCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCert(t, "test-ca", 1, nil)
+	certFile, keyFile := newCert(t, "localhost", 2, ca).write(t, dir)
+
+	r, err := New(Options{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := os.WriteFile(keyFile, []byte("truncated"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(); err == nil {
+		t.Fatal("Expected Reload to fail")
+	}
+
+	server, _, err := handshake(t, r, clientConfig(ca, nil))
+	if err != nil {
+		t.Fatalf("Handshake failed: %v", err)
+	}
+	if server.SerialNumber.Int64() != 2 {
+		t.Errorf("Expected certificate 2, got %v", server.SerialNumber)
+	}
+}
+
+func TestReloader_ClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCert(t, "test-ca", 1, nil)
+	certFile, keyFile := newCert(t, "localhost", 2, ca).write(t, dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", ca.der)
+	clientCert := newCert(t, "billing", 3, ca)
+	otherCA := newCert(t, "other-ca", 4, nil)
+	strangerCert := newCert(t, "stranger", 5, otherCA)
+
+	tests := []struct {
+		name       string
+		clientAuth tls.ClientAuthType
+		cert       *testCert
+		wantErr    bool
+		wantClient string
+	}{
+		{"required and sent", tls.RequireAndVerifyClientCert, clientCert, false, "billing"},
+		{"required and missing", tls.RequireAndVerifyClientCert, nil, true, ""},
+		{"required from another CA", tls.RequireAndVerifyClientCert, strangerCert, true, ""},
+		{"optional and missing", tls.VerifyClientCertIfGiven, nil, false, ""},
+		{"optional from another CA", tls.VerifyClientCertIfGiven, strangerCert, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := New(Options{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, ClientAuth: tt.clientAuth})
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+
+			_, client, err := handshake(t, r, clientConfig(ca, tt.cert))
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected the handshake to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Handshake failed: %v", err)
+			}
+			var cn string
+			if len(client) > 0 {
+				cn = client[0].Subject.CommonName
+			}
+			if cn != tt.wantClient {
+				t.Errorf("Expected client %q, got %q", tt.wantClient, cn)
+			}
+		})
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCert(t, "test-ca", 1, nil)
+	certFile, keyFile := newCert(t, "localhost", 2, ca).write(t, dir)
+	notPEM := filepath.Join(dir, "ca.txt")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"missing certificate", Options{CertFile: filepath.Join(dir, "missing.pem"), KeyFile: keyFile}},
+		{"missing CA bundle", Options{CertFile: certFile, KeyFile: keyFile, ClientAuth: tls.RequireAndVerifyClientCert, ClientCAFile: filepath.Join(dir, "missing.pem")}},
+		{"CA bundle without certificates", Options{CertFile: certFile, KeyFile: keyFile, ClientAuth: tls.RequireAndVerifyClientCert, ClientCAFile: notPEM}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.opts); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
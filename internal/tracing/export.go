@@ -0,0 +1,364 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/metrics"
+)
+
+var spansDroppedTotal = metrics.NewCounterVec(
+	"notes_tracing_spans_dropped_total",
+	"Spans dropped because the export queue was full.",
+).WithLabelValues()
+
+// Exporter sends finished spans to a backend.
+type Exporter interface {
+	ExportSpans(ctx context.Context, resource Resource, spans []SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+// Resource describes the process emitting spans.
+type Resource struct {
+	ServiceName string
+}
+
+const maxQueueSize = 2048
+
+// dropReportInterval bounds how often dropped spans are logged; a full queue
+// drops spans on every request, and a warning per span would flood the log.
+const dropReportInterval = time.Minute
+
+// batchProcessor buffers ended spans and exports them from a single
+// goroutine, dropping spans when the queue is full rather than blocking
+// request handling.
+type batchProcessor struct {
+	opts     Options
+	queue    chan SpanData
+	flush    chan chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	dropped      atomic.Int64 // since the last report
+	lastReported time.Time    // owned by run
+}
+
+func newBatchProcessor(opts Options) *batchProcessor {
+	p := &batchProcessor{
+		opts:  opts,
+		queue: make(chan SpanData, maxQueueSize),
+		flush: make(chan chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+func (p *batchProcessor) enqueue(span SpanData) {
+	select {
+	case p.queue <- span:
+	default:
+		p.dropped.Add(1)
+		spansDroppedTotal.Inc()
+	}
+}
+
+// reportDropped logs the spans dropped since the last report, at most once
+// per dropReportInterval unless force is set.
+func (p *batchProcessor) reportDropped(now time.Time, force bool) {
+	if !force && now.Sub(p.lastReported) < dropReportInterval {
+		return
+	}
+	if n := p.dropped.Swap(0); n > 0 {
+		slog.Warn("tracing: span queue full, dropped spans", "spans", n, "since", p.lastReported)
+	}
+	p.lastReported = now
+}
+
+func (p *batchProcessor) run() {
+	ticker := time.NewTicker(p.opts.BatchTimeout)
+	defer ticker.Stop()
+
+	p.lastReported = time.Now()
+	batch := make([]SpanData, 0, p.opts.BatchSize)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := p.opts.Exporter.ExportSpans(ctx, Resource{ServiceName: p.opts.ServiceName}, batch); err != nil {
+			slog.Warn("tracing: export failed", "error", err, "spans", len(batch))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) >= p.opts.BatchSize {
+				export()
+			}
+		case now := <-ticker.C:
+			export()
+			p.reportDropped(now, false)
+		case ack := <-p.flush:
+			for drained := false; !drained; {
+				select {
+				case span := <-p.queue:
+					batch = append(batch, span)
+				default:
+					drained = true
+				}
+			}
+			export()
+			p.reportDropped(time.Now(), true)
+			close(ack)
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func (p *batchProcessor) shutdown(ctx context.Context) error {
+	var err error
+	p.stopOnce.Do(func() {
+		ack := make(chan struct{})
+		select {
+		case p.flush <- ack:
+			select {
+			case <-ack:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		close(p.done)
+
+		if shutdownErr := p.opts.Exporter.Shutdown(ctx); err == nil {
+			err = shutdownErr
+		}
+	})
+	return err
+}
+
+// WriterExporter writes one JSON object per span, for local debugging.
+type WriterExporter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// NewWriterExporter writes spans to w. If w is an io.Closer other than
+// stdout/stderr, pass it as closer so Shutdown closes it.
+func NewWriterExporter(w io.Writer, closer io.Closer) *WriterExporter {
+	return &WriterExporter{w: w, closer: closer}
+}
+
+type writerSpan struct {
+	Service    string         `json:"service"`
+	Name       string         `json:"name"`
+	Kind       SpanKind       `json:"kind"`
+	TraceID    string         `json:"trace_id"`
+	SpanID     string         `json:"span_id"`
+	ParentID   string         `json:"parent_span_id,omitempty"`
+	Start      time.Time      `json:"start"`
+	DurationMS float64        `json:"duration_ms"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	Status     string         `json:"status,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+func (e *WriterExporter) ExportSpans(ctx context.Context, resource Resource, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	enc := json.NewEncoder(e.w)
+	for _, s := range spans {
+		out := writerSpan{
+			Service:    resource.ServiceName,
+			Name:       s.Name,
+			Kind:       s.Kind,
+			TraceID:    s.SpanContext.TraceID.String(),
+			SpanID:     s.SpanContext.SpanID.String(),
+			Start:      s.Start,
+			DurationMS: float64(s.End.Sub(s.Start).Microseconds()) / 1000,
+		}
+		if s.ParentSpanID.IsValid() {
+			out.ParentID = s.ParentSpanID.String()
+		}
+		if len(s.Attributes) > 0 {
+			out.Attributes = make(map[string]any, len(s.Attributes))
+			for _, a := range s.Attributes {
+				out.Attributes[a.Key] = a.Value
+			}
+		}
+		if s.StatusCode == StatusError {
+			out.Status = "error"
+			out.Error = s.StatusMessage
+		}
+		if err := enc.Encode(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *WriterExporter) Shutdown(ctx context.Context) error {
+	if e.closer != nil {
+		return e.closer.Close()
+	}
+	return nil
+}
+
+// OTLPExporter posts spans to an OTLP/HTTP collector using the JSON
+// encoding, e.g. http://localhost:4318/v1/traces.
+type OTLPExporter struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+}
+
+func NewOTLPExporter(endpoint string, headers map[string]string) *OTLPExporter {
+	return &OTLPExporter{
+		endpoint: endpoint,
+		headers:  headers,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// The types below mirror the JSON mapping of the OTLP protobuf messages.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		TraceState        string         `json:"traceState,omitempty"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              SpanKind       `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpStatus struct {
+		Code    StatusCode `json:"code,omitempty"`
+		Message string     `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+)
+
+func otlpAttributes(attrs []Attribute) []otlpKeyValue {
+	out := make([]otlpKeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		var v otlpValue
+		switch val := a.Value.(type) {
+		case string:
+			v.StringValue = &val
+		case bool:
+			v.BoolValue = &val
+		case int64:
+			s := strconv.FormatInt(val, 10)
+			v.IntValue = &s
+		case float64:
+			v.DoubleValue = &val
+		default:
+			s := fmt.Sprint(val)
+			v.StringValue = &s
+		}
+		out = append(out, otlpKeyValue{Key: a.Key, Value: v})
+	}
+	return out
+}
+
+func (e *OTLPExporter) ExportSpans(ctx context.Context, resource Resource, spans []SpanData) error {
+	converted := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           s.SpanContext.TraceID.String(),
+			SpanID:            s.SpanContext.SpanID.String(),
+			TraceState:        s.SpanContext.TraceState,
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.Attributes),
+			Status:            otlpStatus{Code: s.StatusCode, Message: s.StatusMessage},
+		}
+		if s.ParentSpanID.IsValid() {
+			span.ParentSpanID = s.ParentSpanID.String()
+		}
+		converted = append(converted, span)
+	}
+
+	body, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: otlpAttributes([]Attribute{String("service.name", resource.ServiceName)})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "github.com/ivan-almanza/notes-api/internal/tracing"}, Spans: converted}},
+	}}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp export: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,125 @@
+// Package tracing is a small, dependency-free tracer compatible with
+// OpenTelemetry: it propagates W3C Trace Context (traceparent/tracestate)
+// and exports spans with the OTLP/HTTP JSON encoding, so traces join those
+// of services instrumented with the official SDKs.
+//
+// Spans are started from a context:
+//
+//	ctx, span := tracing.Start(ctx, "notes.create")
+//	defer span.End()
+//
+// Until SetTracer is called, Start returns nil spans, whose methods are no-ops.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+)
+
+type TraceID [16]byte
+
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (t TraceID) IsValid() bool  { return t != TraceID{} }
+
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+func (s SpanID) IsValid() bool  { return s != SpanID{} }
+
+func newTraceID() TraceID {
+	var t TraceID
+	for !t.IsValid() {
+		rand.Read(t[:])
+	}
+	return t
+}
+
+func newSpanID() SpanID {
+	var s SpanID
+	for !s.IsValid() {
+		rand.Read(s[:])
+	}
+	return s
+}
+
+// SpanContext identifies a span across process boundaries.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+	Remote     bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent formats sc as a W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceparent parses a W3C traceparent header value. Future versions
+// are accepted as long as the version 00 fields parse.
+func ParseTraceparent(value string) (SpanContext, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) < 55 {
+		return SpanContext{}, false
+	}
+
+	version, rest, ok := strings.Cut(value, "-")
+	if !ok || len(version) != 2 || version == "ff" || !isLowerHex(version) {
+		return SpanContext{}, false
+	}
+	if version == "00" && len(value) != 55 {
+		return SpanContext{}, false
+	}
+
+	parts := strings.SplitN(rest, "-", 4)
+	if len(parts) < 3 || len(parts[0]) != 32 || len(parts[1]) != 16 || len(parts[2]) != 2 {
+		return SpanContext{}, false
+	}
+	if !isLowerHex(parts[0]) || !isLowerHex(parts[1]) || !isLowerHex(parts[2]) {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	hex.Decode(sc.TraceID[:], []byte(parts[0]))
+	hex.Decode(sc.SpanID[:], []byte(parts[1]))
+	var flags [1]byte
+	hex.Decode(flags[:], []byte(parts[2]))
+
+	sc.Sampled = flags[0]&0x01 == 0x01
+	sc.Remote = true
+	return sc, sc.IsValid()
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// sampledByRatio makes a deterministic decision from the trace ID so every
+// service in a trace agrees without coordination.
+func sampledByRatio(id TraceID, ratio float64) bool {
+	switch {
+	case ratio >= 1:
+		return true
+	case ratio <= 0:
+		return false
+	}
+	bound := uint64(ratio * (1 << 63))
+	return binary.BigEndian.Uint64(id[8:])>>1 < bound
+}
@@ -0,0 +1,217 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingExporter keeps exported spans in memory.
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (e *recordingExporter) ExportSpans(ctx context.Context, resource Resource, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(ctx context.Context) error { return nil }
+
+func TestParseTraceparent_Valid(t *testing.T) {
+	sc, ok := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if !ok {
+		t.Fatal("Expected valid traceparent")
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" {
+		t.Errorf("Unexpected IDs: %s %s", sc.TraceID, sc.SpanID)
+	}
+	if !sc.Sampled || !sc.Remote {
+		t.Error("Expected sampled remote span context")
+	}
+	if got := sc.Traceparent(); got != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("Round trip mismatch: %s", got)
+	}
+}
+
+func TestParseTraceparent_Invalid(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		if _, ok := ParseTraceparent(value); ok {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestStart_ContinuesRemoteTrace(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(Options{SampleRatio: 0, Exporter: exporter})
+
+	h := http.Header{}
+	h.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := Extract(context.Background(), h)
+
+	ctx, parent := tracer.Start(ctx, "parent")
+	_, child := tracer.Start(ctx, "child")
+	child.SetError(errors.New("boom"))
+	child.End()
+	parent.End()
+	tracer.Shutdown(context.Background())
+
+	if len(exporter.spans) != 2 {
+		t.Fatalf("Expected 2 spans (remote parent was sampled), got %d", len(exporter.spans))
+	}
+	c, p := exporter.spans[0], exporter.spans[1]
+	if p.SpanContext.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || p.ParentSpanID.String() != "00f067aa0ba902b7" {
+		t.Errorf("Parent should continue the remote trace: %+v", p)
+	}
+	if c.ParentSpanID != p.SpanContext.SpanID || c.SpanContext.TraceID != p.SpanContext.TraceID {
+		t.Error("Child should be linked to parent")
+	}
+	if c.StatusCode != StatusError || c.StatusMessage != "boom" {
+		t.Errorf("Expected error status on child, got %v %q", c.StatusCode, c.StatusMessage)
+	}
+}
+
+func TestStart_SampleRatioZero(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(Options{SampleRatio: 0, Exporter: exporter})
+
+	_, span := tracer.Start(context.Background(), "dropped")
+	span.End()
+	tracer.Shutdown(context.Background())
+
+	if len(exporter.spans) != 0 {
+		t.Errorf("Expected no exported spans, got %d", len(exporter.spans))
+	}
+}
+
+func TestBatchProcessor_ReportsDropsPerInterval(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	start := time.Now()
+	p := &batchProcessor{queue: make(chan SpanData, 1), lastReported: start}
+	for range 3 {
+		p.enqueue(SpanData{Name: "span"})
+	}
+
+	p.reportDropped(start.Add(time.Second), false)
+	if logs.Len() != 0 {
+		t.Errorf("Expected no report within the interval, got %q", logs.String())
+	}
+
+	p.enqueue(SpanData{Name: "span"})
+	p.reportDropped(start.Add(dropReportInterval), false)
+	if got := strings.Count(logs.String(), "dropped spans"); got != 1 {
+		t.Fatalf("Expected 1 report, got %d: %q", got, logs.String())
+	}
+	if !strings.Contains(logs.String(), "spans=3") {
+		t.Errorf("Expected 3 dropped spans in one line, got %q", logs.String())
+	}
+
+	logs.Reset()
+	p.reportDropped(start.Add(2*dropReportInterval), false)
+	if logs.Len() != 0 {
+		t.Errorf("Expected no report without new drops, got %q", logs.String())
+	}
+}
+
+func TestStart_DisabledReturnsNilSpan(t *testing.T) {
+	SetTracer(nil)
+	ctx, span := Start(context.Background(), "noop")
+
+	// Methods on a nil span must not panic
+	span.SetAttributes(String("k", "v"))
+	span.SetError(errors.New("ignored"))
+	span.End()
+
+	if span != nil || SpanFromContext(ctx) != nil {
+		t.Error("Expected no span when tracing is disabled")
+	}
+}
+
+func TestOTLPExporter_JSONEncoding(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("Unexpected headers: %v", r.Header)
+		}
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPExporter(server.URL+"/v1/traces", map[string]string{"Authorization": "Bearer key"})
+	start := time.Unix(1700000000, 0)
+	err := exporter.ExportSpans(context.Background(), Resource{ServiceName: "notes-api"}, []SpanData{{
+		Name:        "GET /notes",
+		Kind:        SpanKindServer,
+		SpanContext: SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true},
+		Start:       start,
+		End:         start.Add(time.Millisecond),
+		Attributes:  []Attribute{Int("http.response.status_code", 200)},
+	}})
+	if err != nil {
+		t.Fatalf("ExportSpans failed: %v", err)
+	}
+
+	encoded, _ := json.Marshal(body)
+	for _, fragment := range []string{
+		`"service.name"`,
+		`"name":"GET /notes"`,
+		`"kind":2`,
+		`"startTimeUnixNano":"1700000000000000000"`,
+		`"intValue":"200"`,
+	} {
+		if !strings.Contains(string(encoded), fragment) {
+			t.Errorf("Expected %s in OTLP payload: %s", fragment, encoded)
+		}
+	}
+}
+
+func TestTransport_InjectsTraceparent(t *testing.T) {
+	tracer := NewTracer(Options{SampleRatio: 1, Exporter: &recordingExporter{}})
+	SetTracer(tracer)
+	defer SetTracer(nil)
+
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(TraceparentHeader)
+	}))
+	defer server.Close()
+
+	ctx, span := Start(context.Background(), "caller")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	client := &http.Client{Transport: &Transport{}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	span.End()
+
+	sc, ok := ParseTraceparent(received)
+	if !ok || sc.TraceID != span.SpanContext().TraceID {
+		t.Errorf("Expected server to receive the caller's trace, got %q", received)
+	}
+}
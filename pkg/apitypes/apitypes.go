@@ -0,0 +1,61 @@
+// Package apitypes defines the request and response bodies of the Notes API.
+// It is shared by the server and pkg/client and depends only on the standard
+// library, so importing the client does not pull in the server.
+package apitypes
+
+// Machine-readable error codes. Clients should branch on these rather than
+// on the human-readable detail, which may change.
+const (
+	CodeInvalidPayload     = "invalid_payload"
+	CodeInvalidQuery       = "invalid_query"
+	CodeValidationFailed   = "validation_failed"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeEmailTaken         = "email_taken"
+	CodeUnauthorized       = "unauthorized"
+	CodeMissingAuthHeader  = "missing_authorization_header"
+	CodeInvalidAuthHeader  = "invalid_authorization_header"
+	CodeInvalidToken       = "invalid_token"
+	CodeTokenRevoked       = "token_revoked"
+	CodeAccountDisabled    = "account_disabled"
+	CodeNotFound           = "not_found"
+	CodeInvalidUserCode    = "invalid_user_code"
+	CodeRateLimited        = "rate_limited"
+	CodeCORSRejected       = "cors_rejected"
+	CodeInternal           = "internal_error"
+	CodePayloadTooLarge    = "payload_too_large"
+
+	CodeInvalidIdempotencyKey = "invalid_idempotency_key"
+	CodeIdempotencyKeyInUse   = "idempotency_key_in_use"
+	CodeIdempotencyKeyReused  = "idempotency_key_reused"
+)
+
+// Codes of FieldError, one per kind of invalid field.
+const (
+	FieldRequired     = "required"
+	FieldTooShort     = "too_short"
+	FieldTooLong      = "too_long"
+	FieldInvalidEmail = "invalid_email"
+	FieldInvalidType  = "invalid_type"
+	FieldUnknown      = "unknown_field"
+	FieldNotOneOf     = "not_one_of"
+)
+
+// FieldError describes a single invalid field. Pointer is a JSON pointer
+// (RFC 6901) into the request body, e.g. "/email".
+type FieldError struct {
+	Pointer string `json:"pointer"`
+	Code    string `json:"code"`
+	Detail  string `json:"detail"`
+}
+
+// Problem is the RFC 7807 problem details body written for every error.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
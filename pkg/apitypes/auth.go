@@ -0,0 +1,20 @@
+package apitypes
+
+type RegisterRequest struct {
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password" validate:"required,min=6,maxbytes=72"`
+}
+
+type RegisterResponse struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,max=254"`
+	Password string `json:"password" validate:"required,maxbytes=72"`
+}
+
+type LoginResponse struct {
+	Token string `json:"token"`
+}
@@ -0,0 +1,34 @@
+package apitypes
+
+import "time"
+
+type Note struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateNoteRequest struct {
+	Content string `json:"content" validate:"required,max=10000"`
+}
+
+type CreateNoteResponse struct {
+	ID string `json:"id"`
+}
+
+type UpdateNoteRequest struct {
+	Content string `json:"content" validate:"required,max=10000"`
+}
+
+type ListNotesResponse struct {
+	Data []*Note       `json:"data"`
+	Meta ListNotesMeta `json:"meta"`
+}
+
+type ListNotesMeta struct {
+	Count int `json:"count"`
+	// NextCursor is set when more notes follow; pass it back as ?cursor=.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
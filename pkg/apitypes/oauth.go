@@ -0,0 +1,33 @@
+package apitypes
+
+const DeviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+type DeviceCodeResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type DeviceApprovalRequest struct {
+	UserCode string `json:"user_code" validate:"required,max=16"`
+	Deny     bool   `json:"deny,omitempty"`
+}
+
+type DeviceApprovalResponse struct {
+	Status string `json:"status"`
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// OAuthError is the error body defined by RFC 6749, section 5.2.
+type OAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/pkg/apitypes"
+)
+
+// User is the account returned by Register.
+type User = apitypes.RegisterResponse
+
+// Register creates an account. It fails with ErrDuplicateEmail when the
+// email is taken and ErrValidation when the input is rejected.
+func (c *Client) Register(ctx context.Context, email, password string) (*User, error) {
+	var user User
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/auth/register",
+		body:   apitypes.RegisterRequest{Email: email, Password: password},
+	}, &user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Login exchanges credentials for a bearer token used by later calls. The
+// credentials are kept in memory so the token can be renewed when it
+// expires; call SetToken or Logout to drop them.
+func (c *Client) Login(ctx context.Context, email, password string) (string, error) {
+	token, err := c.login(ctx, email, password)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.email, c.password = email, password
+	c.mu.Unlock()
+	c.setToken(token)
+	return token, nil
+}
+
+func (c *Client) login(ctx context.Context, email, password string) (string, error) {
+	var resp apitypes.LoginResponse
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/auth/login",
+		body:   apitypes.LoginRequest{Email: email, Password: password},
+	}, &resp)
+	return resp.Token, err
+}
+
+func (c *Client) hasCredentials() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.email != ""
+}
+
+// ensureToken logs in again before a protected call when the token is about
+// to expire and credentials are cached.
+func (c *Client) ensureToken(ctx context.Context) error {
+	c.mu.Lock()
+	expiring := c.email != "" && (c.token == "" || (!c.tokenExpiry.IsZero() && time.Until(c.tokenExpiry) < refreshSkew))
+	c.mu.Unlock()
+
+	if !expiring {
+		return nil
+	}
+	return c.relogin(ctx)
+}
+
+// relogin renews the token with the cached credentials.
+func (c *Client) relogin(ctx context.Context) error {
+	c.mu.Lock()
+	email, password := c.email, c.password
+	c.mu.Unlock()
+
+	token, err := c.login(ctx, email, password)
+	if err != nil {
+		return err
+	}
+	c.setToken(token)
+	return nil
+}
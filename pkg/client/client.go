@@ -0,0 +1,316 @@
+// Package client is a typed Go client for the Notes API.
+//
+//	c := client.New("https://notes.example.com")
+//	if _, err := c.Login(ctx, email, password); err != nil {
+//		return err
+//	}
+//	for note, err := range c.Notes(ctx, 50) {
+//		...
+//	}
+//
+// The client attaches the bearer token to protected calls, logs in again
+// with the cached credentials when the token expires or is rejected, and
+// retries throttled and transient failures with exponential backoff.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxRetries = 3
+	DefaultMinBackoff = 200 * time.Millisecond
+	DefaultMaxBackoff = 5 * time.Second
+
+	// refreshSkew renews tokens this long before they expire.
+	refreshSkew = time.Minute
+)
+
+// Client is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	userAgent  string
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+	email       string
+	password    string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sets the underlying HTTP client. The default is a client
+// with a 30 second timeout.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithRetries configures how often throttled or transient failures are
+// retried. maxRetries of 0 disables retries.
+func WithRetries(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.minBackoff = minBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// WithToken starts the client with an existing bearer token.
+func WithToken(token string) Option {
+	return func(c *Client) { c.setToken(token) }
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) { c.userAgent = userAgent }
+}
+
+// New returns a client for the API at baseURL, e.g. "https://notes.example.com".
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		userAgent:  "notes-api-go-client",
+		maxRetries: DefaultMaxRetries,
+		minBackoff: DefaultMinBackoff,
+		maxBackoff: DefaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Token returns the current bearer token, or "" when logged out.
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// SetToken replaces the bearer token and forgets cached credentials, so
+// the token is no longer refreshed automatically.
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	c.email, c.password = "", ""
+	c.mu.Unlock()
+	c.setToken(token)
+}
+
+// Logout forgets the token and cached credentials.
+func (c *Client) Logout() {
+	c.SetToken("")
+}
+
+func (c *Client) setToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+	c.tokenExpiry = tokenExpiry(token)
+}
+
+// tokenExpiry reads the exp claim of a JWT without verifying it; the server
+// does that. It returns the zero time when the claim is missing.
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if json.Unmarshal(payload, &claims) != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
+
+// Ready reports whether the API is ready to serve traffic.
+func (c *Client) Ready(ctx context.Context) error {
+	return c.do(ctx, request{method: http.MethodGet, path: "/readyz"}, nil)
+}
+
+// request describes one API call. The body is sent as JSON unless form is set.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   any
+	form   url.Values
+	auth   bool
+}
+
+// do sends req, retrying throttled and transient failures, and decodes a
+// successful response into out when it is not nil.
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	if req.auth {
+		if err := c.ensureToken(ctx); err != nil {
+			return err
+		}
+	}
+
+	refreshed := false
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, req)
+		if err != nil {
+			if ctx.Err() != nil || !c.canRetry(req.method, 0, attempt) {
+				return err
+			}
+			if err := c.sleep(ctx, c.backoff(attempt)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if resp.StatusCode < 300 {
+			defer resp.Body.Close()
+			if out == nil {
+				return nil
+			}
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return fmt.Errorf("client: decoding %s %s response: %w", req.method, req.path, err)
+			}
+			return nil
+		}
+
+		apiErr := decodeError(resp)
+		resp.Body.Close()
+
+		// An expired or revoked token: log in again once and retry.
+		if resp.StatusCode == http.StatusUnauthorized && req.auth && !refreshed && c.hasCredentials() {
+			refreshed = true
+			if err := c.relogin(ctx); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !c.canRetry(req.method, resp.StatusCode, attempt) {
+			return apiErr
+		}
+		delay := c.backoff(attempt)
+		if retryAfter := retryAfterOf(apiErr); retryAfter > 0 {
+			delay = retryAfter
+		}
+		if err := c.sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	u := c.baseURL + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+
+	var body io.Reader
+	contentType := ""
+	switch {
+	case req.form != nil:
+		body = strings.NewReader(req.form.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	case req.body != nil:
+		data, err := json.Marshal(req.body)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("User-Agent", c.userAgent)
+	if req.auth {
+		if token := c.Token(); token != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	return c.httpClient.Do(httpReq)
+}
+
+// canRetry reports whether a failed attempt may be repeated. Throttling
+// (429) and unavailability (503) mean the request was not processed, so any
+// method is retried; network errors and gateway failures only for GET.
+func (c *Client) canRetry(method string, status, attempt int) bool {
+	if attempt >= c.maxRetries {
+		return false
+	}
+	switch status {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case 0, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return method == http.MethodGet
+	}
+	return false
+}
+
+// backoff returns an exponentially growing delay with equal jitter.
+func (c *Client) backoff(attempt int) time.Duration {
+	ceiling := c.minBackoff << attempt
+	if ceiling <= 0 || ceiling > c.maxBackoff {
+		ceiling = c.maxBackoff
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	half := ceiling / 2
+	return half + rand.N(half+1)
+}
+
+func (c *Client) sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func retryAfterOf(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
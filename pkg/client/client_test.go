@@ -0,0 +1,275 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/internal/api"
+	"github.com/ivan-almanza/notes-api/internal/auth"
+	"github.com/ivan-almanza/notes-api/internal/ratelimit"
+	"github.com/ivan-almanza/notes-api/internal/store/memory"
+)
+
+// newTestServer runs the real router and handlers on an httptest.Server.
+func newTestServer(t *testing.T, limiter *api.RateLimiter) *httptest.Server {
+	t.Helper()
+	keys := auth.NewKeys("client-test-secret")
+
+	s := memory.New()
+	router := api.NewRouter(keys, limiter, nil, s)
+	router.Handle(api.Handlers{
+		Auth:    api.NewAuthHandler(s, keys),
+		Notes:   api.NewNotesHandler(s),
+		OAuth:   api.NewOAuthHandler(s, keys, "http://example.test/device"),
+		Health:  api.NewHealthChecker(),
+		Metrics: http.NotFoundHandler(),
+	}.Routes()...)
+
+	server := httptest.NewServer(api.WithRequestID(router))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestClient(server *httptest.Server) *Client {
+	return New(server.URL, WithRetries(3, time.Millisecond, 10*time.Millisecond))
+}
+
+func TestClient_RegisterLoginAndNotes(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(newTestServer(t, nil))
+
+	user, err := c.Register(ctx, "ada@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if user.ID == "" || user.Email != "ada@example.com" {
+		t.Errorf("Unexpected user: %+v", user)
+	}
+
+	if _, err := c.Register(ctx, "ada@example.com", "password123"); !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("Expected ErrDuplicateEmail, got %v", err)
+	}
+	if _, err := c.Login(ctx, "ada@example.com", "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+	if _, err := c.CreateNote(ctx, "too early"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized before login, got %v", err)
+	}
+
+	if _, err := c.Login(ctx, "ada@example.com", "password123"); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, err := c.CreateNote(ctx, fmt.Sprintf("note %d", i)); err != nil {
+			t.Fatalf("CreateNote failed: %v", err)
+		}
+	}
+
+	_, err = c.CreateNote(ctx, "")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrValidation) || len(apiErr.Fields) != 1 || apiErr.Fields[0].Pointer != "/content" {
+		t.Errorf("Expected a field-level validation error, got %v", err)
+	}
+
+	page, err := c.ListNotes(ctx, ListNotesOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListNotes failed: %v", err)
+	}
+	if len(page.Notes) != 2 || page.NextCursor == "" {
+		t.Errorf("Expected a first page of 2 with a cursor, got %+v", page)
+	}
+
+	var contents []string
+	for note, err := range c.Notes(ctx, 2) {
+		if err != nil {
+			t.Fatalf("Notes iteration failed: %v", err)
+		}
+		contents = append(contents, note.Content)
+	}
+	if len(contents) != 5 || contents[0] != "note 0" || contents[4] != "note 4" {
+		t.Errorf("Expected all 5 notes in order, got %v", contents)
+	}
+}
+
+func TestClient_NoteCRUDAndSearch(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(newTestServer(t, nil))
+	c.Register(ctx, "dan@example.com", "password123")
+	if _, err := c.Login(ctx, "dan@example.com", "password123"); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	id, err := c.CreateNote(ctx, "buy milk")
+	if err != nil {
+		t.Fatalf("CreateNote failed: %v", err)
+	}
+	c.CreateNote(ctx, "call mum")
+
+	note, err := c.UpdateNote(ctx, id, "buy oat milk")
+	if err != nil || note.Content != "buy oat milk" || note.ID != id {
+		t.Fatalf("UpdateNote returned %+v, %v", note, err)
+	}
+	if note, err := c.GetNote(ctx, id); err != nil || note.Content != "buy oat milk" {
+		t.Errorf("GetNote returned %+v, %v", note, err)
+	}
+
+	found, err := c.SearchNotes(ctx, "MILK", 0)
+	if err != nil || len(found) != 1 || found[0].ID != id {
+		t.Errorf("SearchNotes returned %v, %v", found, err)
+	}
+
+	if err := c.DeleteNote(ctx, id); err != nil {
+		t.Fatalf("DeleteNote failed: %v", err)
+	}
+	if _, err := c.GetNote(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+	if err := c.DeleteNote(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestClient_RefreshesRejectedToken(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(newTestServer(t, nil))
+
+	if _, err := c.Register(ctx, "bob@example.com", "password123"); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if _, err := c.Login(ctx, "bob@example.com", "password123"); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	// Simulate a revoked token while keeping the cached credentials.
+	c.mu.Lock()
+	c.token = "revoked"
+	c.mu.Unlock()
+
+	if _, err := c.CreateNote(ctx, "after refresh"); err != nil {
+		t.Fatalf("Expected the client to log in again, got %v", err)
+	}
+	if c.Token() == "revoked" {
+		t.Error("Expected a fresh token")
+	}
+
+	c.SetToken("revoked")
+	if _, err := c.CreateNote(ctx, "no credentials"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Without cached credentials the 401 should surface, got %v", err)
+	}
+}
+
+func TestClient_DeviceFlow(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t, nil)
+
+	approver := newTestClient(server)
+	approver.Register(ctx, "carol@example.com", "password123")
+	if _, err := approver.Login(ctx, "carol@example.com", "password123"); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	device := newTestClient(server)
+	da, err := device.RequestDeviceCode(ctx, "cli")
+	if err != nil {
+		t.Fatalf("RequestDeviceCode failed: %v", err)
+	}
+
+	if _, err := device.ExchangeDeviceCode(ctx, "cli", da.DeviceCode); !errors.Is(err, ErrAuthorizationPending) {
+		t.Errorf("Expected ErrAuthorizationPending, got %v", err)
+	}
+
+	if status, err := approver.ApproveDevice(ctx, da.UserCode, false); err != nil || status != "approved" {
+		t.Fatalf("ApproveDevice failed: %q %v", status, err)
+	}
+	if _, err := approver.ApproveDevice(ctx, "ZZZZ-ZZZZ", false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown code, got %v", err)
+	}
+
+	token, err := device.ExchangeDeviceCode(ctx, "cli", da.DeviceCode)
+	if err != nil {
+		t.Fatalf("ExchangeDeviceCode failed: %v", err)
+	}
+	device.SetToken(token.AccessToken)
+
+	if _, err := device.CreateNote(ctx, "from the device"); err != nil {
+		t.Errorf("The device token should authorize API calls: %v", err)
+	}
+}
+
+func TestClient_RateLimitedError(t *testing.T) {
+	ctx := context.Background()
+	limiter := api.NewRateLimiter(ratelimit.NewMemoryLimiter(), ratelimit.Limit{}, map[string]ratelimit.Limit{
+		"POST /auth/register": ratelimit.Per(1, time.Hour),
+	})
+	c := New(newTestServer(t, limiter).URL, WithRetries(0, 0, 0))
+
+	if _, err := c.Register(ctx, "dan@example.com", "password123"); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	_, err := c.Register(ctx, "erin@example.com", "password123")
+	var apiErr *APIError
+	if !errors.Is(err, ErrRateLimited) || !errors.As(err, &apiErr) {
+		t.Fatalf("Expected ErrRateLimited, got %v", err)
+	}
+	if apiErr.RetryAfter < 59*time.Minute || apiErr.RequestID == "" {
+		t.Errorf("Expected Retry-After and a request ID, got %+v", apiErr)
+	}
+}
+
+func TestClient_BackoffOnTransientErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":[{"id":"n1","content":"hi"}],"meta":{"count":1}}`)
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithToken("token"), WithRetries(3, time.Millisecond, 5*time.Millisecond))
+	page, err := c.ListNotes(context.Background(), ListNotesOptions{})
+	if err != nil {
+		t.Fatalf("Expected GET to be retried, got %v", err)
+	}
+	if calls.Load() != 3 || len(page.Notes) != 1 {
+		t.Errorf("Expected 3 calls and 1 note, got %d calls and %+v", calls.Load(), page)
+	}
+
+	// Non-idempotent requests are not retried on gateway errors.
+	calls.Store(0)
+	if _, err := c.CreateNote(context.Background(), "x"); err == nil || calls.Load() != 1 {
+		t.Errorf("Expected a single POST attempt, got %d calls and %v", calls.Load(), err)
+	}
+}
+
+func TestClient_HonoursRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"status":429,"code":"rate_limited"}`)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"n1"}`)
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithToken("token"), WithRetries(2, time.Millisecond, 5*time.Millisecond))
+	id, err := c.CreateNote(context.Background(), "x")
+	if err != nil || id != "n1" || calls.Load() != 2 {
+		t.Errorf("Expected a retried POST after 429, got %q %v after %d calls", id, err, calls.Load())
+	}
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/pkg/apitypes"
+)
+
+// DeviceAuthorization is the response of the device authorization request
+// (RFC 8628). Show UserCode and VerificationURI to the user, then call
+// WaitForDeviceToken.
+type DeviceAuthorization = apitypes.DeviceCodeResponse
+
+type Token = apitypes.TokenResponse
+
+// RequestDeviceCode starts the device authorization grant for clientID.
+func (c *Client) RequestDeviceCode(ctx context.Context, clientID string) (*DeviceAuthorization, error) {
+	var da DeviceAuthorization
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/oauth/device/code",
+		form:   url.Values{"client_id": {clientID}},
+	}, &da)
+	if err != nil {
+		return nil, err
+	}
+	return &da, nil
+}
+
+// ExchangeDeviceCode polls the token endpoint once. Until the user decides
+// it fails with ErrAuthorizationPending or ErrSlowDown.
+func (c *Client) ExchangeDeviceCode(ctx context.Context, clientID, deviceCode string) (*Token, error) {
+	var token Token
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/oauth/token",
+		form: url.Values{
+			"grant_type":  {apitypes.DeviceCodeGrantType},
+			"device_code": {deviceCode},
+			"client_id":   {clientID},
+		},
+	}, &token)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// WaitForDeviceToken polls at the server's interval until the user approves
+// or denies the request or the code expires. On success the client uses
+// the new token for later calls.
+func (c *Client) WaitForDeviceToken(ctx context.Context, clientID string, da *DeviceAuthorization) (*Token, error) {
+	interval := time.Duration(da.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	for {
+		if err := c.sleep(ctx, interval); err != nil {
+			return nil, err
+		}
+
+		token, err := c.ExchangeDeviceCode(ctx, clientID, da.DeviceCode)
+		switch {
+		case err == nil:
+			c.SetToken(token.AccessToken)
+			return token, nil
+		case errors.Is(err, ErrSlowDown):
+			interval += 5 * time.Second
+		case errors.Is(err, ErrAuthorizationPending):
+		default:
+			return nil, err
+		}
+	}
+}
+
+// ApproveDevice approves, or with deny rejects, the device showing userCode
+// on behalf of the logged-in user. It returns the resulting status.
+func (c *Client) ApproveDevice(ctx context.Context, userCode string, deny bool) (string, error) {
+	var resp apitypes.DeviceApprovalResponse
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/oauth/device/approve",
+		body:   apitypes.DeviceApprovalRequest{UserCode: userCode, Deny: deny},
+		auth:   true,
+	}, &resp)
+	return resp.Status, err
+}
@@ -0,0 +1,141 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ivan-almanza/notes-api/pkg/apitypes"
+)
+
+// Sentinel errors matched with errors.Is against *APIError and *OAuthError.
+// They mirror the server's store errors and OAuth error codes.
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrDuplicateEmail     = errors.New("email already exists")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrAccountDisabled    = errors.New("account disabled")
+	ErrValidation         = errors.New("validation failed")
+	ErrRateLimited        = errors.New("rate limited")
+
+	ErrAuthorizationPending = errors.New("authorization pending")
+	ErrSlowDown             = errors.New("slow down")
+	ErrAccessDenied         = errors.New("access denied")
+	ErrExpiredToken         = errors.New("device code expired")
+	ErrInvalidGrant         = errors.New("invalid grant")
+)
+
+// FieldError describes one invalid request field. Pointer is a JSON pointer
+// into the request body, e.g. "/email".
+type FieldError = apitypes.FieldError
+
+// APIError is an RFC 7807 problem returned by the API.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Title      string
+	Detail     string
+	RequestID  string
+	Fields     []FieldError
+	// RetryAfter is the server's Retry-After hint, if any.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("notes api: %d %s", e.StatusCode, e.Code)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	for _, f := range e.Fields {
+		msg += fmt.Sprintf("; %s %s", f.Pointer, f.Detail)
+	}
+	return msg
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Code == apitypes.CodeNotFound
+	case ErrDuplicateEmail:
+		return e.Code == apitypes.CodeEmailTaken
+	case ErrInvalidCredentials:
+		return e.Code == apitypes.CodeInvalidCredentials
+	case ErrAccountDisabled:
+		return e.Code == apitypes.CodeAccountDisabled
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrValidation:
+		return e.Code == apitypes.CodeValidationFailed || e.Code == apitypes.CodeInvalidPayload || e.Code == apitypes.CodeInvalidQuery
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// OAuthError is an RFC 6749 error returned by the /oauth endpoints.
+type OAuthError struct {
+	StatusCode  int
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	if e.Description != "" {
+		return "notes api: oauth " + e.Code + ": " + e.Description
+	}
+	return "notes api: oauth " + e.Code
+}
+
+func (e *OAuthError) Is(target error) bool {
+	switch target {
+	case ErrAuthorizationPending:
+		return e.Code == "authorization_pending"
+	case ErrSlowDown:
+		return e.Code == "slow_down"
+	case ErrAccessDenied:
+		return e.Code == "access_denied"
+	case ErrExpiredToken:
+		return e.Code == "expired_token"
+	case ErrInvalidGrant:
+		return e.Code == "invalid_grant"
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// decodeError turns a non-2xx response into an *APIError or *OAuthError.
+// Bodies that are neither still yield an *APIError with the status code.
+func decodeError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/problem+json") {
+		var oauthErr apitypes.OAuthError
+		if json.Unmarshal(body, &oauthErr) == nil && oauthErr.Error != "" {
+			return &OAuthError{StatusCode: resp.StatusCode, Code: oauthErr.Error, Description: oauthErr.ErrorDescription}
+		}
+	}
+
+	var problem apitypes.Problem
+	if json.Unmarshal(body, &problem) != nil || problem.Code == "" {
+		problem = apitypes.Problem{Detail: strings.TrimSpace(string(body))}
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(resp.StatusCode)
+	}
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Code:       problem.Code,
+		Title:      problem.Title,
+		Detail:     problem.Detail,
+		RequestID:  problem.RequestID,
+		Fields:     problem.Errors,
+		RetryAfter: retryAfter,
+	}
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ivan-almanza/notes-api/pkg/apitypes"
+)
+
+type Note = apitypes.Note
+
+// CreateNote stores a note for the logged-in user and returns its ID.
+func (c *Client) CreateNote(ctx context.Context, content string) (string, error) {
+	var resp apitypes.CreateNoteResponse
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/notes",
+		body:   apitypes.CreateNoteRequest{Content: content},
+		auth:   true,
+	}, &resp)
+	return resp.ID, err
+}
+
+// GetNote returns one note of the logged-in user. It fails with ErrNotFound
+// when the note does not exist or belongs to someone else.
+func (c *Client) GetNote(ctx context.Context, id string) (*Note, error) {
+	var note Note
+	err := c.do(ctx, request{method: http.MethodGet, path: "/notes/" + url.PathEscape(id), auth: true}, &note)
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// UpdateNote replaces the content of a note and returns the updated note.
+func (c *Client) UpdateNote(ctx context.Context, id, content string) (*Note, error) {
+	var note Note
+	err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   "/notes/" + url.PathEscape(id),
+		body:   apitypes.UpdateNoteRequest{Content: content},
+		auth:   true,
+	}, &note)
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// DeleteNote deletes a note of the logged-in user.
+func (c *Client) DeleteNote(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/notes/" + url.PathEscape(id), auth: true}, nil)
+}
+
+// SearchNotes returns up to limit notes containing query, newest first. A
+// limit of zero uses the server default.
+func (c *Client) SearchNotes(ctx context.Context, query string, limit int) ([]*Note, error) {
+	params := url.Values{"q": {query}}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	var resp apitypes.ListNotesResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/notes/search", query: params, auth: true}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+const defaultPageSize = 20
+
+type ListNotesOptions struct {
+	// Limit is the page size, at most 100. Zero uses the server default.
+	Limit int
+	// Cursor is NextCursor from the previous page.
+	Cursor string
+}
+
+type NotePage struct {
+	Notes []*Note
+	// NextCursor is empty on the last page.
+	NextCursor string
+}
+
+// ListNotes returns one page of the logged-in user's notes, oldest first.
+func (c *Client) ListNotes(ctx context.Context, opts ListNotesOptions) (*NotePage, error) {
+	query := url.Values{}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Cursor != "" {
+		query.Set("cursor", opts.Cursor)
+	}
+	if len(query) == 0 {
+		// Without parameters the server returns every note unpaginated.
+		query.Set("limit", strconv.Itoa(defaultPageSize))
+	}
+
+	var resp apitypes.ListNotesResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/notes", query: query, auth: true}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &NotePage{Notes: resp.Data, NextCursor: resp.Meta.NextCursor}, nil
+}
+
+// Notes iterates over every note of the logged-in user, fetching pages of
+// pageSize lazily. Iteration stops after the first error.
+func (c *Client) Notes(ctx context.Context, pageSize int) iter.Seq2[*Note, error] {
+	return func(yield func(*Note, error) bool) {
+		opts := ListNotesOptions{Limit: pageSize}
+		for {
+			page, err := c.ListNotes(ctx, opts)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, note := range page.Notes {
+				if !yield(note, nil) {
+					return
+				}
+			}
+			if page.NextCursor == "" {
+				return
+			}
+			opts.Cursor = page.NextCursor
+		}
+	}
+}